@@ -16,9 +16,12 @@ import (
 	"github.com/openshift/library-go/pkg/features"
 	capimachine "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	"github.com/openshift/machine-api-operator/pkg/metrics"
+	controlplanecontroller "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/controlplane"
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machine"
 	machinesetcontroller "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/machineset"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
 	"github.com/openshift/machine-api-provider-gcp/pkg/version"
 	"k8s.io/apiserver/pkg/util/feature"
@@ -88,6 +91,12 @@ func main() {
 		"Maximum number of concurrent reconciles per controller instance.",
 	)
 
+	controlPlaneBackendServiceScope := flag.String(
+		"control-plane-backend-service-scope",
+		string(machinev1.BackendServiceScopeRegional),
+		"Scope of the backend service of the internal API load balancer the control plane instance groups are registered in. Valid values are Regional and Global, for a cross-region internal or a global external load balancer.",
+	)
+
 	// Sets up feature gates (version from build time, default 4 for unknown)
 	// Default should be changed to 5 once we branch for 5
 	majorVersion := version.Version.Major
@@ -106,6 +115,12 @@ func main() {
 	flag.Set("logtostderr", "true")
 	flag.Parse()
 
+	switch scope := machinev1.GCPBackendServiceScope(*controlPlaneBackendServiceScope); scope {
+	case machinev1.BackendServiceScopeRegional, machinev1.BackendServiceScopeGlobal:
+	default:
+		klog.Fatalf("Unknown control plane backend service scope %q, valid values are %q and %q", scope, machinev1.BackendServiceScopeRegional, machinev1.BackendServiceScopeGlobal)
+	}
+
 	if *printVersion {
 		fmt.Println(version.String)
 		os.Exit(0)
@@ -166,7 +181,11 @@ func main() {
 		EventRecorder:        mgr.GetEventRecorderFor("gcpcontroller"),
 		ComputeClientBuilder: computeservice.NewComputeService,
 		TagsClientBuilder:    tagservice.NewTagService,
+		KMSClientBuilder:     kmsservice.NewCachedKMSService,
+		DNSClientBuilder:     dnsservice.NewCachedDNSService,
 		FeatureGates:         defaultMutableGate,
+
+		ControlPlaneBackendServiceScope: machinev1.GCPBackendServiceScope(*controlPlaneBackendServiceScope),
 	})
 
 	if err := machinev1.AddToScheme(mgr.GetScheme()); err != nil {
@@ -193,6 +212,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&controlplanecontroller.Reconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("ControlPlaneInstanceGroups"),
+
+		BackendServiceScope: machinev1.GCPBackendServiceScope(*controlPlaneBackendServiceScope),
+	}).SetupWithManager(mgr, controller.Options{}); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ControlPlaneInstanceGroups")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
 		klog.Fatal(err)
 	}
@@ -0,0 +1,549 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	mapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	machineRoleLabel  = "machine.openshift.io/cluster-api-machine-role"
+	masterMachineRole = "master"
+
+	// statusConfigMapName is the ConfigMap, in the namespace of the machines, reporting the backend
+	// service of the internal API load balancer and the members of the control plane instance groups.
+	statusConfigMapName = "gcp-control-plane-instance-groups"
+	backendServiceKey   = "backendService"
+
+	instanceGroupLinkFmt = "https://www.googleapis.com/compute/v1/projects/%s/zones/%s/instanceGroups/%s"
+	instanceLinkFmt      = "https://www.googleapis.com/compute/v1/projects/%s/zones/%s/instances/%s"
+
+	operationStatusDone = "DONE"
+
+	// requeueAfter is the time given to created or detached instance groups before they are reconciled again.
+	requeueAfter = 20 * time.Second
+	// resyncPeriod is the period at which stale members and empty instance groups are garbage collected
+	// when no control plane machine changes.
+	resyncPeriod = 10 * time.Minute
+)
+
+// Reconciler reconciles the per-zone instance groups of the control plane machines and the backend
+// service of the internal API load balancer. The desired membership is computed from all the control
+// plane machines of a namespace, so that a single reconciliation adds the new instances, removes the
+// stale ones and deletes the instance groups of the zones left without control plane machines.
+type Reconciler struct {
+	Client client.Client
+	Log    logr.Logger
+
+	// BackendServiceScope is the scope of the backend service of the internal API load balancer,
+	// regional unless set to Global.
+	BackendServiceScope machinev1.GCPBackendServiceScope
+
+	// Allow a mock GCPComputeService to be injected during testing, together with the project the machines are created in
+	getGCPService func(namespace string, providerConfig machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, string, error)
+
+	// insertOperations are the names of the running insert operations of the instance groups, by project,
+	// zone and name of the instance group, so that the instance groups only get members once they are created.
+	insertOperations sync.Map
+}
+
+// SetupWithManager creates a new controller for a manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, options controller.Options) error {
+	_, err := ctrl.NewControllerManagedBy(mgr).
+		Named("controlplane-instance-groups").
+		Watches(
+			&machinev1.Machine{},
+			handler.EnqueueRequestsFromMapFunc(toNamespaceRequest),
+			builder.WithPredicates(predicate.NewPredicateFuncs(isControlPlaneMachine)),
+		).
+		WithOptions(options).
+		Build(r)
+
+	if err != nil {
+		return fmt.Errorf("failed setting up with a controller manager: %w", err)
+	}
+
+	if r.getGCPService == nil {
+		r.getGCPService = r.getRealGCPService
+	}
+	return nil
+}
+
+// toNamespaceRequest maps all the control plane machines of a namespace to a single request,
+// named after the status ConfigMap.
+func toNamespaceRequest(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: statusConfigMapName},
+	}}
+}
+
+func isControlPlaneMachine(obj client.Object) bool {
+	return obj.GetLabels()[machineRoleLabel] == masterMachineRole
+}
+
+// Reconcile implements controller runtime Reconciler interface.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.WithValues("namespace", req.Namespace)
+	logger.V(3).Info("Reconciling")
+
+	result, err := r.reconcile(ctx, req.Namespace)
+	if err != nil {
+		logger.Error(err, "Failed to reconcile control plane instance groups")
+	}
+
+	if isInvalidConfigurationError(err) {
+		// For situations where requeuing won't help we don't return error.
+		// https://github.com/kubernetes-sigs/controller-runtime/issues/617
+		return result, nil
+	}
+	return result, err
+}
+
+// controlPlane is the desired state of the control plane instance groups, computed from the
+// control plane machines.
+type controlPlane struct {
+	clusterID string
+	// providerSpec is the provider spec of the first control plane machine, which provides the
+	// credentials, the project and the region of the control plane.
+	providerSpec *machinev1.GCPMachineProviderSpec
+	zones        map[string]*controlPlaneZone
+}
+
+// controlPlaneZone is the desired state of the instance group of a zone.
+type controlPlaneZone struct {
+	// networkProjectID is the project hosting the network, which is the host project for Shared VPC.
+	networkProjectID string
+	network          string
+	subnetwork       string
+	// members are the names of the instances of the provisioned control plane machines in the zone.
+	members sets.String
+}
+
+// desiredControlPlane computes the desired state of the control plane instance groups. The instances of
+// the machines with a provider ID are members whatever their state, the health checks of the backend
+// service tell whether they are reachable. Machines being deleted are not members, so that their instances
+// are removed from the instance groups and the instance groups of the zones they were the last machines
+// of are deleted.
+func desiredControlPlane(machines []machinev1.Machine) (*controlPlane, error) {
+	sort.Slice(machines, func(i, j int) bool { return machines[i].Name < machines[j].Name })
+
+	cp := &controlPlane{zones: map[string]*controlPlaneZone{}}
+	for _, machine := range machines {
+		if !machine.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil {
+			return nil, mapierrors.InvalidMachineConfiguration("failed to get provider spec of machine %s: %v", machine.Name, err)
+		}
+		if cp.providerSpec == nil {
+			cp.providerSpec = providerSpec
+			cp.clusterID = machine.Labels[machinev1.MachineClusterIDLabel]
+			if cp.clusterID == "" {
+				return nil, mapierrors.InvalidMachineConfiguration("machine %s is missing the %s label", machine.Name, machinev1.MachineClusterIDLabel)
+			}
+		}
+
+		zone, ok := cp.zones[providerSpec.Zone]
+		if !ok {
+			zone = &controlPlaneZone{members: sets.NewString()}
+			// The instance group is created in the network of the first machine of the zone
+			if len(providerSpec.NetworkInterfaces) > 0 {
+				zone.networkProjectID = providerSpec.NetworkInterfaces[0].ProjectID
+				zone.network = providerSpec.NetworkInterfaces[0].Network
+				zone.subnetwork = providerSpec.NetworkInterfaces[0].Subnetwork
+			}
+			cp.zones[providerSpec.Zone] = zone
+		}
+
+		if machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != "" {
+			zone.members.Insert(machine.Name)
+		}
+	}
+	return cp, nil
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, namespace string) (ctrl.Result, error) {
+	machines := &machinev1.MachineList{}
+	if err := r.Client.List(ctx, machines, client.InNamespace(namespace), client.MatchingLabels{machineRoleLabel: masterMachineRole}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list control plane machines: %w", err)
+	}
+
+	cp, err := desiredControlPlane(machines.Items)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(cp.zones) == 0 {
+		// Never garbage collect the instance groups when there are no control plane machines to compute them from
+		return ctrl.Result{}, nil
+	}
+
+	computeService, projectID, err := r.getGCPService(namespace, *cp.providerSpec)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	zones, err := controlPlaneZones(computeService, projectID, cp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	backendServiceName := fmt.Sprintf("%s-api-internal", cp.clusterID)
+	status := map[string]string{backendServiceKey: backendServiceName}
+	desiredBackends := sets.NewString()
+	staleGroups := map[string]string{}
+	requeue := false
+	for _, zone := range zones {
+		group := fmt.Sprintf("%s-%s-%s", cp.clusterID, masterMachineRole, zone)
+		desired, ok := cp.zones[zone]
+
+		// The instance group gets its members and becomes a backend once it has been created
+		inserting, err := r.isInsertingInstanceGroup(computeService, projectID, zone, group)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if inserting {
+			requeue = true
+			continue
+		}
+
+		_, err = computeService.InstanceGroupGet(projectID, zone, group)
+		if isNotFoundError(err) {
+			if ok {
+				if err := r.createInstanceGroup(computeService, projectID, cp.providerSpec.Region, zone, group, desired); err != nil {
+					return ctrl.Result{}, err
+				}
+				requeue = true
+			}
+			continue
+		}
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("instanceGroupGet request for %s failed: %w", group, err)
+		}
+
+		members := sets.NewString()
+		if ok {
+			members = desired.members
+		}
+		if err := reconcileMembers(computeService, projectID, zone, group, members); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		link := fmt.Sprintf(instanceGroupLinkFmt, projectID, zone, group)
+		if ok {
+			desiredBackends.Insert(link)
+			status[group] = strings.Join(members.List(), ",")
+		} else {
+			staleGroups[link] = zone
+		}
+	}
+
+	backendService := &backendService{
+		computeService: computeService,
+		projectID:      projectID,
+		region:         cp.providerSpec.Region,
+		name:           backendServiceName,
+		global:         r.BackendServiceScope == machinev1.BackendServiceScopeGlobal,
+	}
+	detaching, err := backendService.reconcile(desiredBackends, staleGroups)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	for link, zone := range staleGroups {
+		if detaching.Has(link) {
+			// The instance group can only be deleted once it is no longer a backend
+			requeue = true
+			continue
+		}
+		if _, err := computeService.InstanceGroupsDelete(projectID, zone, path.Base(link)); err != nil && !isNotFoundError(err) {
+			return ctrl.Result{}, fmt.Errorf("instanceGroupsDelete request for %s failed: %w", path.Base(link), err)
+		}
+	}
+
+	if err := r.reportStatus(ctx, namespace, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if requeue {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return ctrl.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+// controlPlaneZones returns the zones of the region of the control plane and the zones of the control
+// plane machines, so that the instance groups of the zones without control plane machines are garbage
+// collected as well.
+func controlPlaneZones(computeService computeservice.GCPComputeService, projectID string, cp *controlPlane) ([]string, error) {
+	region, err := computeService.RegionGet(projectID, cp.providerSpec.Region)
+	if err != nil {
+		return nil, fmt.Errorf("regionGet request for %s failed: %w", cp.providerSpec.Region, err)
+	}
+
+	zones := sets.NewString()
+	for _, zone := range region.Zones {
+		// The zones of a region are the URLs of the zones
+		zones.Insert(path.Base(zone))
+	}
+	for zone := range cp.zones {
+		zones.Insert(zone)
+	}
+	return zones.List(), nil
+}
+
+// createInstanceGroup creates the instance group of a zone in the network of its control plane machines.
+// A running insert operation is recorded, the instance group is reconciled again once it is done.
+func (r *Reconciler) createInstanceGroup(computeService computeservice.GCPComputeService, projectID, region, zone, group string, desired *controlPlaneZone) error {
+	networkProjectID := desired.networkProjectID
+	if networkProjectID == "" {
+		networkProjectID = projectID
+	}
+	instanceGroup := &compute.InstanceGroup{
+		Name: group,
+		Zone: zone,
+	}
+	if desired.network != "" {
+		instanceGroup.Network = fmt.Sprintf("projects/%s/global/networks/%s", networkProjectID, desired.network)
+	}
+	if desired.subnetwork != "" {
+		instanceGroup.Subnetwork = fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", networkProjectID, region, desired.subnetwork)
+	}
+
+	op, err := computeService.InstanceGroupInsert(projectID, zone, instanceGroup)
+	if isAlreadyExistsError(err) {
+		// The instance group is still being created by an operation started before a restart
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("instanceGroupInsert request for %s failed: %w", group, err)
+	}
+	if op.Status != operationStatusDone {
+		r.insertOperations.Store(insertOperationKey(projectID, zone, group), op.Name)
+		return nil
+	}
+	return operationError(group, op)
+}
+
+// isInsertingInstanceGroup tells whether the insert operation of the instance group is still running.
+// It returns the error of a failed insert operation once it is done.
+func (r *Reconciler) isInsertingInstanceGroup(computeService computeservice.GCPComputeService, projectID, zone, group string) (bool, error) {
+	key := insertOperationKey(projectID, zone, group)
+	name, ok := r.insertOperations.Load(key)
+	if !ok {
+		return false, nil
+	}
+
+	op, err := computeService.ZoneOperationsGet(projectID, zone, name.(string))
+	if isNotFoundError(err) {
+		// Operations are garbage collected some time after they are done
+		r.insertOperations.Delete(key)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("zoneOperationsGet request for %s failed: %w", name, err)
+	}
+	if op.Status != operationStatusDone {
+		return true, nil
+	}
+	r.insertOperations.Delete(key)
+	return false, operationError(group, op)
+}
+
+func insertOperationKey(projectID, zone, group string) string {
+	return path.Join(projectID, zone, group)
+}
+
+// operationError returns the first error of the done insert operation of the instance group.
+func operationError(group string, op *compute.Operation) error {
+	if op.Error == nil || len(op.Error.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("instanceGroupInsert operation for %s failed: %s", group, op.Error.Errors[0].Message)
+}
+
+// reconcileMembers adds the missing instances to the instance group and removes the stale ones.
+func reconcileMembers(computeService computeservice.GCPComputeService, projectID, zone, group string, desired sets.String) error {
+	list, err := computeService.InstanceGroupsListInstances(projectID, zone, group, &compute.InstanceGroupsListInstancesRequest{
+		InstanceState: "ALL",
+	})
+	if err != nil {
+		return fmt.Errorf("instanceGroupsListInstances request for %s failed: %w", group, err)
+	}
+
+	current := sets.NewString()
+	for _, item := range list.Items {
+		// The instance of a member is the URL of the instance
+		current.Insert(path.Base(item.Instance))
+	}
+
+	for _, name := range desired.Difference(current).List() {
+		if _, err := computeService.InstanceGroupsAddInstances(projectID, zone, fmt.Sprintf(instanceLinkFmt, projectID, zone, name), group); err != nil {
+			return fmt.Errorf("instanceGroupsAddInstances request for %s failed: %w", group, err)
+		}
+	}
+	for _, name := range current.Difference(desired).List() {
+		if _, err := computeService.InstanceGroupsRemoveInstances(projectID, zone, fmt.Sprintf(instanceLinkFmt, projectID, zone, name), group); err != nil {
+			return fmt.Errorf("instanceGroupsRemoveInstances request for %s failed: %w", group, err)
+		}
+	}
+	return nil
+}
+
+// backendService is the regional or global backend service of the internal API load balancer.
+type backendService struct {
+	computeService computeservice.GCPComputeService
+	projectID      string
+	region         string
+	name           string
+	global         bool
+}
+
+// reconcile adds the desired instance groups to the backend service and removes the stale ones. The
+// patch is serialized with the changes of the machine actuator and retried when the backend service
+// was modified concurrently. It returns the stale instance groups that were still backends.
+func (b *backendService) reconcile(desired sets.String, stale map[string]string) (sets.String, error) {
+	region := b.region
+	if b.global {
+		region = ""
+	}
+
+	var detaching sets.String
+	_, err := util.PatchBackendService(b.computeService, b.projectID, region, b.name, func(backendService *compute.BackendService) *compute.BackendService {
+		detaching = sets.NewString()
+		current := sets.NewString()
+		var backends []*compute.Backend
+		for _, backend := range backendService.Backends {
+			if _, ok := stale[backend.Group]; ok {
+				detaching.Insert(backend.Group)
+				continue
+			}
+			current.Insert(backend.Group)
+			backends = append(backends, backend)
+		}
+		missing := desired.Difference(current)
+		for _, group := range missing.List() {
+			backends = append(backends, &compute.Backend{
+				BalancingMode: "CONNECTION",
+				Group:         group,
+			})
+		}
+
+		if missing.Len() == 0 && detaching.Len() == 0 {
+			return nil
+		}
+		return &compute.BackendService{
+			Backends: backends,
+			// Allow patching the last backend away
+			ForceSendFields: []string{"Backends"},
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return detaching, nil
+}
+
+// reportStatus creates or updates the status ConfigMap.
+func (r *Reconciler) reportStatus(ctx context.Context, namespace string, data map[string]string) error {
+	configMap := &corev1.ConfigMap{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: statusConfigMapName}, configMap)
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      statusConfigMapName,
+			},
+			Data: data,
+		}
+		if err := r.Client.Create(ctx, configMap); err != nil {
+			return fmt.Errorf("failed to create status configmap: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get status configmap: %w", err)
+	}
+
+	if reflect.DeepEqual(configMap.Data, data) {
+		return nil
+	}
+	configMap.Data = data
+	if err := r.Client.Update(ctx, configMap); err != nil {
+		return fmt.Errorf("failed to update status configmap: %w", err)
+	}
+	return nil
+}
+
+// getRealGCPService constructs a real GCPService for talking to GCP and returns it
+// together with the project the machines are created in
+func (r *Reconciler) getRealGCPService(namespace string, providerConfig machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, string, error) {
+	serviceAccountJSON, err := util.GetCredentialsSecret(r.Client, namespace, providerConfig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	projectID := providerConfig.ProjectID
+	if len(projectID) == 0 {
+		projectID, err = util.GetProjectIDFromJSONKey([]byte(serviceAccountJSON))
+		if err != nil {
+			return nil, "", mapierrors.InvalidMachineConfiguration("error getting project from JSON key: %v", err)
+		}
+	}
+
+	computeService, err := computeservice.NewComputeService(serviceAccountJSON)
+	if err != nil {
+		return nil, "", mapierrors.InvalidMachineConfiguration("error creating compute service: %v", err)
+	}
+	return computeService, projectID, nil
+}
+
+func isInvalidConfigurationError(err error) bool {
+	switch t := err.(type) {
+	case *mapierrors.MachineError:
+		if t.Reason == machinev1.InvalidConfigurationMachineError {
+			return true
+		}
+	}
+	return false
+}
+
+func isNotFoundError(err error) bool {
+	switch t := err.(type) {
+	case *googleapi.Error:
+		return t.Code == 404
+	}
+	return false
+}
+
+func isAlreadyExistsError(err error) bool {
+	switch t := err.(type) {
+	case *googleapi.Error:
+		return t.Code == 409
+	}
+	return false
+}
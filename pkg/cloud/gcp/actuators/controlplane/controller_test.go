@@ -0,0 +1,490 @@
+package controlplane
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testNamespace = "openshift-machine-api"
+
+func groupLink(zone string) string {
+	return "https://www.googleapis.com/compute/v1/projects/test/zones/" + zone + "/instanceGroups/CLUSTERID-master-" + zone
+}
+
+func instanceLink(zone, name string) string {
+	return "https://www.googleapis.com/compute/v1/projects/test/zones/" + zone + "/instances/" + name
+}
+
+// newControlPlaneMachine returns a control plane machine, without a provider ID when it has no instance state yet.
+func newControlPlaneMachine(t *testing.T, name, zone, instanceState string, deleting bool) *machinev1.Machine {
+	return newSharedVPCControlPlaneMachine(t, name, zone, "", instanceState, deleting)
+}
+
+func newSharedVPCControlPlaneMachine(t *testing.T, name, zone, networkProjectID, instanceState string, deleting bool) *machinev1.Machine {
+	providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{
+		Region: "region1",
+		Zone:   zone,
+		NetworkInterfaces: []*machinev1.GCPNetworkInterface{{
+			ProjectID:  networkProjectID,
+			Network:    "network",
+			Subnetwork: "subnetwork",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	providerStatus, err := util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{
+		InstanceState: pointer.String(instanceState),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				machineRoleLabel:                masterMachineRole,
+				machinev1.MachineClusterIDLabel: "CLUSTERID",
+			},
+		},
+		Spec: machinev1.MachineSpec{
+			ProviderSpec: machinev1.ProviderSpec{Value: providerSpec},
+		},
+		Status: machinev1.MachineStatus{
+			ProviderStatus: providerStatus,
+		},
+	}
+	if instanceState != "" {
+		machine.Spec.ProviderID = pointer.String("gce://test/" + zone + "/" + name)
+	}
+	if deleting {
+		machine.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		machine.Finalizers = []string{machinev1.MachineFinalizer}
+	}
+	return machine
+}
+
+// fakeCloud records the instance groups of the region and the backends of the internal API backend service.
+type fakeCloud struct {
+	groups   map[string][]string
+	backends []string
+	global   bool
+
+	// insertStatus is the status of the insert operations, DONE when empty.
+	insertStatus string
+	// operations are the statuses of the zone operations by name.
+	operations map[string]*compute.Operation
+
+	created []*compute.InstanceGroup
+	added   []string
+	removed []string
+	deleted []string
+	patched *compute.BackendService
+}
+
+func (f *fakeCloud) computeService(t *testing.T) *computeservice.GCPComputeServiceMock {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockRegionGet = func(project string, region string) (*compute.Region, error) {
+		return &compute.Region{Zones: []string{
+			"https://www.googleapis.com/compute/v1/projects/test/zones/zone1",
+			"https://www.googleapis.com/compute/v1/projects/test/zones/zone2",
+		}}, nil
+	}
+	mockComputeService.MockInstanceGroupGet = func(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error) {
+		if _, ok := f.groups[zone]; !ok || instanceGroupName != "CLUSTERID-master-"+zone {
+			return nil, &googleapi.Error{Code: http.StatusNotFound}
+		}
+		return &compute.InstanceGroup{Name: instanceGroupName}, nil
+	}
+	mockComputeService.MockInstanceGroupInsert = func(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error) {
+		f.created = append(f.created, instanceGroup)
+		if f.insertStatus != "" {
+			return &compute.Operation{Name: "insert-" + instanceGroup.Name, Status: f.insertStatus}, nil
+		}
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockZoneOperationsGet = func(project string, zone string, operation string) (*compute.Operation, error) {
+		op, ok := f.operations[operation]
+		if !ok {
+			return nil, &googleapi.Error{Code: http.StatusNotFound}
+		}
+		return op, nil
+	}
+	mockComputeService.MockInstanceGroupsListInstances = func(project string, zone string, instanceGroup string, request *compute.InstanceGroupsListInstancesRequest) (*compute.InstanceGroupsListInstances, error) {
+		if request.InstanceState != "ALL" {
+			t.Errorf("Expected stopped members to be listed as well, got instance state %q", request.InstanceState)
+		}
+		list := &compute.InstanceGroupsListInstances{}
+		for _, name := range f.groups[zone] {
+			list.Items = append(list.Items, &compute.InstanceWithNamedPorts{Instance: instanceLink(zone, name)})
+		}
+		return list, nil
+	}
+	mockComputeService.MockInstanceGroupsAddInstances = func(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+		f.added = append(f.added, instance)
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockInstanceGroupsRemoveInstances = func(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+		f.removed = append(f.removed, instance)
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockInstanceGroupsDelete = func(project string, zone string, instanceGroup string) (*compute.Operation, error) {
+		f.deleted = append(f.deleted, instanceGroup)
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockBackendServiceGet = func(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+		if region != "region1" || backendServiceName != "CLUSTERID-api-internal" {
+			t.Errorf("Unexpected backend service %s in region %s", backendServiceName, region)
+		}
+		backendService := &compute.BackendService{Name: backendServiceName, Fingerprint: "fingerprint"}
+		for _, group := range f.backends {
+			backendService.Backends = append(backendService.Backends, &compute.Backend{Group: group, BalancingMode: "CONNECTION"})
+		}
+		return backendService, nil
+	}
+	mockComputeService.MockRegionBackendServicesPatch = func(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+		if f.global {
+			t.Errorf("Regional backend service %s was not expected to be patched", backendServiceName)
+		}
+		f.patched = backendService
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockGlobalBackendServiceGet = func(project string, backendServiceName string) (*compute.BackendService, error) {
+		if !f.global {
+			t.Errorf("Unexpected global backend service %s", backendServiceName)
+		}
+		return mockComputeService.MockBackendServiceGet(project, "region1", backendServiceName)
+	}
+	mockComputeService.MockBackendServicesPatch = func(project string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+		if !f.global {
+			t.Errorf("Global backend service %s was not expected to be patched", backendServiceName)
+		}
+		f.patched = backendService
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return mockComputeService
+}
+
+func TestReconcile(t *testing.T) {
+	cases := []struct {
+		name                   string
+		scope                  machinev1.GCPBackendServiceScope
+		machines               []*machinev1.Machine
+		groups                 map[string][]string
+		backends               []string
+		expectedCreated        []string
+		expectedNetworkProject string
+		expectedAdded          []string
+		expectedRemoved        []string
+		expectedDeleted        []string
+		expectedBackends       []string
+		expectedStatus         map[string]string
+		expectedRequeue        time.Duration
+	}{
+		{
+			name:            "Create the instance group of a new zone",
+			machines:        []*machinev1.Machine{newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false)},
+			groups:          map[string][]string{},
+			expectedCreated: []string{"CLUSTERID-master-zone1"},
+			expectedStatus: map[string]string{
+				backendServiceKey: "CLUSTERID-api-internal",
+			},
+			expectedRequeue: requeueAfter,
+		},
+		{
+			name:                   "Create the instance group of a new zone in the Shared VPC host project",
+			machines:               []*machinev1.Machine{newSharedVPCControlPlaneMachine(t, "master-0", "zone1", "host", "RUNNING", false)},
+			groups:                 map[string][]string{},
+			expectedCreated:        []string{"CLUSTERID-master-zone1"},
+			expectedNetworkProject: "host",
+			expectedStatus: map[string]string{
+				backendServiceKey: "CLUSTERID-api-internal",
+			},
+			expectedRequeue: requeueAfter,
+		},
+		{
+			name: "Add missing members, remove stale members and register the backend",
+			machines: []*machinev1.Machine{
+				newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false),
+				newControlPlaneMachine(t, "master-1", "zone1", "RUNNING", false),
+				newControlPlaneMachine(t, "master-2", "zone1", "", false),
+				newControlPlaneMachine(t, "master-3", "zone1", "STOPPED", false),
+			},
+			groups:           map[string][]string{"zone1": {"master-1", "master-old"}},
+			expectedAdded:    []string{instanceLink("zone1", "master-0"), instanceLink("zone1", "master-3")},
+			expectedRemoved:  []string{instanceLink("zone1", "master-old")},
+			expectedBackends: []string{groupLink("zone1")},
+			expectedStatus: map[string]string{
+				backendServiceKey:        "CLUSTERID-api-internal",
+				"CLUSTERID-master-zone1": "master-0,master-1,master-3",
+			},
+			expectedRequeue: resyncPeriod,
+		},
+		{
+			name: "Remove the instances of deleted machines",
+			machines: []*machinev1.Machine{
+				newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false),
+				newControlPlaneMachine(t, "master-1", "zone1", "RUNNING", true),
+			},
+			groups:          map[string][]string{"zone1": {"master-0", "master-1"}},
+			backends:        []string{groupLink("zone1")},
+			expectedRemoved: []string{instanceLink("zone1", "master-1")},
+			expectedStatus: map[string]string{
+				backendServiceKey:        "CLUSTERID-api-internal",
+				"CLUSTERID-master-zone1": "master-0",
+			},
+			expectedRequeue: resyncPeriod,
+		},
+		{
+			name:             "Detach the instance group of a zone without control plane machines",
+			machines:         []*machinev1.Machine{newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false)},
+			groups:           map[string][]string{"zone1": {"master-0"}, "zone2": {"master-1"}},
+			backends:         []string{groupLink("zone1"), groupLink("zone2")},
+			expectedRemoved:  []string{instanceLink("zone2", "master-1")},
+			expectedBackends: []string{groupLink("zone1")},
+			expectedStatus: map[string]string{
+				backendServiceKey:        "CLUSTERID-api-internal",
+				"CLUSTERID-master-zone1": "master-0",
+			},
+			expectedRequeue: requeueAfter,
+		},
+		{
+			name:            "Delete the detached instance group of a zone without control plane machines",
+			machines:        []*machinev1.Machine{newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false)},
+			groups:          map[string][]string{"zone1": {"master-0"}, "zone2": {}},
+			backends:        []string{groupLink("zone1")},
+			expectedDeleted: []string{"CLUSTERID-master-zone2"},
+			expectedStatus: map[string]string{
+				backendServiceKey:        "CLUSTERID-api-internal",
+				"CLUSTERID-master-zone1": "master-0",
+			},
+			expectedRequeue: resyncPeriod,
+		},
+		{
+			name:             "Register the backend in a global backend service",
+			scope:            machinev1.BackendServiceScopeGlobal,
+			machines:         []*machinev1.Machine{newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false)},
+			groups:           map[string][]string{"zone1": {"master-0"}},
+			expectedBackends: []string{groupLink("zone1")},
+			expectedStatus: map[string]string{
+				backendServiceKey:        "CLUSTERID-api-internal",
+				"CLUSTERID-master-zone1": "master-0",
+			},
+			expectedRequeue: resyncPeriod,
+		},
+		{
+			name:     "Keep the instance groups when there are no control plane machines",
+			groups:   map[string][]string{"zone1": {"master-0"}},
+			backends: []string{groupLink("zone1")},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := clientgoscheme.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			if err := machinev1.AddToScheme(scheme); err != nil {
+				t.Fatal(err)
+			}
+			var objects []client.Object
+			for _, machine := range tc.machines {
+				objects = append(objects, machine)
+			}
+			fakeClient := controllerfake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+			cloud := &fakeCloud{groups: tc.groups, backends: tc.backends, global: tc.scope == machinev1.BackendServiceScopeGlobal}
+			mockComputeService := cloud.computeService(t)
+			r := &Reconciler{
+				Client:              fakeClient,
+				Log:                 ctrl.Log,
+				BackendServiceScope: tc.scope,
+				getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, string, error) {
+					return mockComputeService, "test", nil
+				},
+			}
+
+			result, err := r.reconcile(context.Background(), testNamespace)
+			if err != nil {
+				t.Fatalf("reconciler was not expected to return error: %v", err)
+			}
+			if result.RequeueAfter != tc.expectedRequeue {
+				t.Errorf("Expected requeue after %v, got: %v", tc.expectedRequeue, result.RequeueAfter)
+			}
+
+			networkProject := tc.expectedNetworkProject
+			if networkProject == "" {
+				networkProject = "test"
+			}
+			var created []string
+			for _, group := range cloud.created {
+				created = append(created, group.Name)
+				if group.Network != "projects/"+networkProject+"/global/networks/network" || group.Subnetwork != "projects/"+networkProject+"/regions/region1/subnetworks/subnetwork" {
+					t.Errorf("Expected instance group %s in the network of its machines, got: %s %s", group.Name, group.Network, group.Subnetwork)
+				}
+			}
+			assertStrings(t, "created instance groups", tc.expectedCreated, created)
+			assertStrings(t, "added instances", tc.expectedAdded, cloud.added)
+			assertStrings(t, "removed instances", tc.expectedRemoved, cloud.removed)
+			assertStrings(t, "deleted instance groups", tc.expectedDeleted, cloud.deleted)
+
+			if tc.expectedBackends == nil {
+				if cloud.patched != nil {
+					t.Errorf("Backend service was not expected to be patched, got: %v", cloud.patched.Backends)
+				}
+			} else {
+				var backends []string
+				for _, backend := range cloud.patched.Backends {
+					backends = append(backends, backend.Group)
+				}
+				assertStrings(t, "backends", tc.expectedBackends, backends)
+				if cloud.patched.Fingerprint != "fingerprint" {
+					t.Errorf("Expected the backend service to be patched with its fingerprint, got: %q", cloud.patched.Fingerprint)
+				}
+			}
+
+			configMap := &corev1.ConfigMap{}
+			err = fakeClient.Get(context.Background(), client.ObjectKey{Namespace: testNamespace, Name: statusConfigMapName}, configMap)
+			if tc.expectedStatus == nil {
+				if err == nil {
+					t.Errorf("Status configmap was not expected to be created, got: %v", configMap.Data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to get status configmap: %v", err)
+			}
+			if !reflect.DeepEqual(configMap.Data, tc.expectedStatus) {
+				t.Errorf("Expected status: %v, got: %v", tc.expectedStatus, configMap.Data)
+			}
+		})
+	}
+}
+
+func TestReconcilePendingInstanceGroupInsert(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	machine := newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false)
+	fakeClient := controllerfake.NewClientBuilder().WithScheme(scheme).WithObjects(machine).Build()
+
+	cloud := &fakeCloud{groups: map[string][]string{}, insertStatus: "RUNNING", operations: map[string]*compute.Operation{}}
+	mockComputeService := cloud.computeService(t)
+	r := &Reconciler{
+		Client: fakeClient,
+		Log:    ctrl.Log,
+		getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, string, error) {
+			return mockComputeService, "test", nil
+		},
+	}
+	reconcile := func() (ctrl.Result, error) {
+		t.Helper()
+		return r.reconcile(context.Background(), testNamespace)
+	}
+
+	// The instance group is created by a running operation
+	if result, err := reconcile(); err != nil || result.RequeueAfter != requeueAfter {
+		t.Fatalf("Expected a requeue after %v, got: %v %v", requeueAfter, result, err)
+	}
+	// The instance group already exists while the operation is running, but gets no members
+	cloud.groups["zone1"] = nil
+	cloud.operations["insert-CLUSTERID-master-zone1"] = &compute.Operation{Status: "RUNNING"}
+	if result, err := reconcile(); err != nil || result.RequeueAfter != requeueAfter {
+		t.Fatalf("Expected a requeue after %v, got: %v %v", requeueAfter, result, err)
+	}
+	if len(cloud.created) != 1 || len(cloud.added) != 0 || cloud.patched != nil {
+		t.Fatalf("Expected a single instance group without members while it is created, got: created %d, added %v", len(cloud.created), cloud.added)
+	}
+
+	// The instance group gets its members once the operation is done
+	cloud.operations["insert-CLUSTERID-master-zone1"] = &compute.Operation{Status: "DONE"}
+	if result, err := reconcile(); err != nil || result.RequeueAfter != resyncPeriod {
+		t.Fatalf("Expected a requeue after %v, got: %v %v", resyncPeriod, result, err)
+	}
+	assertStrings(t, "added instances", []string{instanceLink("zone1", "master-0")}, cloud.added)
+}
+
+func TestReconcileFailedInstanceGroupInsert(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	machine := newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false)
+	fakeClient := controllerfake.NewClientBuilder().WithScheme(scheme).WithObjects(machine).Build()
+
+	cloud := &fakeCloud{groups: map[string][]string{}, insertStatus: "RUNNING", operations: map[string]*compute.Operation{}}
+	mockComputeService := cloud.computeService(t)
+	r := &Reconciler{
+		Client: fakeClient,
+		Log:    ctrl.Log,
+		getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, string, error) {
+			return mockComputeService, "test", nil
+		},
+	}
+
+	if _, err := r.reconcile(context.Background(), testNamespace); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+	cloud.operations["insert-CLUSTERID-master-zone1"] = &compute.Operation{
+		Status: "DONE",
+		Error:  &compute.OperationError{Errors: []*compute.OperationErrorErrors{{Message: "subnetwork not found"}}},
+	}
+	if _, err := r.reconcile(context.Background(), testNamespace); err == nil {
+		t.Fatal("Expected the failed insert operation to be reported")
+	}
+
+	// The instance group is created again once the failure has been reported
+	if _, err := r.reconcile(context.Background(), testNamespace); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+	if len(cloud.created) != 2 {
+		t.Errorf("Expected the instance group to be created again, got %d inserts", len(cloud.created))
+	}
+}
+
+func assertStrings(t *testing.T, what string, expected, got []string) {
+	t.Helper()
+	sort.Strings(got)
+	if len(expected) == 0 && len(got) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Expected %s: %v, got: %v", what, expected, got)
+	}
+}
+
+func TestToNamespaceRequest(t *testing.T) {
+	machine := newControlPlaneMachine(t, "master-0", "zone1", "RUNNING", false)
+	requests := toNamespaceRequest(context.Background(), machine)
+	if len(requests) != 1 || requests[0].Namespace != testNamespace || requests[0].Name != statusConfigMapName {
+		t.Errorf("Expected a single request for namespace %s, got: %v", testNamespace, requests)
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
@@ -33,7 +35,11 @@ type Actuator struct {
 	eventRecorder        record.EventRecorder
 	computeClientBuilder computeservice.BuilderFuncType
 	tagsClientBuilder    tagservice.BuilderFuncType
+	kmsClientBuilder     kmsservice.BuilderFuncType
+	dnsClientBuilder     dnsservice.BuilderFuncType
 	featureGates         featuregate.FeatureGate
+
+	controlPlaneBackendServiceScope machinev1.GCPBackendServiceScope
 }
 
 // ActuatorParams holds parameter information for Actuator.
@@ -42,7 +48,12 @@ type ActuatorParams struct {
 	EventRecorder        record.EventRecorder
 	ComputeClientBuilder computeservice.BuilderFuncType
 	TagsClientBuilder    tagservice.BuilderFuncType
+	KMSClientBuilder     kmsservice.BuilderFuncType
+	DNSClientBuilder     dnsservice.BuilderFuncType
 	FeatureGates         featuregate.FeatureGate
+
+	// ControlPlaneBackendServiceScope is the scope of the backend service of the internal API load balancer.
+	ControlPlaneBackendServiceScope machinev1.GCPBackendServiceScope
 }
 
 // NewActuator returns an actuator.
@@ -52,7 +63,11 @@ func NewActuator(params ActuatorParams) *Actuator {
 		eventRecorder:        params.EventRecorder,
 		computeClientBuilder: params.ComputeClientBuilder,
 		tagsClientBuilder:    params.TagsClientBuilder,
+		kmsClientBuilder:     params.KMSClientBuilder,
+		dnsClientBuilder:     params.DNSClientBuilder,
 		featureGates:         params.FeatureGates,
+
+		controlPlaneBackendServiceScope: params.ControlPlaneBackendServiceScope,
 	}
 }
 
@@ -75,7 +90,12 @@ func (a *Actuator) Create(ctx context.Context, machine *machinev1.Machine) error
 		machine:              machine,
 		computeClientBuilder: a.computeClientBuilder,
 		tagsClientBuilder:    a.tagsClientBuilder,
+		kmsClientBuilder:     a.kmsClientBuilder,
+		dnsClientBuilder:     a.dnsClientBuilder,
 		featureGates:         a.featureGates,
+		eventRecorder:        a.eventRecorder,
+
+		controlPlaneBackendServiceScope: a.controlPlaneBackendServiceScope,
 	})
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
@@ -99,7 +119,12 @@ func (a *Actuator) Exists(ctx context.Context, machine *machinev1.Machine) (bool
 		machine:              machine,
 		computeClientBuilder: a.computeClientBuilder,
 		tagsClientBuilder:    a.tagsClientBuilder,
+		kmsClientBuilder:     a.kmsClientBuilder,
+		dnsClientBuilder:     a.dnsClientBuilder,
 		featureGates:         a.featureGates,
+		eventRecorder:        a.eventRecorder,
+
+		controlPlaneBackendServiceScope: a.controlPlaneBackendServiceScope,
 	})
 	if err != nil {
 		return false, fmt.Errorf(scopeFailFmt, machine.Name, err)
@@ -140,7 +165,12 @@ func (a *Actuator) Update(ctx context.Context, machine *machinev1.Machine) error
 		machine:              machine,
 		computeClientBuilder: a.computeClientBuilder,
 		tagsClientBuilder:    a.tagsClientBuilder,
+		kmsClientBuilder:     a.kmsClientBuilder,
+		dnsClientBuilder:     a.dnsClientBuilder,
 		featureGates:         a.featureGates,
+		eventRecorder:        a.eventRecorder,
+
+		controlPlaneBackendServiceScope: a.controlPlaneBackendServiceScope,
 	})
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
@@ -177,13 +207,20 @@ func (a *Actuator) Delete(ctx context.Context, machine *machinev1.Machine) error
 		machine:              machine,
 		computeClientBuilder: a.computeClientBuilder,
 		tagsClientBuilder:    a.tagsClientBuilder,
+		kmsClientBuilder:     a.kmsClientBuilder,
+		dnsClientBuilder:     a.dnsClientBuilder,
 		featureGates:         a.featureGates,
+		eventRecorder:        a.eventRecorder,
+
+		controlPlaneBackendServiceScope: a.controlPlaneBackendServiceScope,
 	})
 	if err != nil {
 		fmtErr := fmt.Errorf(scopeFailFmt, machine.GetName(), err)
 		return a.handleMachineError(machine, fmtErr, deleteEventAction)
 	}
 	if err := newReconciler(scope).delete(); err != nil {
+		// Update machine status in case retained disks or snapshots were recorded
+		scope.Close()
 		fmtErr := fmt.Errorf(reconcilerFailFmt, machine.GetName(), deleteEventAction, err)
 		return a.handleMachineError(machine, fmtErr, deleteEventAction)
 	}
@@ -15,6 +15,8 @@ import (
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	"github.com/openshift/library-go/pkg/features"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
 	"github.com/openshift/machine-api-provider-gcp/pkg/version"
@@ -250,6 +252,8 @@ func TestActuatorEvents(t *testing.T) {
 			operation: func(actuator *Actuator, machine *machinev1.Machine) {
 				actuator.computeClientBuilder = computeservice.MockBuilderFuncTypeNotFound
 				actuator.tagsClientBuilder = tagservice.NewMockTagServiceBuilder
+				actuator.kmsClientBuilder = kmsservice.NewMockKMSServiceBuilder
+				actuator.dnsClientBuilder = dnsservice.NewFakeDNSServiceBuilder(dnsservice.NewFakeDNSService())
 				actuator.Delete(context.Background(), machine)
 			},
 			event: "Deleted machine test",
@@ -320,6 +324,8 @@ func TestActuatorEvents(t *testing.T) {
 				EventRecorder:        eventRecorder,
 				ComputeClientBuilder: computeservice.MockBuilderFuncType,
 				TagsClientBuilder:    tagservice.NewMockTagServiceBuilder,
+				KMSClientBuilder:     kmsservice.NewMockKMSServiceBuilder,
+				DNSClientBuilder:     dnsservice.NewFakeDNSServiceBuilder(dnsservice.NewFakeDNSService()),
 				FeatureGates:         gate,
 			}
 
@@ -424,6 +430,8 @@ func TestActuatorExists(t *testing.T) {
 				CoreClient:           controllerfake.NewFakeClient(userDataSecret, credentialsSecret),
 				ComputeClientBuilder: computeservice.MockBuilderFuncType,
 				TagsClientBuilder:    tagservice.NewMockTagServiceBuilder,
+				KMSClientBuilder:     kmsservice.NewMockKMSServiceBuilder,
+				DNSClientBuilder:     dnsservice.NewFakeDNSServiceBuilder(dnsservice.NewFakeDNSService()),
 				FeatureGates:         gate,
 			}
 
@@ -0,0 +1,63 @@
+package machine
+
+import (
+	"net"
+	"strings"
+
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// instanceNodeAddresses returns the IP addresses of all the network interfaces of the instance.
+// Interfaces are reported in the order of the instance, each as its internal IPv4 address,
+// single address alias IP ranges and internal IPv6 address followed by its external IPv4 and IPv6 addresses.
+func instanceNodeAddresses(instance *compute.Instance) []corev1.NodeAddress {
+	var nodeAddresses []corev1.NodeAddress
+	seen := map[corev1.NodeAddress]bool{}
+	add := func(addressType corev1.NodeAddressType, address string) {
+		nodeAddress := corev1.NodeAddress{Type: addressType, Address: address}
+		if address == "" || seen[nodeAddress] {
+			return
+		}
+		seen[nodeAddress] = true
+		nodeAddresses = append(nodeAddresses, nodeAddress)
+	}
+
+	for _, networkInterface := range instance.NetworkInterfaces {
+		add(corev1.NodeInternalIP, networkInterface.NetworkIP)
+		for _, aliasIPRange := range networkInterface.AliasIpRanges {
+			add(corev1.NodeInternalIP, aliasIPRangeAddress(aliasIPRange.IpCidrRange))
+		}
+		add(corev1.NodeInternalIP, networkInterface.Ipv6Address)
+
+		for _, config := range networkInterface.AccessConfigs {
+			add(corev1.NodeExternalIP, config.NatIP)
+		}
+		for _, config := range networkInterface.Ipv6AccessConfigs {
+			add(corev1.NodeExternalIP, config.ExternalIpv6)
+		}
+	}
+
+	return nodeAddresses
+}
+
+// aliasIPRangeAddress returns the address of an alias IP range made of a single address,
+// e.g. "10.1.2.3" or "10.1.2.3/32". Larger ranges, which are typically used for pod IPs,
+// are not addresses of the node and an empty string is returned for them.
+func aliasIPRangeAddress(ipCidrRange string) string {
+	if !strings.Contains(ipCidrRange, "/") {
+		if ip := net.ParseIP(ipCidrRange); ip != nil {
+			return ip.String()
+		}
+		return ""
+	}
+
+	ip, ipNet, err := net.ParseCIDR(ipCidrRange)
+	if err != nil {
+		return ""
+	}
+	if ones, bits := ipNet.Mask.Size(); ones != bits {
+		return ""
+	}
+	return ip.String()
+}
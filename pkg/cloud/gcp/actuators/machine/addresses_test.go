@@ -0,0 +1,105 @@
+package machine
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestInstanceNodeAddresses(t *testing.T) {
+	cases := []struct {
+		name              string
+		networkInterfaces []*compute.NetworkInterface
+		expectedAddresses []corev1.NodeAddress
+	}{
+		{
+			name: "Single interface with external IP",
+			networkInterfaces: []*compute.NetworkInterface{
+				{
+					NetworkIP:     "10.0.0.15",
+					AccessConfigs: []*compute.AccessConfig{{NatIP: "35.243.147.143"}},
+				},
+			},
+			expectedAddresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.15"},
+				{Type: corev1.NodeExternalIP, Address: "35.243.147.143"},
+			},
+		},
+		{
+			name: "Multiple interfaces are reported in order",
+			networkInterfaces: []*compute.NetworkInterface{
+				{
+					NetworkIP: "10.0.0.15",
+				},
+				{
+					NetworkIP:     "10.1.0.7",
+					AccessConfigs: []*compute.AccessConfig{{NatIP: "35.243.147.144"}},
+				},
+			},
+			expectedAddresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.15"},
+				{Type: corev1.NodeInternalIP, Address: "10.1.0.7"},
+				{Type: corev1.NodeExternalIP, Address: "35.243.147.144"},
+			},
+		},
+		{
+			name: "Single address alias IP ranges are reported, larger ranges are not",
+			networkInterfaces: []*compute.NetworkInterface{
+				{
+					NetworkIP: "10.0.0.15",
+					AliasIpRanges: []*compute.AliasIpRange{
+						{IpCidrRange: "10.0.1.0/24"},
+						{IpCidrRange: "10.0.2.5/32"},
+						{IpCidrRange: "10.0.2.6"},
+					},
+				},
+			},
+			expectedAddresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.15"},
+				{Type: corev1.NodeInternalIP, Address: "10.0.2.5"},
+				{Type: corev1.NodeInternalIP, Address: "10.0.2.6"},
+			},
+		},
+		{
+			name: "Dual-stack interface",
+			networkInterfaces: []*compute.NetworkInterface{
+				{
+					NetworkIP:         "10.0.0.15",
+					Ipv6Address:       "fd20:1:2:3::",
+					AccessConfigs:     []*compute.AccessConfig{{NatIP: "35.243.147.143"}},
+					Ipv6AccessConfigs: []*compute.AccessConfig{{ExternalIpv6: "2600:1900:4000:1::"}},
+				},
+			},
+			expectedAddresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.15"},
+				{Type: corev1.NodeInternalIP, Address: "fd20:1:2:3::"},
+				{Type: corev1.NodeExternalIP, Address: "35.243.147.143"},
+				{Type: corev1.NodeExternalIP, Address: "2600:1900:4000:1::"},
+			},
+		},
+		{
+			name: "Empty and duplicate addresses are skipped",
+			networkInterfaces: []*compute.NetworkInterface{
+				{
+					NetworkIP:     "10.0.0.15",
+					AccessConfigs: []*compute.AccessConfig{{}, {NatIP: "35.243.147.143"}, {NatIP: "35.243.147.143"}},
+				},
+			},
+			expectedAddresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "10.0.0.15"},
+				{Type: corev1.NodeExternalIP, Address: "35.243.147.143"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			addresses := instanceNodeAddresses(&compute.Instance{NetworkInterfaces: tc.networkInterfaces})
+			if !reflect.DeepEqual(addresses, tc.expectedAddresses) {
+				t.Errorf("Expected: %v, got: %v", tc.expectedAddresses, addresses)
+			}
+		})
+	}
+}
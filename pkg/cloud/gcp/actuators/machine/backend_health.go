@@ -0,0 +1,145 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// backendHealthyConditionType reports whether the backend service of the internal API load balancer
+	// considers the instance of a control plane machine healthy.
+	backendHealthyConditionType = "BackendHealthy"
+	backendHealthyReason        = "BackendHealthy"
+	backendUnhealthyReason      = "BackendUnhealthy"
+	backendHealthUnknownReason  = "BackendHealthUnknown"
+
+	// backendDrainedConditionType reports whether the connections to the instance of a control plane
+	// machine being deleted have been drained. Its last transition time is the time the instance
+	// was removed from its instance group.
+	backendDrainedConditionType = "BackendDrained"
+	backendDrainingReason       = "ConnectionDraining"
+	backendDrainedReason        = "ConnectionsDrained"
+
+	healthStateHealthy = "HEALTHY"
+)
+
+// reconcileBackendHealth sets the BackendHealthy condition of control plane machines from the health
+// of the instance in the backend service of the internal API load balancer. Its health is only read once
+// the instance group is a backend of the backend service, and a failure to read it is reported in the
+// condition rather than failing the reconciliation. An unhealthy instance does not fail the update of
+// the machine either, the condition is refreshed on the next reconciliation.
+func (r *Reconciler) reconcileBackendHealth() {
+	if r.machine.Labels[openshiftMachineRoleLabel] != masterMachineRole {
+		return
+	}
+
+	attachment := r.controlPlaneInstanceGroupAttachment()
+	condition := metav1.Condition{
+		Type:   backendHealthyConditionType,
+		Status: metav1.ConditionUnknown,
+		Reason: backendHealthUnknownReason,
+	}
+
+	backendService, err := r.getBackendService(attachment)
+	if err != nil {
+		klog.Warningf("%s: failed to get backend service %s, skipping its health check: %v", r.machine.Name, attachment.backendService, err)
+		condition.Message = fmt.Sprintf("failed to get backend service %s: %v", attachment.backendService, err)
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+		return
+	}
+	// The control plane controller adds the instance group to the backend service while the cluster bootstraps
+	group := r.FQDNInstanceGroup(attachment.instanceGroup)
+	if !hasBackend(backendService, group) {
+		condition.Message = fmt.Sprintf("instance group %s is not a backend of backend service %s yet", attachment.instanceGroup, attachment.backendService)
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+		return
+	}
+
+	health, err := r.getBackendServiceHealth(attachment)
+	if err != nil {
+		klog.Warningf("%s: failed to get the health of backend service %s: %v", r.machine.Name, attachment.backendService, err)
+		condition.Message = fmt.Sprintf("failed to get the health of backend service %s: %v", attachment.backendService, err)
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+		return
+	}
+
+	var states []string
+	for _, status := range health.HealthStatus {
+		// The instance of a health status is the URL of the instance
+		if path.Base(status.Instance) == r.machine.Name {
+			states = append(states, status.HealthState)
+		}
+	}
+
+	switch {
+	case len(states) == 0:
+		condition.Message = fmt.Sprintf("instance is not a backend of backend service %s yet", attachment.backendService)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = backendHealthyReason
+		condition.Message = fmt.Sprintf("instance is healthy in backend service %s", attachment.backendService)
+		for _, state := range states {
+			if state != healthStateHealthy {
+				condition.Status = metav1.ConditionFalse
+				condition.Reason = backendUnhealthyReason
+				condition.Message = fmt.Sprintf("instance is %s in backend service %s", state, attachment.backendService)
+				break
+			}
+		}
+	}
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, condition)
+}
+
+// waitForBackendDrain waits for the connection draining timeout of the backend service of the internal
+// API load balancer to elapse once the instance of a control plane machine has been removed from its
+// instance group. The removal time is recorded in the BackendDrained condition, so that it is kept
+// between reconciliations.
+func (r *Reconciler) waitForBackendDrain() error {
+	if r.machine.Labels[openshiftMachineRoleLabel] != masterMachineRole {
+		return nil
+	}
+
+	attachment := r.controlPlaneInstanceGroupAttachment()
+	backendService, err := r.getBackendService(attachment)
+	if err != nil {
+		return fmt.Errorf("backendServiceGet request failed: %v", err)
+	}
+	var timeout time.Duration
+	if backendService.ConnectionDraining != nil {
+		timeout = time.Duration(backendService.ConnectionDraining.DrainingTimeoutSec) * time.Second
+	}
+
+	condition := findCondition(r.providerStatus.Conditions, backendDrainedConditionType)
+	if condition != nil && condition.Status == metav1.ConditionTrue {
+		return nil
+	}
+	if condition == nil && timeout > 0 {
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    backendDrainedConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  backendDrainingReason,
+			Message: fmt.Sprintf("waiting %s for connections to backend service %s to drain", timeout, attachment.backendService),
+		})
+		condition = findCondition(r.providerStatus.Conditions, backendDrainedConditionType)
+	}
+
+	if condition != nil {
+		if remaining := timeout - time.Since(condition.LastTransitionTime.Time); remaining > 0 {
+			klog.Infof("%s: waiting %s for connections to drain, requeuing...", r.machine.Name, remaining.Round(time.Second))
+			return &machinecontroller.RequeueAfterError{RequeueAfter: remaining}
+		}
+	}
+
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    backendDrainedConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  backendDrainedReason,
+		Message: fmt.Sprintf("connections to backend service %s are drained", attachment.backendService),
+	})
+	return nil
+}
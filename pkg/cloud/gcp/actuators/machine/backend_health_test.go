@@ -0,0 +1,234 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newBackendHealthReconciler(role string, scope machinev1.GCPBackendServiceScope, mockComputeService *computeservice.GCPComputeServiceMock, conditions ...metav1.Condition) *Reconciler {
+	return newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "machine-0",
+				Labels: map[string]string{
+					openshiftMachineRoleLabel:       role,
+					machinev1.MachineClusterIDLabel: "CLUSTERID",
+				},
+			},
+		},
+		coreClient: controllerfake.NewFakeClient(),
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Region: "region1",
+			Zone:   "zone1",
+		},
+		projectID: "test",
+		providerStatus: &machinev1.GCPMachineProviderStatus{
+			Conditions: conditions,
+		},
+		computeService:                  mockComputeService,
+		controlPlaneBackendServiceScope: scope,
+	})
+}
+
+func TestReconcileBackendHealth(t *testing.T) {
+	instanceURL := "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instances/machine-0"
+
+	cases := []struct {
+		name           string
+		role           string
+		scope          machinev1.GCPBackendServiceScope
+		notBackend     bool
+		healthStatus   []*compute.HealthStatus
+		healthErr      error
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "Healthy backend",
+			role:           masterMachineRole,
+			healthStatus:   []*compute.HealthStatus{{Instance: instanceURL, HealthState: "HEALTHY"}},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: backendHealthyReason,
+		},
+		{
+			name: "Unhealthy backend",
+			role: masterMachineRole,
+			healthStatus: []*compute.HealthStatus{
+				{Instance: instanceURL, HealthState: "UNHEALTHY"},
+				{Instance: "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instances/machine-1", HealthState: "HEALTHY"},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: backendUnhealthyReason,
+		},
+		{
+			name: "Instance is not a backend yet",
+			role: masterMachineRole,
+			healthStatus: []*compute.HealthStatus{
+				{Instance: "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instances/machine-1", HealthState: "HEALTHY"},
+			},
+			expectedStatus: metav1.ConditionUnknown,
+			expectedReason: backendHealthUnknownReason,
+		},
+		{
+			name:           "Instance group is not a backend yet",
+			role:           masterMachineRole,
+			notBackend:     true,
+			expectedStatus: metav1.ConditionUnknown,
+			expectedReason: backendHealthUnknownReason,
+		},
+		{
+			name:           "Health cannot be read",
+			role:           masterMachineRole,
+			healthErr:      errors.New("backend service is not ready"),
+			expectedStatus: metav1.ConditionUnknown,
+			expectedReason: backendHealthUnknownReason,
+		},
+		{
+			name:           "Healthy backend of a global backend service",
+			role:           masterMachineRole,
+			scope:          machinev1.BackendServiceScopeGlobal,
+			healthStatus:   []*compute.HealthStatus{{Instance: instanceURL, HealthState: "HEALTHY"}},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: backendHealthyReason,
+		},
+		{
+			name: "Worker machines are not checked",
+			role: "worker",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			backendService := func() (*compute.BackendService, error) {
+				if tc.notBackend {
+					return &compute.BackendService{}, nil
+				}
+				return &compute.BackendService{Backends: []*compute.Backend{
+					{Group: "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instanceGroups/CLUSTERID-master-zone1"},
+				}}, nil
+			}
+			mockComputeService.MockBackendServiceGet = func(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+				return backendService()
+			}
+			mockComputeService.MockGlobalBackendServiceGet = func(project string, backendServiceName string) (*compute.BackendService, error) {
+				return backendService()
+			}
+			mockComputeService.MockBackendServiceGetHealth = func(project string, region string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error) {
+				if tc.notBackend {
+					t.Errorf("Unexpected health request for an instance group which is not a backend")
+				}
+				if tc.healthErr != nil {
+					return nil, tc.healthErr
+				}
+				if tc.scope == machinev1.BackendServiceScopeGlobal {
+					t.Errorf("Unexpected regional health request for %s", backendServiceName)
+				}
+				if backendServiceName != "CLUSTERID-api-internal" || instanceGroup != "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instanceGroups/CLUSTERID-master-zone1" {
+					t.Errorf("Unexpected health request for %s and %s", backendServiceName, instanceGroup)
+				}
+				return &compute.BackendServiceGroupHealth{HealthStatus: tc.healthStatus}, nil
+			}
+			mockComputeService.MockGlobalBackendServiceGetHealth = func(project string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error) {
+				if tc.scope != machinev1.BackendServiceScopeGlobal {
+					t.Errorf("Unexpected global health request for %s", backendServiceName)
+				}
+				return &compute.BackendServiceGroupHealth{HealthStatus: tc.healthStatus}, nil
+			}
+			r := newBackendHealthReconciler(tc.role, tc.scope, mockComputeService)
+
+			r.reconcileBackendHealth()
+
+			condition := findCondition(r.providerStatus.Conditions, backendHealthyConditionType)
+			if tc.expectedStatus == "" {
+				if condition != nil {
+					t.Errorf("Expected no %s condition, got: %v", backendHealthyConditionType, condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != tc.expectedStatus || condition.Reason != tc.expectedReason {
+				t.Errorf("Expected %s condition with status %s and reason %s, got: %v", backendHealthyConditionType, tc.expectedStatus, tc.expectedReason, condition)
+			}
+		})
+	}
+}
+
+func TestWaitForBackendDrain(t *testing.T) {
+	cases := []struct {
+		name            string
+		drainingTimeout int64
+		conditions      []metav1.Condition
+		expectRequeue   bool
+		expectedStatus  metav1.ConditionStatus
+	}{
+		{
+			name:           "No connection draining",
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:            "Start draining",
+			drainingTimeout: 300,
+			expectRequeue:   true,
+			expectedStatus:  metav1.ConditionFalse,
+		},
+		{
+			name:            "Draining in progress",
+			drainingTimeout: 300,
+			conditions: []metav1.Condition{{
+				Type:               backendDrainedConditionType,
+				Status:             metav1.ConditionFalse,
+				Reason:             backendDrainingReason,
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+			}},
+			expectRequeue:  true,
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:            "Draining complete",
+			drainingTimeout: 30,
+			conditions: []metav1.Condition{{
+				Type:               backendDrainedConditionType,
+				Status:             metav1.ConditionFalse,
+				Reason:             backendDrainingReason,
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+			}},
+			expectedStatus: metav1.ConditionTrue,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockBackendServiceGet = func(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+				return &compute.BackendService{
+					Name:               backendServiceName,
+					ConnectionDraining: &compute.ConnectionDraining{DrainingTimeoutSec: tc.drainingTimeout},
+				}, nil
+			}
+			r := newBackendHealthReconciler(masterMachineRole, machinev1.BackendServiceScopeRegional, mockComputeService, tc.conditions...)
+
+			err := r.waitForBackendDrain()
+			var requeueErr *machinecontroller.RequeueAfterError
+			if requeued := errors.As(err, &requeueErr); requeued != tc.expectRequeue {
+				t.Errorf("Expected requeue: %v, got: %v", tc.expectRequeue, err)
+			} else if !requeued && err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			} else if requeued && requeueErr.RequeueAfter > time.Duration(tc.drainingTimeout)*time.Second {
+				t.Errorf("Expected to requeue within the draining timeout, got: %v", requeueErr.RequeueAfter)
+			}
+
+			condition := findCondition(r.providerStatus.Conditions, backendDrainedConditionType)
+			if condition == nil || condition.Status != tc.expectedStatus {
+				t.Errorf("Expected %s condition with status %s, got: %v", backendDrainedConditionType, tc.expectedStatus, condition)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// bulkInsertMaxCount is the maximum number of instances of a bulk insert request.
+	bulkInsertMaxCount = 1000
+	// bulkInsertTargetShape keeps the instances of a regional bulk insert in a single zone.
+	bulkInsertTargetShape = "ANY_SINGLE_ZONE"
+
+	locationPreferenceAllow = "ALLOW"
+)
+
+func validateBulkInsert(machine machinev1.Machine, providerSpec machinev1.GCPMachineProviderSpec) error {
+	bulkInsert := providerSpec.BulkInsert
+	if bulkInsert == nil {
+		return nil
+	}
+
+	if machineSetName(&machine) == "" {
+		return fmt.Errorf("bulk insert is only supported for machines that are part of a machine set")
+	}
+	switch bulkInsert.Targeting {
+	case "", machinev1.BulkInsertTargetingZonal:
+		if len(bulkInsert.Zones) > 0 {
+			return fmt.Errorf("bulk insert zones can only be set with the %q targeting", machinev1.BulkInsertTargetingRegional)
+		}
+	case machinev1.BulkInsertTargetingRegional:
+	default:
+		return fmt.Errorf("unknown bulk insert targeting %q, valid values are %q and %q", bulkInsert.Targeting, machinev1.BulkInsertTargetingZonal, machinev1.BulkInsertTargetingRegional)
+	}
+	if bulkInsert.MinCount < 0 {
+		return fmt.Errorf("bulk insert minimum count must not be negative")
+	}
+
+	// The instance properties of a bulk insert are shared by all its instances
+	if providerSpec.DeletionProtection {
+		return fmt.Errorf("bulk insert does not support deletion protection")
+	}
+	for _, nic := range providerSpec.NetworkInterfaces {
+		if nic.InternalAddress != nil || nic.ExternalAddress != nil {
+			return fmt.Errorf("bulk insert does not support static addresses")
+		}
+	}
+	return nil
+}
+
+// bulkInsert creates the instance of the machine along with the instances of the other pending machines
+// of its machine set. The other machines find their instance when they are reconciled, and the ones whose
+// instance could not be created retry with a bulk insert of the machines still pending.
+func (r *Reconciler) bulkInsert(instance *compute.Instance, sourceInstanceTemplate string) error {
+	existingInstances, err := r.existingInstances()
+	if err != nil {
+		return err
+	}
+	// The instance may have been created by the bulk insert of another machine, in another zone
+	if existing, ok := existingInstances[r.machine.Name]; ok {
+		r.setInstanceZone(existing)
+		return r.reconcileMachineWithCloudState(nil)
+	}
+
+	machines, err := r.pendingBulkInsertMachines(existingInstances)
+	if err != nil {
+		return err
+	}
+
+	resource := &compute.BulkInsertInstanceResource{
+		Count:                  int64(len(machines)),
+		MinCount:               int64(len(machines)),
+		InstanceProperties:     toInstanceProperties(instance),
+		PerInstanceProperties:  map[string]compute.BulkInsertInstanceResourcePerInstanceProperties{},
+		SourceInstanceTemplate: sourceInstanceTemplate,
+	}
+	if minCount := int64(r.providerSpec.BulkInsert.MinCount); minCount > 0 && minCount < resource.Count {
+		resource.MinCount = minCount
+	}
+	for _, machine := range machines {
+		hostname, err := instanceHostname(*machine, *r.providerSpec)
+		if err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+		resource.PerInstanceProperties[machine.Name] = compute.BulkInsertInstanceResourcePerInstanceProperties{
+			Name:     machine.Name,
+			Hostname: hostname,
+		}
+	}
+
+	klog.Infof("%s: creating %d instances of machine set %s in bulk, at least %d", r.machine.Name, resource.Count, machineSetName(r.machine), resource.MinCount)
+	var op *compute.Operation
+	if r.providerSpec.BulkInsert.Targeting == machinev1.BulkInsertTargetingRegional {
+		resource.LocationPolicy = r.bulkInsertLocationPolicy()
+		op, err = r.computeService.RegionInstancesBulkInsert(r.projectID, r.providerSpec.Region, resource)
+		if err == nil {
+			err = r.awaitRegionOperation(op)
+		}
+	} else {
+		op, err = r.computeService.InstancesBulkInsert(r.projectID, r.providerSpec.Zone, resource)
+		if err == nil {
+			err = r.awaitZoneOperation(op)
+		}
+	}
+	// The machines find their instance once the bulk insert is done
+	if isRequeueAfterError(err) {
+		return err
+	}
+	if isAlreadyExistsError(err) {
+		// Instances of the bulk insert were created by the bulk insert of another machine in the meantime,
+		// the machine finds its instance or creates it with the machines still pending
+		klog.Infof("%s: instances of machine set %s already exist, requeuing...", r.machine.Name, machineSetName(r.machine))
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+	if err != nil {
+		r.reportCreationFailure(err)
+		// Only a rejected request signals a misconfiguration, quota and permission errors may be transient
+		if googleError, ok := err.(*googleapi.Error); ok && googleError.Code == http.StatusBadRequest {
+			return machinecontroller.InvalidMachineConfiguration("error launching instances in bulk: %v", googleError.Error())
+		}
+		return fmt.Errorf("failed to create instances in bulk: %w", err)
+	}
+	klog.Infof("%s: bulk insert of machine set %s done: %s", r.machine.Name, machineSetName(r.machine), bulkInsertStatus(op))
+
+	existingInstances, err = r.existingInstances()
+	if err != nil {
+		return err
+	}
+	existing, ok := existingInstances[r.machine.Name]
+	if !ok {
+		err := fmt.Errorf("instance was not created by the bulk insert of %d instances: %s", resource.Count, bulkInsertStatus(op))
+		r.reportCreationFailure(err)
+		return err
+	}
+	r.setInstanceZone(existing)
+	return r.reconcileMachineWithCloudState(nil)
+}
+
+// existingInstances returns the instances the bulk insert could have created by name, which are the
+// instances of the machine set in the zone of the machine, or in the zones of the bulk insert with the
+// Regional targeting.
+func (r *Reconciler) existingInstances() (map[string]*compute.Instance, error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	zones, err := r.bulkInsertZones()
+	if err != nil {
+		return nil, err
+	}
+	// The machines of a machine set, and so their instances, are named after the machine set
+	filter := fmt.Sprintf("name eq \"%s-.*\"", regexp.QuoteMeta(machineSetName(r.machine)))
+	existing := map[string]*compute.Instance{}
+	for _, zone := range zones {
+		instances, err := r.computeService.InstancesList(ctx, r.projectID, zone, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list instances in zone %s: %w", zone, err)
+		}
+		for _, instance := range instances {
+			existing[instance.Name] = instance
+		}
+	}
+	return existing, nil
+}
+
+// bulkInsertZones returns the zones the bulk insert creates instances in, which are the zone of the machine,
+// or with the Regional targeting the zones of the bulk insert configuration, all the zones of the region by default.
+func (r *Reconciler) bulkInsertZones() ([]string, error) {
+	if r.providerSpec.BulkInsert.Targeting != machinev1.BulkInsertTargetingRegional {
+		return []string{r.providerSpec.Zone}, nil
+	}
+	if len(r.providerSpec.BulkInsert.Zones) > 0 {
+		return r.providerSpec.BulkInsert.Zones, nil
+	}
+
+	region, err := r.computeService.RegionGet(r.projectID, r.providerSpec.Region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get region %s: %w", r.providerSpec.Region, err)
+	}
+	return resourceNames(region.Zones), nil
+}
+
+// pendingBulkInsertMachines returns the machines of the machine set of the machine, with the same provider spec,
+// whose instance does not exist yet. The machine comes first, followed by the other machines ordered by name.
+func (r *Reconciler) pendingBulkInsertMachines(existingInstances map[string]*compute.Instance) ([]*machinev1.Machine, error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	providerSpec, err := util.ProviderSpecFromRawExtension(r.machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return nil, machinecontroller.InvalidMachineConfiguration("failed to get machine config: %v", err)
+	}
+
+	machines := &machinev1.MachineList{}
+	if err := r.coreClient.List(ctx, machines, controllerclient.InNamespace(r.machine.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	machineSet := machineSetName(r.machine)
+	pending := []*machinev1.Machine{}
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		if machine.Name == r.machine.Name || machine.DeletionTimestamp != nil || machine.Spec.ProviderID != nil || machineSetName(machine) != machineSet {
+			continue
+		}
+		if _, ok := existingInstances[machine.Name]; ok {
+			continue
+		}
+		machineProviderSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil || !equality.Semantic.DeepEqual(machineProviderSpec, providerSpec) {
+			continue
+		}
+		pending = append(pending, machine)
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Name < pending[j].Name })
+
+	pending = append([]*machinev1.Machine{r.machine}, pending...)
+	if len(pending) > bulkInsertMaxCount {
+		pending = pending[:bulkInsertMaxCount]
+	}
+	return pending, nil
+}
+
+// bulkInsertLocationPolicy restricts a regional bulk insert to the zones of the bulk insert configuration.
+func (r *Reconciler) bulkInsertLocationPolicy() *compute.LocationPolicy {
+	policy := &compute.LocationPolicy{
+		TargetShape: bulkInsertTargetShape,
+	}
+	if len(r.providerSpec.BulkInsert.Zones) > 0 {
+		policy.Locations = map[string]compute.LocationPolicyLocation{}
+		for _, zone := range r.providerSpec.BulkInsert.Zones {
+			policy.Locations["zones/"+zone] = compute.LocationPolicyLocation{Preference: locationPreferenceAllow}
+		}
+	}
+	return policy
+}
+
+// setInstanceZone records the zone the instance was created in in the providerStatus, when it differs
+// from the zone of the provider spec as the instance was created by a regional bulk insert. The machine
+// is then reconciled in the zone of its instance, the provider spec keeps its zone.
+func (r *Reconciler) setInstanceZone(instance *compute.Instance) {
+	zone := path.Base(instance.Zone)
+	if instance.Zone == "" || zone == r.providerSpec.Zone {
+		return
+	}
+	klog.Infof("%s: instance was created in zone %s rather than in zone %s", r.machine.Name, zone, r.providerSpec.Zone)
+	r.providerStatus.Zone = zone
+	r.providerSpec.Zone = zone
+	r.providerID = fmt.Sprintf("gce://%s/%s/%s", r.projectID, zone, r.machine.Name)
+}
+
+// bulkInsertStatus summarizes the instances created and failed to be created by a bulk insert operation.
+func bulkInsertStatus(op *compute.Operation) string {
+	if op == nil || op.InstancesBulkInsertOperationMetadata == nil {
+		return "no status reported"
+	}
+
+	locations := []string{}
+	for location := range op.InstancesBulkInsertOperationMetadata.PerLocationStatus {
+		locations = append(locations, location)
+	}
+	sort.Strings(locations)
+
+	statuses := []string{}
+	for _, location := range locations {
+		status := op.InstancesBulkInsertOperationMetadata.PerLocationStatus[location]
+		statuses = append(statuses, fmt.Sprintf("%s: %d of %d instances created, %d failed", path.Base(location), status.CreatedVmCount, status.TargetVmCount, status.FailedToCreateVmCount))
+	}
+	if len(statuses) == 0 {
+		return "no status reported"
+	}
+	return strings.Join(statuses, ", ")
+}
+
+// toInstanceProperties converts the instance to the properties of the instances of a bulk insert,
+// which reference zonal and regional resources by name rather than by URL.
+func toInstanceProperties(instance *compute.Instance) *compute.InstanceProperties {
+	properties := &compute.InstanceProperties{
+		AdvancedMachineFeatures:    instance.AdvancedMachineFeatures,
+		CanIpForward:               instance.CanIpForward,
+		ConfidentialInstanceConfig: instance.ConfidentialInstanceConfig,
+		Description:                instance.Description,
+		KeyRevocationActionType:    instance.KeyRevocationActionType,
+		Labels:                     instance.Labels,
+		MachineType:                resourceName(instance.MachineType),
+		Metadata:                   instance.Metadata,
+		MinCpuPlatform:             instance.MinCpuPlatform,
+		NetworkInterfaces:          instance.NetworkInterfaces,
+		NetworkPerformanceConfig:   instance.NetworkPerformanceConfig,
+		ReservationAffinity:        instance.ReservationAffinity,
+		ResourcePolicies:           resourceNames(instance.ResourcePolicies),
+		Scheduling:                 instance.Scheduling,
+		ServiceAccounts:            instance.ServiceAccounts,
+		ShieldedInstanceConfig:     instance.ShieldedInstanceConfig,
+		Tags:                       instance.Tags,
+	}
+	if instance.Params != nil {
+		properties.ResourceManagerTags = instance.Params.ResourceManagerTags
+	}
+
+	for _, accelerator := range instance.GuestAccelerators {
+		properties.GuestAccelerators = append(properties.GuestAccelerators, &compute.AcceleratorConfig{
+			AcceleratorCount: accelerator.AcceleratorCount,
+			AcceleratorType:  resourceName(accelerator.AcceleratorType),
+		})
+	}
+
+	for _, disk := range instance.Disks {
+		propertiesDisk := *disk
+		if disk.InitializeParams != nil {
+			initParams := *disk.InitializeParams
+			initParams.DiskType = resourceName(initParams.DiskType)
+			initParams.ResourcePolicies = resourceNames(initParams.ResourcePolicies)
+			propertiesDisk.InitializeParams = &initParams
+		}
+		properties.Disks = append(properties.Disks, &propertiesDisk)
+	}
+	return properties
+}
+
+// resourceName returns the name of the resource referenced by URL.
+func resourceName(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+// resourceNames returns the names of the resources referenced by URL.
+func resourceNames(urls []string) []string {
+	var names []string
+	for _, url := range urls {
+		names = append(names, resourceName(url))
+	}
+	return names
+}
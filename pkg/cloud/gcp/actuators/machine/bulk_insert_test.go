@@ -0,0 +1,393 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newBulkInsertMachine(t *testing.T, name string, machineSet string, providerSpec *machinev1.GCPMachineProviderSpec) *machinev1.Machine {
+	machine := newPlacementPolicyMachine(t, name, machineSet, nil)
+	value, err := util.RawExtensionFromProviderSpec(providerSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine.Spec.ProviderSpec.Value = value
+	return machine
+}
+
+func TestValidateBulkInsert(t *testing.T) {
+	cases := []struct {
+		name          string
+		machineSet    string
+		providerSpec  machinev1.GCPMachineProviderSpec
+		expectedError string
+	}{
+		{
+			name:         "Zonal bulk insert",
+			machineSet:   "gpus",
+			providerSpec: machinev1.GCPMachineProviderSpec{BulkInsert: &machinev1.GCPBulkInsert{MinCount: 4}},
+		},
+		{
+			name:       "Regional bulk insert in some zones",
+			machineSet: "gpus",
+			providerSpec: machinev1.GCPMachineProviderSpec{BulkInsert: &machinev1.GCPBulkInsert{
+				Targeting: machinev1.BulkInsertTargetingRegional,
+				Zones:     []string{"zone1", "zone2"},
+			}},
+		},
+		{
+			name:          "Machine without machine set",
+			providerSpec:  machinev1.GCPMachineProviderSpec{BulkInsert: &machinev1.GCPBulkInsert{}},
+			expectedError: "bulk insert is only supported for machines that are part of a machine set",
+		},
+		{
+			name:          "Negative minimum count",
+			machineSet:    "gpus",
+			providerSpec:  machinev1.GCPMachineProviderSpec{BulkInsert: &machinev1.GCPBulkInsert{MinCount: -1}},
+			expectedError: "bulk insert minimum count must not be negative",
+		},
+		{
+			name:       "Zones of a zonal bulk insert",
+			machineSet: "gpus",
+			providerSpec: machinev1.GCPMachineProviderSpec{BulkInsert: &machinev1.GCPBulkInsert{
+				Zones: []string{"zone1"},
+			}},
+			expectedError: "bulk insert zones can only be set with the \"Regional\" targeting",
+		},
+		{
+			name:       "Bulk insert with static addresses",
+			machineSet: "gpus",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				BulkInsert: &machinev1.GCPBulkInsert{},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{InternalAddress: &machinev1.GCPStaticAddress{}},
+				},
+			},
+			expectedError: "bulk insert does not support static addresses",
+		},
+		{
+			name:       "Bulk insert with deletion protection",
+			machineSet: "gpus",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				BulkInsert:         &machinev1.GCPBulkInsert{},
+				DeletionProtection: true,
+			},
+			expectedError: "bulk insert does not support deletion protection",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := newPlacementPolicyMachine(t, "gpus-0", tc.machineSet, nil)
+			err := validateBulkInsert(*machine, tc.providerSpec)
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestBulkInsert(t *testing.T) {
+	providerSpec := &machinev1.GCPMachineProviderSpec{
+		MachineType: "a2-highgpu-1g",
+		Region:      "region1",
+		Zone:        "zone1",
+		BulkInsert:  &machinev1.GCPBulkInsert{MinCount: 2},
+	}
+	// Machines of the machine set, with the same provider spec unless stated otherwise
+	newMachines := func(spec *machinev1.GCPMachineProviderSpec) []controllerclient.Object {
+		otherSpec := spec.DeepCopy()
+		otherSpec.MachineType = "a2-highgpu-2g"
+		provisionedMachine := newBulkInsertMachine(t, "gpus-2", "gpus", spec)
+		provisionedMachine.Spec.ProviderID = ptr.To("gce://test/zone1/gpus-2")
+
+		return []controllerclient.Object{
+			newBulkInsertMachine(t, "gpus-1", "gpus", spec),
+			provisionedMachine,
+			newBulkInsertMachine(t, "gpus-3", "gpus", spec),
+			newBulkInsertMachine(t, "gpus-4", "gpus", otherSpec),
+			newBulkInsertMachine(t, "cpus-0", "cpus", spec),
+		}
+	}
+
+	cases := []struct {
+		name              string
+		targeting         machinev1.GCPBulkInsertTargeting
+		existingInstances []*compute.Instance
+		createdInstances  []*compute.Instance
+		expectedNames     []string
+		expectedMinCount  int64
+		expectedLocations *compute.LocationPolicy
+		insertErr         error
+		expectedZone      string
+		expectedError     error
+		expectRequeue     bool
+		expectInvalidSpec bool
+	}{
+		{
+			name:              "Zonal bulk insert of the pending machines",
+			existingInstances: []*compute.Instance{{Name: "gpus-3", Zone: "zones/zone1"}},
+			createdInstances:  []*compute.Instance{{Name: "gpus-0", Zone: "zones/zone1"}, {Name: "gpus-1", Zone: "zones/zone1"}},
+			expectedNames:     []string{"gpus-0", "gpus-1"},
+			expectedMinCount:  2,
+			expectedZone:      "zone1",
+		},
+		{
+			name:             "Minimum count capped to the pending machines",
+			createdInstances: []*compute.Instance{{Name: "gpus-0", Zone: "zones/zone1"}},
+			existingInstances: []*compute.Instance{
+				{Name: "gpus-1", Zone: "zones/zone1"},
+				{Name: "gpus-3", Zone: "zones/zone1"},
+			},
+			expectedNames:    []string{"gpus-0"},
+			expectedMinCount: 1,
+			expectedZone:     "zone1",
+		},
+		{
+			name:              "Regional bulk insert moves the machine to the zone of its instance",
+			targeting:         machinev1.BulkInsertTargetingRegional,
+			existingInstances: []*compute.Instance{{Name: "gpus-3", Zone: "zones/zone2"}},
+			createdInstances:  []*compute.Instance{{Name: "gpus-0", Zone: "zones/zone2"}, {Name: "gpus-1", Zone: "zones/zone2"}},
+			expectedNames:     []string{"gpus-0", "gpus-1"},
+			expectedMinCount:  2,
+			expectedLocations: &compute.LocationPolicy{TargetShape: "ANY_SINGLE_ZONE"},
+			expectedZone:      "zone2",
+		},
+		{
+			name:              "Instance created by the regional bulk insert of another machine",
+			targeting:         machinev1.BulkInsertTargetingRegional,
+			existingInstances: []*compute.Instance{{Name: "gpus-0", Zone: "zones/zone3"}},
+			expectedZone:      "zone3",
+		},
+		{
+			name:             "Instance of the machine not created",
+			createdInstances: []*compute.Instance{{Name: "gpus-1", Zone: "zones/zone1"}, {Name: "gpus-3", Zone: "zones/zone1"}},
+			expectedNames:    []string{"gpus-0", "gpus-1", "gpus-3"},
+			expectedMinCount: 2,
+			expectedZone:     "zone1",
+			expectedError:    errors.New("instance was not created by the bulk insert of 3 instances: no status reported"),
+		},
+		{
+			name:             "Instances created by the bulk insert of another machine in the meantime",
+			insertErr:        &googleapi.Error{Code: http.StatusConflict},
+			expectedNames:    []string{"gpus-0", "gpus-1", "gpus-3"},
+			expectedMinCount: 2,
+			expectedZone:     "zone1",
+			expectRequeue:    true,
+		},
+		{
+			name:              "Bulk insert rejected",
+			insertErr:         &googleapi.Error{Code: http.StatusBadRequest},
+			expectedNames:     []string{"gpus-0", "gpus-1", "gpus-3"},
+			expectedMinCount:  2,
+			expectedZone:      "zone1",
+			expectInvalidSpec: true,
+		},
+		{
+			name:             "Bulk insert over quota",
+			insertErr:        &googleapi.Error{Code: http.StatusForbidden},
+			expectedNames:    []string{"gpus-0", "gpus-1", "gpus-3"},
+			expectedMinCount: 2,
+			expectedZone:     "zone1",
+			expectedError:    fmt.Errorf("failed to create instances in bulk: %w", &googleapi.Error{Code: http.StatusForbidden}),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := providerSpec.DeepCopy()
+			spec.BulkInsert.Targeting = tc.targeting
+			machine := newBulkInsertMachine(t, "gpus-0", "gpus", spec)
+
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			instances := tc.existingInstances
+			mockComputeService.MockInstancesList = func(_ context.Context, project string, zone string, filter string) ([]*compute.Instance, error) {
+				if filter != `name eq "gpus-.*"` {
+					t.Errorf("Unexpected instances filter %s", filter)
+				}
+				var zoneInstances []*compute.Instance
+				for _, instance := range instances {
+					if path.Base(instance.Zone) == zone {
+						zoneInstances = append(zoneInstances, instance)
+					}
+				}
+				return zoneInstances, nil
+			}
+			mockComputeService.MockRegionGet = func(project string, region string) (*compute.Region, error) {
+				return &compute.Region{Zones: []string{
+					"https://www.googleapis.com/compute/v1/projects/test/zones/zone1",
+					"https://www.googleapis.com/compute/v1/projects/test/zones/zone2",
+					"https://www.googleapis.com/compute/v1/projects/test/zones/zone3",
+				}}, nil
+			}
+			var inserted *compute.BulkInsertInstanceResource
+			bulkInsert := func(resource *compute.BulkInsertInstanceResource) (*compute.Operation, error) {
+				inserted = resource
+				if tc.insertErr != nil {
+					return nil, tc.insertErr
+				}
+				instances = append(instances, tc.createdInstances...)
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockInstancesBulkInsert = func(project string, zone string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error) {
+				if tc.targeting == machinev1.BulkInsertTargetingRegional || zone != "zone1" {
+					t.Errorf("Unexpected zonal bulk insert in zone %s", zone)
+				}
+				return bulkInsert(resource)
+			}
+			mockComputeService.MockRegionInstancesBulkInsert = func(project string, region string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error) {
+				if tc.targeting != machinev1.BulkInsertTargetingRegional || region != "region1" {
+					t.Errorf("Unexpected regional bulk insert in region %s", region)
+				}
+				return bulkInsert(resource)
+			}
+			mockComputeService.MockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+				return &compute.Instance{
+					Name:              instance,
+					Zone:              zone,
+					Status:            "RUNNING",
+					NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.0.0.2"}},
+				}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine:        machine,
+				coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(newMachines(spec)...).Build(),
+				providerSpec:   spec,
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				specZone:       spec.Zone,
+				projectID:      "test",
+				providerID:     "gce://test/zone1/gpus-0",
+				computeService: mockComputeService,
+			})
+
+			err := r.bulkInsert(&compute.Instance{
+				Name:        "gpus-0",
+				MachineType: "zones/zone1/machineTypes/a2-highgpu-1g",
+			}, "")
+			switch {
+			case tc.expectRequeue:
+				if !isRequeueAfterError(err) {
+					t.Errorf("Expected a requeue, got: %v", err)
+				}
+			case tc.expectInvalidSpec:
+				if !isInvalidMachineConfigurationError(err) {
+					t.Errorf("Expected invalid machine configuration, got: %v", err)
+				}
+			case !reflect.DeepEqual(err, tc.expectedError):
+				t.Errorf("Expected error: %v, got: %v", tc.expectedError, err)
+			}
+
+			var names []string
+			if inserted != nil {
+				for name, properties := range inserted.PerInstanceProperties {
+					if properties.Name != name {
+						t.Errorf("Expected instance %s to be named after its machine, got: %s", name, properties.Name)
+					}
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				if inserted.Count != int64(len(names)) || inserted.MinCount != tc.expectedMinCount {
+					t.Errorf("Expected %d instances, at least %d, got: %d, at least %d", len(names), tc.expectedMinCount, inserted.Count, inserted.MinCount)
+				}
+				if inserted.InstanceProperties.MachineType != "a2-highgpu-1g" {
+					t.Errorf("Expected machine type a2-highgpu-1g, got: %s", inserted.InstanceProperties.MachineType)
+				}
+				if !reflect.DeepEqual(inserted.LocationPolicy, tc.expectedLocations) {
+					t.Errorf("Expected location policy: %v, got: %v", tc.expectedLocations, inserted.LocationPolicy)
+				}
+			}
+			if !reflect.DeepEqual(names, tc.expectedNames) {
+				t.Errorf("Expected instances %v to be created, got: %v", tc.expectedNames, names)
+			}
+
+			if r.providerSpec.Zone != tc.expectedZone {
+				t.Errorf("Expected machine in zone %s, got: %s", tc.expectedZone, r.providerSpec.Zone)
+			}
+			if expectedProviderID := "gce://test/" + tc.expectedZone + "/gpus-0"; r.providerID != expectedProviderID {
+				t.Errorf("Expected provider ID %s, got: %s", expectedProviderID, r.providerID)
+			}
+
+			// The zone of the instance is recorded in the providerStatus, not in the provider spec of the machine
+			expectedStatusZone := ""
+			if tc.expectedZone != "zone1" {
+				expectedStatusZone = tc.expectedZone
+			}
+			if r.providerStatus.Zone != expectedStatusZone {
+				t.Errorf("Expected zone %q in the provider status, got: %q", expectedStatusZone, r.providerStatus.Zone)
+			}
+			if err := r.setMachineSpec(); err != nil {
+				t.Fatal(err)
+			}
+			storedSpec, err := util.ProviderSpecFromRawExtension(r.machine.Spec.ProviderSpec.Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if storedSpec.Zone != "zone1" {
+				t.Errorf("Expected the provider spec of the machine to keep zone zone1, got: %s", storedSpec.Zone)
+			}
+		})
+	}
+}
+
+func TestToInstanceProperties(t *testing.T) {
+	instance := &compute.Instance{
+		Name:        "gpus-0",
+		MachineType: "zones/zone1/machineTypes/a2-highgpu-1g",
+		GuestAccelerators: []*compute.AcceleratorConfig{
+			{AcceleratorType: "zones/zone1/acceleratorTypes/nvidia-tesla-a100", AcceleratorCount: 1},
+		},
+		Disks: []*compute.AttachedDisk{
+			{
+				Boot: true,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskType:         "zones/zone1/diskTypes/pd-ssd",
+					ResourcePolicies: []string{"projects/test/regions/region1/resourcePolicies/snapshots"},
+				},
+			},
+		},
+		ResourcePolicies: []string{"projects/test/regions/region1/resourcePolicies/gpus-placement"},
+		Params: &compute.InstanceParams{
+			ResourceManagerTags: map[string]string{"tagKeys/1": "tagValues/2"},
+		},
+	}
+
+	properties := toInstanceProperties(instance)
+	assertStrings(t, "instance properties", []string{
+		"a2-highgpu-1g",
+		"nvidia-tesla-a100",
+		"pd-ssd",
+		"snapshots",
+		"gpus-placement",
+		"tagValues/2",
+	}, []string{
+		properties.MachineType,
+		properties.GuestAccelerators[0].AcceleratorType,
+		properties.Disks[0].InitializeParams.DiskType,
+		properties.Disks[0].InitializeParams.ResourcePolicies[0],
+		properties.ResourcePolicies[0],
+		properties.ResourceManagerTags["tagKeys/1"],
+	})
+	// The disks of the instance are left untouched
+	assertStrings(t, "instance disk type", []string{"zones/zone1/diskTypes/pd-ssd"}, []string{instance.Disks[0].InitializeParams.DiskType})
+}
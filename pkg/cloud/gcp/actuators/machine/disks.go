@@ -0,0 +1,358 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxResourceNameLength is the maximum length of a GCE resource name.
+	maxResourceNameLength = 63
+	// snapshotReadyStatus is the status of a snapshot that has been successfully created.
+	snapshotReadyStatus = "READY"
+	// snapshotFailedStatus is the status of a snapshot that could not be created.
+	snapshotFailedStatus = "FAILED"
+
+	resourcePolicyFmt = "projects/%s/regions/%s/resourcePolicies/%s"
+	// diskDeviceNameFmt is the device name of the disk attached for each providerSpec disk.
+	diskDeviceNameFmt = "machine-disk-%d"
+)
+
+// validateDiskDeletionPolicy makes sure the deletion policy of a disk is a known value.
+func validateDiskDeletionPolicy(policy machinev1.GCPDiskDeletionPolicy) error {
+	switch policy {
+	case "", machinev1.DiskDeletionPolicyDelete, machinev1.DiskDeletionPolicyRetain, machinev1.DiskDeletionPolicySnapshot:
+		return nil
+	}
+	return fmt.Errorf("unsupported disk deletion policy %q, valid values are %q, %q and %q", policy,
+		machinev1.DiskDeletionPolicyDelete, machinev1.DiskDeletionPolicyRetain, machinev1.DiskDeletionPolicySnapshot)
+}
+
+// inspectBootImage retrieves the image of the boot disk, validates the boot disk size
+// against it and checks the image is usable with the machine type.
+// It returns nil if the providerSpec has no boot disk, or if the image can not be read while
+// the providerSpec sets the ShieldedInstanceConfig, which otherwise depends on the image.
+func (r *Reconciler) inspectBootImage() (*compute.Image, error) {
+	var bootDisk *machinev1.GCPDisk
+	for _, disk := range r.providerSpec.Disks {
+		if disk.Boot {
+			bootDisk = disk
+			break
+		}
+	}
+	if bootDisk == nil {
+		return nil, nil
+	}
+	if bootDisk.Image == "" {
+		return nil, machinecontroller.InvalidMachineConfiguration("boot disk must specify an image")
+	}
+
+	image, err := util.GetBootImage(r.computeService, r.providerSpec)
+	if err != nil && r.providerSpec.ShieldedInstanceConfig != (machinev1.GCPShieldedInstanceConfig{}) {
+		klog.Warningf("%s: failed to read the boot image, skipping its checks: %v", r.machine.Name, err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching disk information: %w", err)
+	}
+
+	warnings, err := util.InspectBootImage(image, r.providerSpec)
+	if err != nil {
+		return nil, machinecontroller.InvalidMachineConfiguration("invalid boot disk: %v", err)
+	}
+	for _, warning := range warnings {
+		klog.Warningf("%s: %s", r.machine.Name, warning)
+	}
+
+	return image, nil
+}
+
+// resourcePolicyURLs validates that the named resource policies exist in the region
+// of the machine and returns their relative URLs.
+func (r *Reconciler) resourcePolicyURLs(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	urls := []string{}
+	for _, name := range names {
+		if _, err := r.computeService.ResourcePoliciesGet(r.projectID, r.providerSpec.Region, name); err != nil {
+			if isNotFoundError(err) {
+				return nil, machinecontroller.InvalidMachineConfiguration("resource policy %s not found in region %s", name, r.providerSpec.Region)
+			}
+			return nil, fmt.Errorf("failed to get resource policy %s: %w", name, err)
+		}
+		urls = append(urls, fmt.Sprintf(resourcePolicyFmt, r.projectID, r.providerSpec.Region, name))
+	}
+	return urls, nil
+}
+
+// reconcileDiskResourcePolicies attaches the resource policies listed for each disk
+// to the disks of an existing instance, if they are not attached yet.
+// Resource policies attached outside of the providerSpec are left untouched.
+func (r *Reconciler) reconcileDiskResourcePolicies() error {
+	hasResourcePolicies := false
+	for _, disk := range r.providerSpec.Disks {
+		if len(disk.ResourcePolicies) > 0 {
+			hasResourcePolicies = true
+			break
+		}
+	}
+	if !hasResourcePolicies {
+		return nil
+	}
+
+	instance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.machine.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get instance via compute service: %v", err)
+	}
+
+	for i, disk := range r.providerSpec.Disks {
+		if len(disk.ResourcePolicies) == 0 {
+			continue
+		}
+		attachedDisk := attachedDiskForSpec(instance, i, disk)
+		if attachedDisk == nil {
+			continue
+		}
+
+		diskName := path.Base(attachedDisk.Source)
+		gceDisk, err := r.computeService.DisksGet(r.projectID, r.providerSpec.Zone, diskName)
+		if err != nil {
+			return fmt.Errorf("failed to get disk %s: %w", diskName, err)
+		}
+
+		attachedPolicies := sets.NewString()
+		for _, policy := range gceDisk.ResourcePolicies {
+			attachedPolicies.Insert(path.Base(policy))
+		}
+
+		missingPolicies := []string{}
+		for _, name := range disk.ResourcePolicies {
+			if !attachedPolicies.Has(name) {
+				missingPolicies = append(missingPolicies, name)
+			}
+		}
+		if len(missingPolicies) == 0 {
+			continue
+		}
+
+		urls, err := r.resourcePolicyURLs(missingPolicies)
+		if err != nil {
+			return err
+		}
+
+		klog.Infof("%s: attaching resource policies %v to disk %s", r.machine.Name, missingPolicies, diskName)
+		op, err := r.computeService.DisksAddResourcePolicies(r.projectID, r.providerSpec.Zone, diskName, urls)
+		if err != nil {
+			return fmt.Errorf("failed to attach resource policies to disk %s: %w", diskName, err)
+		}
+		if err := r.awaitZoneOperation(op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diskDeviceName returns the device name of the disk attached for the providerSpec disk at the given index.
+func diskDeviceName(index int) string {
+	return fmt.Sprintf(diskDeviceNameFmt, index)
+}
+
+// attachedDiskForSpec returns the disk attached to the instance for the providerSpec disk at the given index.
+// Disks are matched on the device name set when the instance is created, as their index changes
+// when a disk is detached or the instance template provides disks of its own. The boot disk of an
+// instance created without these device names is matched on its source disk, which is named after the instance.
+func attachedDiskForSpec(instance *compute.Instance, index int, disk *machinev1.GCPDisk) *compute.AttachedDisk {
+	deviceName := diskDeviceName(index)
+	for _, attachedDisk := range instance.Disks {
+		if attachedDisk.DeviceName == deviceName {
+			return attachedDisk
+		}
+	}
+	if !disk.Boot {
+		return nil
+	}
+	for _, attachedDisk := range instance.Disks {
+		if attachedDisk.Boot && path.Base(attachedDisk.Source) == instance.Name {
+			return attachedDisk
+		}
+	}
+	return nil
+}
+
+// snapshotName generates a deterministic snapshot name for the given disk so that
+// the snapshot is only taken once even if the deletion is retried.
+func snapshotName(diskName string, machineUID string) string {
+	suffix := "-snapshot"
+	if len(machineUID) >= 8 {
+		suffix = "-" + machineUID[:8]
+	}
+	if len(diskName)+len(suffix) > maxResourceNameLength {
+		diskName = diskName[:maxResourceNameLength-len(suffix)]
+	}
+	return diskName + suffix
+}
+
+// reconcileDisksBeforeDelete applies the deletion policy of each disk before the instance is deleted.
+// Disks to be snapshotted are snapshotted first, the reconciler requeues until every snapshot is ready.
+// Retained disks are then detached, or in the case of boot disks which cannot be detached
+// from a running instance, excluded from auto-deletion. Disks to be deleted are finally
+// marked for auto-deletion so they go away together with the instance.
+func (r *Reconciler) reconcileDisksBeforeDelete(instance *compute.Instance) error {
+	snapshotsPending := false
+	for i, disk := range r.providerSpec.Disks {
+		if disk.DeletionPolicy != machinev1.DiskDeletionPolicySnapshot {
+			continue
+		}
+		attachedDisk := attachedDiskForSpec(instance, i, disk)
+		if attachedDisk == nil {
+			continue
+		}
+		ready, err := r.ensureDiskSnapshot(disk, attachedDisk)
+		if err != nil {
+			return err
+		}
+		if !ready {
+			snapshotsPending = true
+		}
+	}
+	if snapshotsPending {
+		klog.Infof("%s: waiting for disk snapshots to be ready, requeuing...", r.machine.Name)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+
+	for i, disk := range r.providerSpec.Disks {
+		attachedDisk := attachedDiskForSpec(instance, i, disk)
+		if attachedDisk == nil {
+			continue
+		}
+
+		switch disk.DeletionPolicy {
+		case machinev1.DiskDeletionPolicyRetain:
+			if err := r.retainDisk(instance, attachedDisk); err != nil {
+				return err
+			}
+		case machinev1.DiskDeletionPolicyDelete, machinev1.DiskDeletionPolicySnapshot:
+			if attachedDisk.AutoDelete {
+				continue
+			}
+			if err := r.setDiskAutoDelete(instance, attachedDisk, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureDiskSnapshot takes a labeled snapshot of the attached disk if it does not exist yet.
+// It returns true once the snapshot is ready.
+func (r *Reconciler) ensureDiskSnapshot(disk *machinev1.GCPDisk, attachedDisk *compute.AttachedDisk) (bool, error) {
+	diskName := path.Base(attachedDisk.Source)
+	name := snapshotName(diskName, string(r.machine.UID))
+
+	snapshot, err := r.computeService.SnapshotsGet(r.projectID, name)
+	if err != nil && !isNotFoundError(err) {
+		return false, fmt.Errorf("failed to get snapshot %s of disk %s: %w", name, diskName, err)
+	}
+
+	if snapshot == nil {
+		labels, err := util.GetLabelsList(r.coreClient, r.machine.Labels[machinev1.MachineClusterIDLabel], disk.Labels)
+		if err != nil {
+			return false, fmt.Errorf("error getting user-defined labels for snapshot of disk %s: %w", diskName, err)
+		}
+
+		// Disks encrypted with a customer-supplied key can only be read with that key,
+		// the snapshot is protected with the same key.
+		var encryptionKey *compute.CustomerEncryptionKey
+		if disk.EncryptionKey != nil && disk.EncryptionKey.CustomerSuppliedKey != nil {
+			if encryptionKey, err = r.diskEncryptionKey(disk.EncryptionKey); err != nil {
+				return false, err
+			}
+		}
+
+		klog.Infof("%s: taking snapshot %s of disk %s", r.machine.Name, name, diskName)
+		op, err := r.computeService.DisksCreateSnapshot(r.projectID, r.providerSpec.Zone, diskName, &compute.Snapshot{
+			Name:                    name,
+			Description:             fmt.Sprintf("Snapshot of disk %s taken on deletion of machine %s", diskName, r.machine.Name),
+			Labels:                  labels,
+			SourceDiskEncryptionKey: encryptionKey,
+			SnapshotEncryptionKey:   encryptionKey,
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to create snapshot %s of disk %s: %w", name, diskName, err)
+		}
+		r.recordDiskSnapshot(name)
+		if err := r.awaitZoneOperation(op); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	r.recordDiskSnapshot(name)
+	switch snapshot.Status {
+	case snapshotReadyStatus:
+		return true, nil
+	case snapshotFailedStatus:
+		return false, fmt.Errorf("snapshot %s of disk %s failed", name, diskName)
+	}
+	return false, nil
+}
+
+// retainDisk makes sure the attached disk outlives the instance.
+func (r *Reconciler) retainDisk(instance *compute.Instance, attachedDisk *compute.AttachedDisk) error {
+	diskName := path.Base(attachedDisk.Source)
+
+	if attachedDisk.Boot {
+		// The boot disk can not be detached while the instance is running.
+		if attachedDisk.AutoDelete {
+			if err := r.setDiskAutoDelete(instance, attachedDisk, false); err != nil {
+				return err
+			}
+		}
+	} else {
+		klog.Infof("%s: detaching disk %s to retain it", r.machine.Name, diskName)
+		op, err := r.computeService.InstancesDetachDisk(r.projectID, r.providerSpec.Zone, instance.Name, attachedDisk.DeviceName)
+		if err != nil {
+			return fmt.Errorf("failed to detach disk %s: %w", diskName, err)
+		}
+		r.recordRetainedDisk(diskName)
+		return r.awaitZoneOperation(op)
+	}
+
+	r.recordRetainedDisk(diskName)
+	return nil
+}
+
+func (r *Reconciler) setDiskAutoDelete(instance *compute.Instance, attachedDisk *compute.AttachedDisk, autoDelete bool) error {
+	diskName := path.Base(attachedDisk.Source)
+
+	klog.Infof("%s: setting auto-delete of disk %s to %t", r.machine.Name, diskName, autoDelete)
+	op, err := r.computeService.InstancesSetDiskAutoDelete(r.projectID, r.providerSpec.Zone, instance.Name, autoDelete, attachedDisk.DeviceName)
+	if err != nil {
+		return fmt.Errorf("failed to set auto-delete of disk %s: %w", diskName, err)
+	}
+	return r.awaitZoneOperation(op)
+}
+
+func (r *Reconciler) recordRetainedDisk(name string) {
+	if !sets.NewString(r.providerStatus.RetainedDisks...).Has(name) {
+		r.providerStatus.RetainedDisks = append(r.providerStatus.RetainedDisks, name)
+	}
+}
+
+func (r *Reconciler) recordDiskSnapshot(name string) {
+	if !sets.NewString(r.providerStatus.DiskSnapshots...).Has(name) {
+		r.providerStatus.DiskSnapshots = append(r.providerStatus.DiskSnapshots, name)
+	}
+}
@@ -0,0 +1,333 @@
+package machine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	googleapi "google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSnapshotName(t *testing.T) {
+	cases := []struct {
+		name     string
+		diskName string
+		uid      string
+		expected string
+	}{
+		{
+			name:     "uses the first characters of the machine UID",
+			diskName: "machine-0",
+			uid:      "8f2a6c1e-0b4e-4c1a-9d62-1f3f1b7d2a10",
+			expected: "machine-0-8f2a6c1e",
+		},
+		{
+			name:     "falls back to a fixed suffix without UID",
+			diskName: "machine-0",
+			expected: "machine-0-snapshot",
+		},
+		{
+			name:     "truncates long disk names",
+			diskName: strings.Repeat("a", 63),
+			uid:      "8f2a6c1e-0b4e-4c1a-9d62-1f3f1b7d2a10",
+			expected: strings.Repeat("a", 54) + "-8f2a6c1e",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := snapshotName(tc.diskName, tc.uid); got != tc.expected {
+				t.Errorf("Expected: %s, got: %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestReconcileDisksBeforeDelete(t *testing.T) {
+	instance := &compute.Instance{
+		Name: "machine-0",
+		Disks: []*compute.AttachedDisk{
+			{
+				Index:      0,
+				Boot:       true,
+				AutoDelete: true,
+				DeviceName: "persistent-disk-0",
+				Source:     "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/disks/machine-0",
+			},
+			{
+				// Disk of the instance template, which shifts the index of the providerSpec data disk
+				Index:      1,
+				AutoDelete: false,
+				DeviceName: "persistent-disk-1",
+				Source:     "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/disks/machine-0-template",
+			},
+			{
+				Index:      2,
+				AutoDelete: false,
+				DeviceName: "machine-disk-1",
+				Source:     "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/disks/machine-0-data",
+			},
+		},
+	}
+
+	type autoDeleteCall struct {
+		deviceName string
+		autoDelete bool
+	}
+
+	cases := []struct {
+		name                    string
+		policies                []machinev1.GCPDiskDeletionPolicy
+		mockSnapshotsGet        func(project string, snapshot string) (*compute.Snapshot, error)
+		expectedError           error
+		expectedRequeue         bool
+		expectedSnapshotsTaken  []string
+		expectedDetached        []string
+		expectedAutoDeleteCalls []autoDeleteCall
+		expectedRetainedDisks   []string
+		expectedDiskSnapshots   []string
+	}{
+		{
+			name:     "No deletion policy leaves the disks untouched",
+			policies: []machinev1.GCPDiskDeletionPolicy{"", ""},
+		},
+		{
+			name:                    "Delete policy enables auto-delete",
+			policies:                []machinev1.GCPDiskDeletionPolicy{machinev1.DiskDeletionPolicyDelete, machinev1.DiskDeletionPolicyDelete},
+			expectedAutoDeleteCalls: []autoDeleteCall{{deviceName: "machine-disk-1", autoDelete: true}},
+		},
+		{
+			name:                    "Retain policy detaches data disks and disables auto-delete of the boot disk",
+			policies:                []machinev1.GCPDiskDeletionPolicy{machinev1.DiskDeletionPolicyRetain, machinev1.DiskDeletionPolicyRetain},
+			expectedDetached:        []string{"machine-disk-1"},
+			expectedAutoDeleteCalls: []autoDeleteCall{{deviceName: "persistent-disk-0", autoDelete: false}},
+			expectedRetainedDisks:   []string{"machine-0", "machine-0-data"},
+		},
+		{
+			name:     "Snapshot policy takes a snapshot and requeues until it is ready",
+			policies: []machinev1.GCPDiskDeletionPolicy{"", machinev1.DiskDeletionPolicySnapshot},
+			mockSnapshotsGet: func(project string, snapshot string) (*compute.Snapshot, error) {
+				return nil, &googleapi.Error{Code: 404}
+			},
+			expectedRequeue:        true,
+			expectedSnapshotsTaken: []string{"machine-0-data-8f2a6c1e"},
+			expectedDiskSnapshots:  []string{"machine-0-data-8f2a6c1e"},
+		},
+		{
+			name:                    "Snapshot policy deletes the disk once the snapshot is ready",
+			policies:                []machinev1.GCPDiskDeletionPolicy{"", machinev1.DiskDeletionPolicySnapshot},
+			expectedAutoDeleteCalls: []autoDeleteCall{{deviceName: "machine-disk-1", autoDelete: true}},
+			expectedDiskSnapshots:   []string{"machine-0-data-8f2a6c1e"},
+		},
+		{
+			name:     "Snapshot policy fails when the snapshot failed",
+			policies: []machinev1.GCPDiskDeletionPolicy{"", machinev1.DiskDeletionPolicySnapshot},
+			mockSnapshotsGet: func(project string, snapshot string) (*compute.Snapshot, error) {
+				return &compute.Snapshot{Name: snapshot, Status: "FAILED"}, nil
+			},
+			expectedError:         errors.New("snapshot machine-0-data-8f2a6c1e of disk machine-0-data failed"),
+			expectedDiskSnapshots: []string{"machine-0-data-8f2a6c1e"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+
+			var snapshotsTaken, detached []string
+			var autoDeleteCalls []autoDeleteCall
+			mockComputeService.MockSnapshotsGet = tc.mockSnapshotsGet
+			mockComputeService.MockDisksCreateSnapshot = func(project string, zone string, disk string, snapshot *compute.Snapshot) (*compute.Operation, error) {
+				snapshotsTaken = append(snapshotsTaken, snapshot.Name)
+				if snapshot.Labels["kubernetes-io-cluster-CLUSTERID"] != "owned" {
+					t.Errorf("Expected snapshot %s to be labeled with the cluster ID, got labels: %v", snapshot.Name, snapshot.Labels)
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockInstancesDetachDisk = func(project string, zone string, instance string, deviceName string) (*compute.Operation, error) {
+				detached = append(detached, deviceName)
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockInstancesSetDiskAutoDelete = func(project string, zone string, instance string, autoDelete bool, deviceName string) (*compute.Operation, error) {
+				autoDeleteCalls = append(autoDeleteCalls, autoDeleteCall{deviceName: deviceName, autoDelete: autoDelete})
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			disks := []*machinev1.GCPDisk{}
+			for i, policy := range tc.policies {
+				disks = append(disks, &machinev1.GCPDisk{Boot: i == 0, DeletionPolicy: policy})
+			}
+
+			infraObj := &configv1.Infrastructure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster",
+				},
+			}
+
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "machine-0",
+						UID:  "8f2a6c1e-0b4e-4c1a-9d62-1f3f1b7d2a10",
+						Labels: map[string]string{
+							machinev1.MachineClusterIDLabel: "CLUSTERID",
+						},
+					},
+				},
+				coreClient: controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(infraObj).Build(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Zone:  "zone1",
+					Disks: disks,
+				},
+				projectID:      "test",
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+			})
+
+			err := r.reconcileDisksBeforeDelete(instance)
+
+			if tc.expectedRequeue {
+				var requeueErr *machinecontroller.RequeueAfterError
+				if !errors.As(err, &requeueErr) {
+					t.Errorf("Expected requeue error, got: %v", err)
+				}
+			} else if tc.expectedError != nil {
+				if err == nil || err.Error() != tc.expectedError.Error() {
+					t.Errorf("Expected: %v, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+
+			assertStrings(t, "snapshots taken", tc.expectedSnapshotsTaken, snapshotsTaken)
+			assertStrings(t, "detached disks", tc.expectedDetached, detached)
+			assertStrings(t, "retained disks", tc.expectedRetainedDisks, r.providerStatus.RetainedDisks)
+			assertStrings(t, "disk snapshots", tc.expectedDiskSnapshots, r.providerStatus.DiskSnapshots)
+
+			if len(autoDeleteCalls) != len(tc.expectedAutoDeleteCalls) {
+				t.Fatalf("Expected auto-delete calls: %v, got: %v", tc.expectedAutoDeleteCalls, autoDeleteCalls)
+			}
+			for i := range autoDeleteCalls {
+				if autoDeleteCalls[i] != tc.expectedAutoDeleteCalls[i] {
+					t.Errorf("Expected auto-delete calls: %v, got: %v", tc.expectedAutoDeleteCalls, autoDeleteCalls)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileDiskResourcePolicies(t *testing.T) {
+	cases := []struct {
+		name             string
+		resourcePolicies []string
+		attachedPolicies []string
+		expectedAdded    []string
+		expectedError    error
+	}{
+		{
+			name: "No resource policies",
+		},
+		{
+			name:             "Attach missing resource policy",
+			resourcePolicies: []string{"daily-snapshots", "weekly-snapshots"},
+			attachedPolicies: []string{"https://www.googleapis.com/compute/v1/projects/test/regions/region1/resourcePolicies/daily-snapshots"},
+			expectedAdded:    []string{"projects/test/regions/region1/resourcePolicies/weekly-snapshots"},
+		},
+		{
+			name:             "Resource policies already attached",
+			resourcePolicies: []string{"daily-snapshots"},
+			attachedPolicies: []string{
+				"https://www.googleapis.com/compute/v1/projects/test/regions/region1/resourcePolicies/daily-snapshots",
+				"https://www.googleapis.com/compute/v1/projects/test/regions/region1/resourcePolicies/unmanaged",
+			},
+		},
+		{
+			name:             "Fail when the resource policy does not exist",
+			resourcePolicies: []string{"missing-policy"},
+			expectedError:    machinecontroller.InvalidMachineConfiguration("resource policy missing-policy not found in region region1"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+				return &compute.Instance{
+					Name: instance,
+					Disks: []*compute.AttachedDisk{
+						{
+							Index:  0,
+							Boot:   true,
+							Source: "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/disks/machine-0",
+						},
+					},
+				}, nil
+			}
+			mockComputeService.MockDisksGet = func(project string, zone string, disk string) (*compute.Disk, error) {
+				return &compute.Disk{Name: disk, ResourcePolicies: tc.attachedPolicies}, nil
+			}
+			mockComputeService.MockResourcePoliciesGet = func(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error) {
+				if resourcePolicy == "missing-policy" {
+					return nil, &googleapi.Error{Code: 404}
+				}
+				return &compute.ResourcePolicy{Name: resourcePolicy}, nil
+			}
+			var added []string
+			mockComputeService.MockDisksAddResourcePolicies = func(project string, zone string, disk string, resourcePolicies []string) (*compute.Operation, error) {
+				if disk != "machine-0" {
+					t.Errorf("Expected resource policies to be attached to disk machine-0, got: %s", disk)
+				}
+				added = append(added, resourcePolicies...)
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "machine-0",
+					},
+				},
+				coreClient: controllerfake.NewFakeClient(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Region: "region1",
+					Zone:   "zone1",
+					Disks: []*machinev1.GCPDisk{
+						{
+							Boot:             true,
+							ResourcePolicies: tc.resourcePolicies,
+						},
+					},
+				},
+				projectID:      "test",
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+			})
+
+			err := r.reconcileDiskResourcePolicies()
+			if tc.expectedError != nil {
+				if err == nil || err.Error() != tc.expectedError.Error() {
+					t.Errorf("Expected: %v, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+
+			assertStrings(t, "added resource policies", tc.expectedAdded, added)
+		})
+	}
+}
+
+func assertStrings(t *testing.T, what string, expected, got []string) {
+	t.Helper()
+	if strings.Join(expected, ",") != strings.Join(got, ",") {
+		t.Errorf("Expected %s: %v, got: %v", what, expected, got)
+	}
+}
@@ -0,0 +1,228 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	dns "google.golang.org/api/dns/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultDNSRecordTTL = 300
+
+	dnsRecordTypeA    = "A"
+	dnsRecordTypeAAAA = "AAAA"
+
+	// dnsRecordsReadyConditionType reports whether the records of the machine point to its instance.
+	dnsRecordsReadyConditionType = "DNSRecordsReady"
+	dnsRecordsReadyReason        = "DNSRecordsReady"
+	dnsRecordsFailedReason       = "DNSRecordsFailed"
+)
+
+// managedZoneDNSNames caches the DNS name of the managed zones by project and name, so that the
+// zone is not read on every reconciliation. The DNS name of a managed zone cannot be changed.
+var managedZoneDNSNames = &dnsNameCache{}
+
+type dnsNameCache struct {
+	mutex sync.Mutex
+	names map[string]string
+}
+
+func (c *dnsNameCache) get(projectID, managedZone string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	name, ok := c.names[projectID+"/"+managedZone]
+	return name, ok
+}
+
+func (c *dnsNameCache) set(projectID, managedZone, dnsName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.names == nil {
+		c.names = map[string]string{}
+	}
+	c.names[projectID+"/"+managedZone] = dnsName
+}
+
+// dnsRecordName returns the project of the managed zone the machine is registered in and the
+// fully qualified name of the records of the machine in that zone.
+func (r *Reconciler) dnsRecordName(ctx context.Context) (string, string, error) {
+	projectID := r.providerSpec.DNSRecords.ProjectID
+	if projectID == "" {
+		projectID = r.projectID
+	}
+	managedZone := r.providerSpec.DNSRecords.ManagedZone
+
+	dnsName, ok := managedZoneDNSNames.get(projectID, managedZone)
+	if !ok {
+		zone, err := r.dnsService.ManagedZonesGet(ctx, projectID, managedZone)
+		if err != nil {
+			if isNotFoundError(err) {
+				return "", "", machinecontroller.InvalidMachineConfiguration("managed zone %s not found in project %s", managedZone, projectID)
+			}
+			return "", "", fmt.Errorf("failed to get managed zone %s: %w", managedZone, err)
+		}
+		dnsName = zone.DnsName
+		if !strings.HasSuffix(dnsName, ".") {
+			dnsName += "."
+		}
+		managedZoneDNSNames.set(projectID, managedZone, dnsName)
+	}
+	return projectID, fmt.Sprintf("%s.%s", r.machine.Name, dnsName), nil
+}
+
+// registerDNSRecords reconciles the records of the machine and reports the result in the DNSRecordsReady
+// condition and, on failure, in an event. The machine is usable without its records, so failures are not
+// returned. It returns the name of the records once they point to the instance.
+func (r *Reconciler) registerDNSRecords(instance *compute.Instance) string {
+	if r.providerSpec.DNSRecords == nil {
+		return ""
+	}
+
+	name, err := r.reconcileDNSRecords(instance)
+	if err != nil {
+		klog.Warningf("%s: failed to reconcile DNS records: %v", r.machine.Name, err)
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    dnsRecordsReadyConditionType,
+			Status:  metav1.ConditionFalse,
+			Reason:  dnsRecordsFailedReason,
+			Message: err.Error(),
+		})
+		if r.eventRecorder != nil {
+			r.eventRecorder.Eventf(r.machine, corev1.EventTypeWarning, dnsRecordsFailedReason, "Failed to reconcile DNS records: %v", err)
+		}
+		return ""
+	}
+
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    dnsRecordsReadyConditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  dnsRecordsReadyReason,
+		Message: fmt.Sprintf("DNS records %s point to the instance", name),
+	})
+	return name
+}
+
+// reconcileDNSRecords makes the A and AAAA records of the machine point to the internal IP addresses
+// of the first network interface of the instance. It returns the name of the records, without the
+// trailing dot, or an empty string when the machine is not registered in a managed zone.
+func (r *Reconciler) reconcileDNSRecords(instance *compute.Instance) (string, error) {
+	if r.providerSpec.DNSRecords == nil {
+		return "", nil
+	}
+
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	projectID, name, err := r.dnsRecordName(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	desired := map[string][]string{}
+	if len(instance.NetworkInterfaces) > 0 {
+		nic := instance.NetworkInterfaces[0]
+		if nic.NetworkIP != "" {
+			desired[dnsRecordTypeA] = []string{nic.NetworkIP}
+		}
+		if nic.Ipv6Address != "" {
+			desired[dnsRecordTypeAAAA] = []string{nic.Ipv6Address}
+		}
+	}
+
+	for _, recordType := range []string{dnsRecordTypeA, dnsRecordTypeAAAA} {
+		if err := r.syncDNSRecord(ctx, projectID, name, recordType, desired[recordType]); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(name, "."), nil
+}
+
+// syncDNSRecord creates, updates or deletes the record set so that it resolves to the given addresses.
+func (r *Reconciler) syncDNSRecord(ctx context.Context, projectID string, name string, recordType string, rrdatas []string) error {
+	managedZone := r.providerSpec.DNSRecords.ManagedZone
+	ttl := r.providerSpec.DNSRecords.TTL
+	if ttl == 0 {
+		ttl = defaultDNSRecordTTL
+	}
+	recordSet := &dns.ResourceRecordSet{
+		Name:    name,
+		Type:    recordType,
+		Ttl:     ttl,
+		Rrdatas: rrdatas,
+	}
+
+	existing, err := r.dnsService.ResourceRecordSetsGet(ctx, projectID, managedZone, name, recordType)
+	switch {
+	case isNotFoundError(err) && len(rrdatas) == 0:
+		return nil
+	case isNotFoundError(err):
+		klog.Infof("%s: creating %s record %s", r.machine.Name, recordType, name)
+		if _, err := r.dnsService.ResourceRecordSetsCreate(ctx, projectID, managedZone, recordSet); err != nil {
+			return fmt.Errorf("failed to create %s record %s: %w", recordType, name, err)
+		}
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get %s record %s: %w", recordType, name, err)
+	case len(rrdatas) == 0:
+		return r.deleteDNSRecord(ctx, projectID, name, recordType)
+	case existing.Ttl == ttl && slices.Equal(existing.Rrdatas, rrdatas):
+		return nil
+	}
+
+	klog.Infof("%s: updating %s record %s to %v", r.machine.Name, recordType, name, rrdatas)
+	if _, err := r.dnsService.ResourceRecordSetsPatch(ctx, projectID, managedZone, recordSet); err != nil {
+		return fmt.Errorf("failed to update %s record %s: %w", recordType, name, err)
+	}
+	return nil
+}
+
+// deleteDNSRecords removes the records of the machine from its managed zone.
+func (r *Reconciler) deleteDNSRecords() error {
+	if r.providerSpec.DNSRecords == nil {
+		return nil
+	}
+
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	projectID, name, err := r.dnsRecordName(ctx)
+	if isInvalidMachineConfigurationError(err) {
+		// Nothing to clean up when the managed zone does not exist
+		klog.Warningf("%s: skipping deletion of DNS records: %v", r.machine.Name, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, recordType := range []string{dnsRecordTypeA, dnsRecordTypeAAAA} {
+		if err := r.deleteDNSRecord(ctx, projectID, name, recordType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) deleteDNSRecord(ctx context.Context, projectID string, name string, recordType string) error {
+	err := r.dnsService.ResourceRecordSetsDelete(ctx, projectID, r.providerSpec.DNSRecords.ManagedZone, name, recordType)
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s record %s: %w", recordType, name, err)
+	}
+	klog.Infof("%s: deleted %s record %s", r.machine.Name, recordType, name)
+	return nil
+}
@@ -0,0 +1,121 @@
+package machine
+
+import (
+	"fmt"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	compute "google.golang.org/api/compute/v1"
+	dns "google.golang.org/api/dns/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDNSRecordsLifecycle(t *testing.T) {
+	fakeDNSService := dnsservice.NewFakeDNSService(&dns.ManagedZone{Name: "nodes", DnsName: "nodes.example.internal."})
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+
+	nic := &compute.NetworkInterface{NetworkIP: "10.0.0.15", Ipv6Address: "fd20:1:2:3::"}
+	mockComputeService.MockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+		return &compute.Instance{
+			Name:              instance,
+			Status:            "RUNNING",
+			NetworkInterfaces: []*compute.NetworkInterface{nic},
+		}, nil
+	}
+
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "machine-0",
+			},
+		},
+		coreClient: controllerfake.NewFakeClient(),
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Region: "region1",
+			Zone:   "zone1",
+			DNSRecords: &machinev1.GCPDNSRecordsConfig{
+				ManagedZone: "nodes",
+			},
+		},
+		projectID:      "test",
+		providerID:     fmt.Sprintf("gce://%s/%s/%s", "test", "zone1", "machine-0"),
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+		dnsService:     fakeDNSService,
+	})
+
+	expectRecords := func(step string, expected map[string][]string) {
+		t.Helper()
+		records := fakeDNSService.Records("nodes")
+		if len(records) != len(expected) {
+			t.Errorf("%s: expected %d records, got: %v", step, len(expected), records)
+		}
+		for key, rrdatas := range expected {
+			record, ok := records[key]
+			if !ok {
+				t.Errorf("%s: expected record %s", step, key)
+				continue
+			}
+			assertStrings(t, step+" "+key, rrdatas, record.Rrdatas)
+			if record.Ttl != defaultDNSRecordTTL {
+				t.Errorf("%s: expected record %s to have the default TTL, got: %d", step, key, record.Ttl)
+			}
+		}
+	}
+	aKey := dnsservice.RecordKey("machine-0.nodes.example.internal.", "A")
+	aaaaKey := dnsservice.RecordKey("machine-0.nodes.example.internal.", "AAAA")
+
+	if err := r.reconcileMachineWithCloudState(nil); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+	expectRecords("create", map[string][]string{aKey: {"10.0.0.15"}, aaaaKey: {"fd20:1:2:3::"}})
+	expectedAddress := corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "machine-0.nodes.example.internal"}
+	found := false
+	for _, address := range r.machine.Status.Addresses {
+		found = found || address == expectedAddress
+	}
+	if !found {
+		t.Errorf("Expected address %v, got: %v", expectedAddress, r.machine.Status.Addresses)
+	}
+
+	nic.NetworkIP = "10.0.0.16"
+	nic.Ipv6Address = ""
+	if err := r.reconcileMachineWithCloudState(nil); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+	expectRecords("sync", map[string][]string{aKey: {"10.0.0.16"}})
+
+	if err := r.deleteDNSRecords(); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+	expectRecords("delete", map[string][]string{})
+
+	r.providerSpec.DNSRecords.ManagedZone = "missing"
+	if err := r.deleteDNSRecords(); err != nil {
+		t.Errorf("deleting records of a missing managed zone was not expected to return error: %v", err)
+	}
+	if _, err := r.reconcileDNSRecords(&compute.Instance{}); err == nil || err.Error() != "managed zone missing not found in project test" {
+		t.Errorf("Expected the missing managed zone to be reported, got: %v", err)
+	}
+
+	// Failing to register the records does not hold back the addresses of the machine
+	if err := r.reconcileMachineWithCloudState(nil); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+	for _, address := range r.machine.Status.Addresses {
+		if address == expectedAddress {
+			t.Errorf("Expected no address for the records, got: %v", r.machine.Status.Addresses)
+		}
+	}
+	if len(r.machine.Status.Addresses) == 0 {
+		t.Errorf("Expected the addresses of the instance to be set")
+	}
+	condition := findCondition(r.providerStatus.Conditions, dnsRecordsReadyConditionType)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != dnsRecordsFailedReason {
+		t.Errorf("Expected the %s condition to report the failure, got: %v", dnsRecordsReadyConditionType, condition)
+	}
+}
@@ -0,0 +1,119 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimachineryerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// csekRawKeySecretKey is the Secret entry holding a base64 encoded 256-bit customer-supplied key.
+	csekRawKeySecretKey = "rawKey"
+	// csekRSAEncryptedKeySecretKey is the Secret entry holding a base64 encoded RSA-wrapped customer-supplied key.
+	csekRSAEncryptedKeySecretKey = "rsaEncryptedKey"
+
+	kmsKeyAccessibleMessage = "KMS keys can be used to encrypt the disks"
+)
+
+// validateDiskEncryptionKey makes sure a disk references at most one kind of encryption key.
+func validateDiskEncryptionKey(keyRef *machinev1.GCPEncryptionKeyReference) error {
+	if keyRef == nil || keyRef.CustomerSuppliedKey == nil {
+		return nil
+	}
+	if keyRef.KMSKey != nil {
+		return fmt.Errorf("kmsKey and customerSuppliedKey are mutually exclusive")
+	}
+	if keyRef.CustomerSuppliedKey.SecretRef.Name == "" {
+		return fmt.Errorf("customerSuppliedKey must reference a secret")
+	}
+	return nil
+}
+
+// diskEncryptionKey returns the encryption key of a disk for the instance insert request.
+// Customer-supplied keys are read from their Secret: the returned key must never be logged
+// or copied into the Machine.
+func (r *Reconciler) diskEncryptionKey(keyRef *machinev1.GCPEncryptionKeyReference) (*compute.CustomerEncryptionKey, error) {
+	if keyRef == nil || keyRef.CustomerSuppliedKey == nil {
+		return generateDiskEncryptionKey(keyRef, r.projectID), nil
+	}
+
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	secretName := keyRef.CustomerSuppliedKey.SecretRef.Name
+	var keySecret corev1.Secret
+	if err := r.coreClient.Get(ctx, client.ObjectKey{Namespace: r.machine.GetNamespace(), Name: secretName}, &keySecret); err != nil {
+		if apimachineryerrors.IsNotFound(err) {
+			return nil, machinecontroller.InvalidMachineConfiguration("encryption key secret %q in namespace %q not found: %v", secretName, r.machine.GetNamespace(), err)
+		}
+		return nil, fmt.Errorf("error getting encryption key secret %q in namespace %q: %v", secretName, r.machine.GetNamespace(), err)
+	}
+
+	rawKey := strings.TrimSpace(string(keySecret.Data[csekRawKeySecretKey]))
+	rsaEncryptedKey := strings.TrimSpace(string(keySecret.Data[csekRSAEncryptedKeySecretKey]))
+	switch {
+	case rawKey != "" && rsaEncryptedKey != "":
+		return nil, machinecontroller.InvalidMachineConfiguration("secret %v/%v must not have both %q and %q fields set", r.machine.GetNamespace(), secretName, csekRawKeySecretKey, csekRSAEncryptedKeySecretKey)
+	case rawKey != "":
+		return &compute.CustomerEncryptionKey{RawKey: rawKey}, nil
+	case rsaEncryptedKey != "":
+		return &compute.CustomerEncryptionKey{RsaEncryptedKey: rsaEncryptedKey}, nil
+	}
+	return nil, machinecontroller.InvalidMachineConfiguration("secret %v/%v does not have %q or %q field set", r.machine.GetNamespace(), secretName, csekRawKeySecretKey, csekRSAEncryptedKeySecretKey)
+}
+
+// validateKMSKeys makes sure the KMS keys encrypting the disks can be used before inserting the instance
+// and reports the result in the KMSKeyAccessible condition.
+func (r *Reconciler) validateKMSKeys() error {
+	usesKMSKeys := false
+	for _, disk := range r.providerSpec.Disks {
+		if disk.EncryptionKey != nil && disk.EncryptionKey.KMSKey != nil {
+			usesKMSKeys = true
+			break
+		}
+	}
+	if !usesKMSKeys {
+		return nil
+	}
+
+	err := util.ValidateKMSKeys(r.Context, r.kmsService, r.projectID, r.providerSpec)
+	var keyErr *util.KMSKeyError
+	if errors.As(err, &keyErr) {
+		r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+			Type:    util.KMSKeyAccessibleCondition,
+			Status:  metav1.ConditionFalse,
+			Reason:  keyErr.Reason,
+			Message: keyErr.Message,
+		})
+		if keyErr.Warning {
+			// The instance insert reports the key if it turns out to be unusable
+			klog.Warningf("%s: %s", r.machine.Name, keyErr.Message)
+			return nil
+		}
+		return fmt.Errorf("KMS key cannot be used: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to validate KMS keys: %w", err)
+	}
+
+	r.providerStatus.Conditions = reconcileConditions(r.providerStatus.Conditions, metav1.Condition{
+		Type:    util.KMSKeyAccessibleCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  util.KMSKeyAccessibleReason,
+		Message: kmsKeyAccessibleMessage,
+	})
+	return nil
+}
@@ -0,0 +1,57 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// hostnameTemplateData is the data the hostname template of the provider spec is rendered with.
+type hostnameTemplateData struct {
+	MachineName string
+}
+
+// instanceHostname renders the hostname template of the provider spec for the machine.
+// It returns an empty string when no custom hostname is configured, in which case the
+// instance uses the default internal DNS name of GCP.
+func instanceHostname(machine machinev1.Machine, providerSpec machinev1.GCPMachineProviderSpec) (string, error) {
+	if providerSpec.Hostname == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New("hostname").Option("missingkey=error").Parse(providerSpec.Hostname)
+	if err != nil {
+		return "", fmt.Errorf("invalid hostname template %q: %w", providerSpec.Hostname, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, hostnameTemplateData{MachineName: machine.Name}); err != nil {
+		return "", fmt.Errorf("invalid hostname template %q: %w", providerSpec.Hostname, err)
+	}
+
+	hostname := strings.TrimSuffix(b.String(), ".")
+	if err := validateHostname(hostname); err != nil {
+		return "", err
+	}
+	return hostname, nil
+}
+
+// validateHostname checks the hostname is accepted by GCP, i.e. it is a lowercase RFC 1035
+// domain name made of at least two labels.
+func validateHostname(hostname string) error {
+	if len(hostname) > validation.DNS1123SubdomainMaxLength {
+		return fmt.Errorf("hostname %q must be no more than %d characters", hostname, validation.DNS1123SubdomainMaxLength)
+	}
+	labels := strings.Split(hostname, ".")
+	if len(labels) < 2 {
+		return fmt.Errorf("hostname %q must be a fully qualified domain name with at least two labels", hostname)
+	}
+	for _, label := range labels {
+		if errs := validation.IsDNS1035Label(label); len(errs) > 0 {
+			return fmt.Errorf("hostname %q is invalid: label %q: %s", hostname, label, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
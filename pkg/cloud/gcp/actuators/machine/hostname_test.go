@@ -0,0 +1,106 @@
+package machine
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInstanceHostname(t *testing.T) {
+	cases := []struct {
+		name             string
+		hostname         string
+		expectedHostname string
+		expectedError    string
+	}{
+		{
+			name: "No custom hostname",
+		},
+		{
+			name:             "Hostname rendered from the machine name",
+			hostname:         "{{ .MachineName }}.nodes.example.com",
+			expectedHostname: "machine-0.nodes.example.com",
+		},
+		{
+			name:             "Trailing dot is removed",
+			hostname:         "{{ .MachineName }}.nodes.example.com.",
+			expectedHostname: "machine-0.nodes.example.com",
+		},
+		{
+			name:          "Unknown template field",
+			hostname:      "{{ .Name }}.nodes.example.com",
+			expectedError: "invalid hostname template \"{{ .Name }}.nodes.example.com\": template: hostname:1:3: executing \"hostname\" at <.Name>: can't evaluate field Name in type machine.hostnameTemplateData",
+		},
+		{
+			name:          "Single label",
+			hostname:      "{{ .MachineName }}",
+			expectedError: "hostname \"machine-0\" must be a fully qualified domain name with at least two labels",
+		},
+		{
+			name:          "Uppercase label",
+			hostname:      "{{ .MachineName }}.Example.com",
+			expectedError: "hostname \"machine-0.Example.com\" is invalid: label \"Example\": a DNS-1035 label must consist of lower case alphanumeric characters or '-', start with an alphabetic character, and end with an alphanumeric character (e.g. 'my-name',  or 'abc-123', regex used for validation is '[a-z]([-a-z0-9]*[a-z0-9])?')",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine-0"}}
+			hostname, err := instanceHostname(machine, machinev1.GCPMachineProviderSpec{Hostname: tc.hostname})
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("instanceHostname was not expected to return error: %v", err)
+			}
+			if hostname != tc.expectedHostname {
+				t.Errorf("Expected hostname: %q, got: %q", tc.expectedHostname, hostname)
+			}
+		})
+	}
+}
+
+func TestHostnameNodeAddress(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+		return &compute.Instance{
+			Name:              instance,
+			Hostname:          "machine-0.nodes.example.com",
+			Status:            "RUNNING",
+			NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.0.0.15"}},
+		}, nil
+	}
+
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "machine-0",
+			},
+		},
+		coreClient: controllerfake.NewFakeClient(),
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Zone:     "zone1",
+			Hostname: "{{ .MachineName }}.nodes.example.com",
+		},
+		projectID:      "test",
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+	})
+	if err := r.reconcileMachineWithCloudState(nil); err != nil {
+		t.Fatalf("reconciler was not expected to return error: %v", err)
+	}
+
+	// The custom hostname comes right after the IP addresses, ahead of the default internal DNS names
+	expectedAddress := corev1.NodeAddress{Type: corev1.NodeInternalDNS, Address: "machine-0.nodes.example.com"}
+	if len(r.machine.Status.Addresses) < 2 || r.machine.Status.Addresses[1] != expectedAddress {
+		t.Errorf("Expected address %v after the internal IP, got: %v", expectedAddress, r.machine.Status.Addresses)
+	}
+}
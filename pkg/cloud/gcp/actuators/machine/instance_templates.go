@@ -0,0 +1,141 @@
+package machine
+
+import (
+	"errors"
+	"net/http"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/klog/v2"
+)
+
+// usesInstanceTemplateDisks tells whether the disks of the instance, and so its boot disk, are the ones
+// of the source instance template.
+func usesInstanceTemplateDisks(providerSpec *machinev1.GCPMachineProviderSpec) bool {
+	return providerSpec.SourceInstanceTemplate != "" && len(providerSpec.Disks) == 0
+}
+
+// applyInstanceTemplate prepares the instance to be created from the source instance template of the
+// machine and returns the relative URL of the template, empty when the machine has none.
+// The fields the providerSpec does not set are left to the template, the fields it sets override the
+// template and the labels, network tags, metadata and resource manager tags are merged with the ones
+// of the template.
+func (r *Reconciler) applyInstanceTemplate(instance *compute.Instance) (string, error) {
+	if r.providerSpec.SourceInstanceTemplate == "" {
+		return "", nil
+	}
+
+	template, url, err := util.GetInstanceTemplate(r.computeService, r.projectID, r.providerSpec)
+	if err != nil {
+		// Only transient API errors are retried, a reference to a template that cannot be used is not
+		var googleError *googleapi.Error
+		if !errors.As(err, &googleError) || googleError.Code == http.StatusNotFound {
+			return "", machinecontroller.InvalidMachineConfiguration("invalid source instance template: %v", err)
+		}
+		return "", err
+	}
+	properties := template.Properties
+	klog.V(3).Infof("%s: creating instance from instance template %s", r.machine.Name, url)
+
+	if r.providerSpec.MachineType == "" {
+		instance.MachineType = ""
+	}
+	if len(r.providerSpec.Disks) == 0 {
+		instance.Disks = nil
+	}
+	if len(r.providerSpec.NetworkInterfaces) == 0 {
+		instance.NetworkInterfaces = nil
+	}
+	if len(r.providerSpec.ServiceAccounts) == 0 {
+		instance.ServiceAccounts = nil
+	}
+	if len(r.providerSpec.GPUs) == 0 {
+		instance.GuestAccelerators = nil
+	}
+	if r.providerSpec.ShieldedInstanceConfig == (machinev1.GCPShieldedInstanceConfig{}) {
+		instance.ShieldedInstanceConfig = nil
+	}
+	instance.Scheduling = mergeScheduling(properties.Scheduling, instance.Scheduling)
+
+	for key, value := range properties.Labels {
+		if _, ok := instance.Labels[key]; !ok {
+			instance.Labels[key] = value
+		}
+	}
+	if properties.Tags != nil {
+		for _, tag := range properties.Tags.Items {
+			if !containsString(instance.Tags.Items, tag) {
+				instance.Tags.Items = append(instance.Tags.Items, tag)
+			}
+		}
+	}
+	if properties.Metadata != nil {
+		instance.Metadata.Items = mergeMetadataItems(properties.Metadata.Items, instance.Metadata.Items)
+	}
+	if len(properties.ResourceManagerTags) > 0 {
+		if instance.Params.ResourceManagerTags == nil {
+			instance.Params.ResourceManagerTags = map[string]string{}
+		}
+		for key, value := range properties.ResourceManagerTags {
+			if _, ok := instance.Params.ResourceManagerTags[key]; !ok {
+				instance.Params.ResourceManagerTags[key] = value
+			}
+		}
+	}
+
+	return url, nil
+}
+
+// mergeScheduling overrides the scheduling of the instance template with the scheduling options set
+// by the providerSpec, as the scheduling of the request replaces the one of the template as a whole.
+func mergeScheduling(templateScheduling, scheduling *compute.Scheduling) *compute.Scheduling {
+	if templateScheduling == nil {
+		return scheduling
+	}
+
+	merged := *templateScheduling
+	if scheduling.Preemptible {
+		merged.Preemptible = true
+	}
+	if scheduling.OnHostMaintenance != "" {
+		merged.OnHostMaintenance = scheduling.OnHostMaintenance
+	}
+	if scheduling.ProvisioningModel != "" {
+		merged.ProvisioningModel = scheduling.ProvisioningModel
+	}
+	if len(scheduling.NodeAffinities) > 0 {
+		merged.NodeAffinities = scheduling.NodeAffinities
+	}
+	if scheduling.AutomaticRestart != nil {
+		merged.AutomaticRestart = scheduling.AutomaticRestart
+	}
+	return &merged
+}
+
+// mergeMetadataItems adds the metadata items of the instance template the providerSpec does not set.
+// An empty user data, when the machine has no user data secret, does not replace the one of the template.
+func mergeMetadataItems(templateItems, items []*compute.MetadataItems) []*compute.MetadataItems {
+	templateKeys := map[string]bool{}
+	for _, item := range templateItems {
+		templateKeys[item.Key] = true
+	}
+
+	merged := []*compute.MetadataItems{}
+	keys := map[string]bool{}
+	for _, item := range items {
+		if templateKeys[item.Key] && (item.Value == nil || *item.Value == "") {
+			continue
+		}
+		merged = append(merged, item)
+		keys[item.Key] = true
+	}
+	for _, item := range templateItems {
+		if !keys[item.Key] {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,202 @@
+package machine
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestApplyInstanceTemplate(t *testing.T) {
+	template := &compute.InstanceTemplate{
+		Name: "workers",
+		Properties: &compute.InstanceProperties{
+			MachineType: "n2-standard-4",
+			Labels:      map[string]string{"team": "infra", "env": "test"},
+			Tags:        &compute.Tags{Items: []string{"worker", "template"}},
+			Metadata: &compute.Metadata{Items: []*compute.MetadataItems{
+				{Key: "user-data", Value: ptr.To("template user data")},
+				{Key: "startup-script", Value: ptr.To("template script")},
+			}},
+			ResourceManagerTags: map[string]string{"tagKeys/1": "tagValues/1"},
+			Scheduling: &compute.Scheduling{
+				OnHostMaintenance: "TERMINATE",
+				AutomaticRestart:  ptr.To(false),
+			},
+		},
+	}
+
+	cases := []struct {
+		name                   string
+		sourceInstanceTemplate string
+		providerSpec           machinev1.GCPMachineProviderSpec
+		userData               string
+		templateErr            error
+		expectedURL            string
+		expectedError          string
+		expectInvalidSpec      bool
+		validateInstance       func(t *testing.T, instance *compute.Instance)
+	}{
+		{
+			name: "No source instance template",
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.MachineType == "" {
+					t.Errorf("Expected the machine type to be kept")
+				}
+			},
+		},
+		{
+			name:                   "Fields left to the template",
+			sourceInstanceTemplate: "workers",
+			expectedURL:            "projects/test/global/instanceTemplates/workers",
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.MachineType != "" || instance.Disks != nil || instance.ShieldedInstanceConfig != nil {
+					t.Errorf("Expected the machine type, disks and shielded instance config of the template, got: %q, %v, %v", instance.MachineType, instance.Disks, instance.ShieldedInstanceConfig)
+				}
+				expectedLabels := map[string]string{"team": "machines", "env": "test", "machine.openshift.io/cluster-api-cluster": "CLUSTERID"}
+				if !reflect.DeepEqual(instance.Labels, expectedLabels) {
+					t.Errorf("Expected labels: %v, got: %v", expectedLabels, instance.Labels)
+				}
+				assertStrings(t, "tags", []string{"worker", "gpu", "template"}, instance.Tags.Items)
+				var metadata []string
+				for _, item := range instance.Metadata.Items {
+					metadata = append(metadata, item.Key+"="+*item.Value)
+				}
+				assertStrings(t, "metadata", []string{"startup-script=machine script", "user-data=template user data"}, metadata)
+				expectedTags := map[string]string{"tagKeys/1": "tagValues/1", "tagKeys/2": "tagValues/2"}
+				if !reflect.DeepEqual(instance.Params.ResourceManagerTags, expectedTags) {
+					t.Errorf("Expected resource manager tags: %v, got: %v", expectedTags, instance.Params.ResourceManagerTags)
+				}
+				if instance.Scheduling.OnHostMaintenance != "TERMINATE" || instance.Scheduling.AutomaticRestart == nil || *instance.Scheduling.AutomaticRestart {
+					t.Errorf("Expected the scheduling of the template, got: %+v", instance.Scheduling)
+				}
+			},
+		},
+		{
+			name:                   "Fields overriding the template",
+			sourceInstanceTemplate: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				MachineType:            "n2-standard-8",
+				Disks:                  []*machinev1.GCPDisk{{Boot: true, Image: "rhcos"}},
+				ShieldedInstanceConfig: machinev1.GCPShieldedInstanceConfig{SecureBoot: machinev1.SecureBootPolicyEnabled},
+			},
+			userData:    "machine user data",
+			expectedURL: "projects/test/global/instanceTemplates/workers",
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.MachineType != "zones/zone1/machineTypes/n2-standard-8" {
+					t.Errorf("Expected the machine type of the machine, got: %q", instance.MachineType)
+				}
+				if len(instance.Disks) != 1 || instance.ShieldedInstanceConfig == nil {
+					t.Errorf("Expected the disks and shielded instance config of the machine, got: %v, %v", instance.Disks, instance.ShieldedInstanceConfig)
+				}
+				var metadata []string
+				for _, item := range instance.Metadata.Items {
+					metadata = append(metadata, item.Key+"="+*item.Value)
+				}
+				assertStrings(t, "metadata", []string{"user-data=machine user data", "startup-script=machine script"}, metadata)
+			},
+		},
+		{
+			name:                   "Regional source instance template",
+			sourceInstanceTemplate: "projects/other/regions/region1/instanceTemplates/workers",
+			expectedURL:            "projects/other/regions/region1/instanceTemplates/workers",
+		},
+		{
+			name:                   "Source instance template URL",
+			sourceInstanceTemplate: "https://www.googleapis.com/compute/v1/projects/other/global/instanceTemplates/workers",
+			expectedURL:            "projects/other/global/instanceTemplates/workers",
+		},
+		{
+			name:                   "Regional source instance template in another region",
+			sourceInstanceTemplate: "projects/test/regions/region2/instanceTemplates/workers",
+			expectedError:          "invalid source instance template: instance template \"workers\" is in region \"region2\", not in the region \"region1\" of the machine",
+			expectInvalidSpec:      true,
+		},
+		{
+			name:                   "Invalid source instance template reference",
+			sourceInstanceTemplate: "projects/test/zones/zone1/instanceTemplates/workers",
+			expectedError:          "invalid source instance template: failed to parse source instance template reference: unrecognized instance template path format in \"projects/test/zones/zone1/instanceTemplates/workers\"",
+			expectInvalidSpec:      true,
+		},
+		{
+			name:                   "Missing source instance template",
+			sourceInstanceTemplate: "workers",
+			templateErr:            &googleapi.Error{Code: http.StatusNotFound},
+			expectedError:          "invalid source instance template: unable to retrieve instance template \"workers\" in project \"test\": googleapi: got HTTP response code 404 with body: ",
+			expectInvalidSpec:      true,
+		},
+		{
+			name:                   "Failure to get the source instance template",
+			sourceInstanceTemplate: "workers",
+			templateErr:            &googleapi.Error{Code: http.StatusInternalServerError},
+			expectedError:          "unable to retrieve instance template \"workers\" in project \"test\": googleapi: got HTTP response code 500 with body: ",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockInstanceTemplatesGet = func(project string, instanceTemplate string) (*compute.InstanceTemplate, error) {
+				if tc.templateErr != nil {
+					return nil, tc.templateErr
+				}
+				return template, nil
+			}
+			mockComputeService.MockRegionInstanceTemplatesGet = func(project string, region string, instanceTemplate string) (*compute.InstanceTemplate, error) {
+				return template, nil
+			}
+
+			providerSpec := tc.providerSpec
+			providerSpec.Region = "region1"
+			providerSpec.Zone = "zone1"
+			providerSpec.SourceInstanceTemplate = tc.sourceInstanceTemplate
+			r := newReconciler(&machineScope{
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}},
+				providerSpec:   &providerSpec,
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			instance := &compute.Instance{
+				Name:        "worker-0",
+				MachineType: "zones/zone1/machineTypes/" + providerSpec.MachineType,
+				Labels:      map[string]string{"team": "machines", "machine.openshift.io/cluster-api-cluster": "CLUSTERID"},
+				Tags:        &compute.Tags{Items: []string{"worker", "gpu"}},
+				Metadata: &compute.Metadata{Items: []*compute.MetadataItems{
+					{Key: "user-data", Value: ptr.To(tc.userData)},
+					{Key: "startup-script", Value: ptr.To("machine script")},
+				}},
+				Params:                 &compute.InstanceParams{ResourceManagerTags: map[string]string{"tagKeys/2": "tagValues/2"}},
+				Scheduling:             &compute.Scheduling{},
+				ShieldedInstanceConfig: &compute.ShieldedInstanceConfig{EnableVtpm: true},
+			}
+			for range providerSpec.Disks {
+				instance.Disks = append(instance.Disks, &compute.AttachedDisk{Boot: true})
+			}
+
+			url, err := r.applyInstanceTemplate(instance)
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if isInvalidMachineConfigurationError(err) != tc.expectInvalidSpec {
+				t.Errorf("Expected invalid machine configuration: %v, got: %v", tc.expectInvalidSpec, err)
+			}
+			if url != tc.expectedURL {
+				t.Errorf("Expected source instance template %q, got: %q", tc.expectedURL, url)
+			}
+			if tc.validateInstance != nil {
+				tc.validateInstance(t, instance)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package machine
+
+import (
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"google.golang.org/api/compute/v1"
+)
+
+// instanceGroupAttachment is the membership of the instance in an unmanaged instance group
+// of its zone, which is a backend of the backend service of a load balancer.
+type instanceGroupAttachment struct {
+	instanceGroup  string
+	backendService string
+	global         bool
+	balancingMode  string
+}
+
+// instanceGroupAttachments returns the instance groups of the load balancers of the provider spec
+// the machine must be a member of. The control plane instance groups are owned by the control plane
+// controller, which computes their members from all the control plane machines.
+func (r *Reconciler) instanceGroupAttachments() []instanceGroupAttachment {
+	var attachments []instanceGroupAttachment
+	for _, lb := range r.providerSpec.LoadBalancers {
+		attachments = append(attachments, instanceGroupAttachment{
+			instanceGroup:  lb.InstanceGroup,
+			backendService: lb.BackendService,
+			global:         lb.BackendServiceScope == machinev1.BackendServiceScopeGlobal,
+			balancingMode:  toComputeBalancingMode(lb.BalancingMode),
+		})
+	}
+	return attachments
+}
+
+// controlPlaneInstanceGroupAttachment returns the membership of a control plane machine
+// in the internal API load balancer.
+func (r *Reconciler) controlPlaneInstanceGroupAttachment() instanceGroupAttachment {
+	return instanceGroupAttachment{
+		instanceGroup:  r.controlPlaneGroupName(),
+		backendService: r.backendServiceName(),
+		global:         r.controlPlaneBackendServiceScope == machinev1.BackendServiceScopeGlobal,
+		balancingMode:  "CONNECTION",
+	}
+}
+
+// getBackendService returns the regional or global backend service of the attachment.
+func (r *Reconciler) getBackendService(attachment instanceGroupAttachment) (*compute.BackendService, error) {
+	if attachment.global {
+		return r.computeService.GlobalBackendServiceGet(r.projectID, attachment.backendService)
+	}
+	return r.computeService.BackendServiceGet(r.projectID, r.providerSpec.Region, attachment.backendService)
+}
+
+// getBackendServiceHealth returns the health of the instances of the instance group of the attachment
+// in its regional or global backend service.
+func (r *Reconciler) getBackendServiceHealth(attachment instanceGroupAttachment) (*compute.BackendServiceGroupHealth, error) {
+	group := r.FQDNInstanceGroup(attachment.instanceGroup)
+	if attachment.global {
+		return r.computeService.GlobalBackendServiceGetHealth(r.projectID, attachment.backendService, group)
+	}
+	return r.computeService.BackendServiceGetHealth(r.projectID, r.providerSpec.Region, attachment.backendService, group)
+}
+
+// hasBackend returns whether the instance group is a backend of the backend service.
+func hasBackend(backendService *compute.BackendService, group string) bool {
+	for _, backend := range backendService.Backends {
+		if backend.Group == group {
+			return true
+		}
+	}
+	return false
+}
+
+func toComputeBalancingMode(mode machinev1.GCPBalancingMode) string {
+	switch mode {
+	case machinev1.BalancingModeUtilization:
+		return "UTILIZATION"
+	default:
+		return "CONNECTION"
+	}
+}
+
+func validateLoadBalancerAttachment(attachment machinev1.GCPLoadBalancerAttachment) error {
+	if attachment.InstanceGroup == "" {
+		return fmt.Errorf("load balancer instance group must have a valid name")
+	}
+	if attachment.BackendService == "" {
+		return fmt.Errorf("load balancer backend service must have a valid name")
+	}
+
+	switch attachment.BackendServiceScope {
+	case "", machinev1.BackendServiceScopeRegional, machinev1.BackendServiceScopeGlobal:
+	default:
+		return fmt.Errorf("unknown backend service scope %q, valid values are %q and %q", attachment.BackendServiceScope, machinev1.BackendServiceScopeRegional, machinev1.BackendServiceScopeGlobal)
+	}
+
+	switch attachment.BalancingMode {
+	case "", machinev1.BalancingModeConnection, machinev1.BalancingModeUtilization:
+	default:
+		return fmt.Errorf("unknown balancing mode %q, valid values are %q and %q", attachment.BalancingMode, machinev1.BalancingModeConnection, machinev1.BalancingModeUtilization)
+	}
+	return nil
+}
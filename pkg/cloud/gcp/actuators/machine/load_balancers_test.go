@@ -0,0 +1,299 @@
+package machine
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRegisterInstanceToLoadBalancers(t *testing.T) {
+	instanceGroupURL := func(name string) string {
+		return "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instanceGroups/" + name
+	}
+
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	existingGroups := map[string]bool{"ingress-zone1": true}
+	var createdGroups []string
+	mockComputeService.MockInstanceGroupGet = func(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error) {
+		if !existingGroups[instanceGroupName] {
+			return nil, &googleapi.Error{Code: http.StatusNotFound}
+		}
+		return &compute.InstanceGroup{Name: instanceGroupName}, nil
+	}
+	mockComputeService.MockInstanceGroupInsert = func(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error) {
+		createdGroups = append(createdGroups, instanceGroup.Name)
+		existingGroups[instanceGroup.Name] = true
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockInstanceGroupsListInstances = func(project string, zone string, instanceGroup string, request *compute.InstanceGroupsListInstancesRequest) (*compute.InstanceGroupsListInstances, error) {
+		return &compute.InstanceGroupsListInstances{}, nil
+	}
+	var addedToGroups []string
+	mockComputeService.MockInstanceGroupsAddInstances = func(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+		addedToGroups = append(addedToGroups, instanceGroup)
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	mockComputeService.MockBackendServiceGet = func(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+		if region != "region1" || backendServiceName != "ingress" {
+			t.Errorf("Unexpected regional backend service %s in region %s", backendServiceName, region)
+		}
+		return &compute.BackendService{
+			Name:     backendServiceName,
+			Backends: []*compute.Backend{{Group: instanceGroupURL("ingress-zone1")}},
+		}, nil
+	}
+	mockComputeService.MockRegionBackendServicesPatch = func(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+		t.Errorf("Regional backend service %s was not expected to be updated", backendServiceName)
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	var updatedGlobalBackendService *compute.BackendService
+	mockComputeService.MockBackendServicesPatch = func(project string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+		if backendServiceName != "web" {
+			t.Errorf("Unexpected global backend service %s", backendServiceName)
+		}
+		updatedGlobalBackendService = backendService
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "machine-0",
+				Labels: map[string]string{
+					openshiftMachineRoleLabel:       "worker",
+					machinev1.MachineClusterIDLabel: "CLUSTERID",
+				},
+			},
+		},
+		coreClient: controllerfake.NewFakeClient(),
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Region: "region1",
+			Zone:   "zone1",
+			NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+				{
+					Network:    "network",
+					Subnetwork: "subnetwork",
+				},
+			},
+			LoadBalancers: []machinev1.GCPLoadBalancerAttachment{
+				{
+					InstanceGroup:  "ingress-zone1",
+					BackendService: "ingress",
+				},
+				{
+					InstanceGroup:       "web-zone1",
+					BackendService:      "web",
+					BackendServiceScope: machinev1.BackendServiceScopeGlobal,
+					BalancingMode:       machinev1.BalancingModeUtilization,
+				},
+			},
+		},
+		projectID: "test",
+		providerStatus: &machinev1.GCPMachineProviderStatus{
+			InstanceState: pointer.String("RUNNING"),
+		},
+		computeService: mockComputeService,
+	})
+
+	attachments := r.instanceGroupAttachments()
+	if len(attachments) != 2 {
+		t.Fatalf("Expected the two load balancers of a worker machine, got: %v", attachments)
+	}
+	for _, attachment := range attachments {
+		if err := r.registerInstanceToInstanceGroup(attachment); err != nil {
+			t.Errorf("reconciler was not expected to return error: %v", err)
+		}
+	}
+
+	assertStrings(t, "created instance groups", []string{"web-zone1"}, createdGroups)
+	assertStrings(t, "instance groups", []string{"ingress-zone1", "web-zone1"}, addedToGroups)
+	expectedBackends := []*compute.Backend{{Group: instanceGroupURL("web-zone1"), BalancingMode: "UTILIZATION"}}
+	if updatedGlobalBackendService == nil || !reflect.DeepEqual(updatedGlobalBackendService.Backends, expectedBackends) {
+		t.Errorf("Expected global backend service backends: %v, got: %v", expectedBackends, updatedGlobalBackendService)
+	}
+}
+
+func TestInstanceGroupAttachments(t *testing.T) {
+	r := newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "machine-0",
+				Labels: map[string]string{
+					openshiftMachineRoleLabel:       masterMachineRole,
+					machinev1.MachineClusterIDLabel: "CLUSTERID",
+				},
+			},
+		},
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Region: "region1",
+			Zone:   "zone1",
+			LoadBalancers: []machinev1.GCPLoadBalancerAttachment{
+				{
+					InstanceGroup:       "external-api-zone1",
+					BackendService:      "external-api",
+					BackendServiceScope: machinev1.BackendServiceScopeRegional,
+					BalancingMode:       machinev1.BalancingModeConnection,
+				},
+			},
+		},
+		projectID: "test",
+	})
+
+	// The control plane instance group is owned by the control plane controller
+	expected := []instanceGroupAttachment{
+		{instanceGroup: "external-api-zone1", backendService: "external-api", balancingMode: "CONNECTION"},
+	}
+	if attachments := r.instanceGroupAttachments(); !reflect.DeepEqual(attachments, expected) {
+		t.Errorf("Expected attachments: %v, got: %v", expected, attachments)
+	}
+}
+
+// fakeBackendService is a regional backend service rejecting patches with a stale fingerprint.
+type fakeBackendService struct {
+	lock        sync.Mutex
+	generation  int
+	backends    []*compute.Backend
+	patches     int
+	conflicting int
+}
+
+func (f *fakeBackendService) get(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return &compute.BackendService{
+		Name:        backendServiceName,
+		Backends:    append([]*compute.Backend{}, f.backends...),
+		Fingerprint: fmt.Sprintf("fingerprint-%d", f.generation),
+	}, nil
+}
+
+func (f *fakeBackendService) patch(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.patches++
+	// Simulate a change by another client, e.g. the installer, for the first patches
+	if f.conflicting > 0 {
+		f.conflicting--
+		f.generation++
+	}
+	if backendService.Fingerprint != fmt.Sprintf("fingerprint-%d", f.generation) {
+		return nil, &googleapi.Error{Code: http.StatusPreconditionFailed}
+	}
+	f.generation++
+	f.backends = backendService.Backends
+	return &compute.Operation{Status: "DONE"}, nil
+}
+
+func newBackendServiceReconciler(zone string, fake *fakeBackendService) *Reconciler {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	mockComputeService.MockBackendServiceGet = fake.get
+	mockComputeService.MockRegionBackendServicesPatch = fake.patch
+
+	return newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "machine-" + zone,
+				Labels: map[string]string{
+					openshiftMachineRoleLabel:       masterMachineRole,
+					machinev1.MachineClusterIDLabel: "CLUSTERID",
+				},
+			},
+		},
+		coreClient: controllerfake.NewFakeClient(),
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Region: "region1",
+			Zone:   zone,
+		},
+		projectID:      "test",
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+	})
+}
+
+func TestUpdateBackendServiceWithInstanceGroup(t *testing.T) {
+	backoff := util.BackendServiceUpdateBackoff
+	util.BackendServiceUpdateBackoff.Duration = time.Millisecond
+	defer func() { util.BackendServiceUpdateBackoff = backoff }()
+
+	cases := []struct {
+		name            string
+		conflicting     int
+		expectedPatches int
+		expectedError   string
+	}{
+		{
+			name:            "Patch with the fingerprint",
+			expectedPatches: 1,
+		},
+		{
+			name:            "Retry when the backend service is modified concurrently",
+			conflicting:     2,
+			expectedPatches: 3,
+		},
+		{
+			name:            "Give up when the backend service keeps being modified",
+			conflicting:     util.MaxBackendServiceUpdateAttempts,
+			expectedPatches: util.MaxBackendServiceUpdateAttempts,
+			expectedError:   "addInstanceGroupToBackendService request failed: failed to patch backend service CLUSTERID-api-internal: googleapi: got HTTP response code 412 with body: ",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeBackendService{conflicting: tc.conflicting}
+			r := newBackendServiceReconciler("zone1", fake)
+
+			err := r.updateBackendServiceWithInstanceGroup(r.controlPlaneInstanceGroupAttachment())
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if fake.patches != tc.expectedPatches {
+				t.Errorf("Expected %d patches, got: %d", tc.expectedPatches, fake.patches)
+			}
+		})
+	}
+}
+
+func TestUpdateBackendServiceWithInstanceGroupConcurrently(t *testing.T) {
+	fake := &fakeBackendService{}
+	zones := []string{"zone1", "zone2", "zone3"}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(zones))
+	for i, zone := range zones {
+		wg.Add(1)
+		go func(i int, r *Reconciler) {
+			defer wg.Done()
+			errs[i] = r.updateBackendServiceWithInstanceGroup(r.controlPlaneInstanceGroupAttachment())
+		}(i, newBackendServiceReconciler(zone, fake))
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("reconciler of %s was not expected to return error: %v", zones[i], err)
+		}
+	}
+	if len(fake.backends) != len(zones) {
+		t.Errorf("Expected the instance groups of the %d zones to be backends, got: %d", len(zones), len(fake.backends))
+	}
+	if fake.patches != len(zones) {
+		t.Errorf("Expected patches to be serialized without conflicts, got %d patches", fake.patches)
+	}
+}
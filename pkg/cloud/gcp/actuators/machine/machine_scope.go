@@ -7,12 +7,15 @@ import (
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	machineapierros "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
 	"k8s.io/component-base/featuregate"
 
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -25,7 +28,12 @@ type machineScopeParams struct {
 	machine              *machinev1.Machine
 	computeClientBuilder computeservice.BuilderFuncType
 	tagsClientBuilder    tagservice.BuilderFuncType
+	kmsClientBuilder     kmsservice.BuilderFuncType
+	dnsClientBuilder     dnsservice.BuilderFuncType
 	featureGates         featuregate.FeatureGate
+	eventRecorder        record.EventRecorder
+
+	controlPlaneBackendServiceScope machinev1.GCPBackendServiceScope
 }
 
 // machineScope defines a scope defined around a machine and its cluster.
@@ -40,6 +48,10 @@ type machineScope struct {
 	providerSpec   *machinev1.GCPMachineProviderSpec
 	providerStatus *machinev1.GCPMachineProviderStatus
 
+	// specZone is the zone of the provider spec as stored in the machine. The zone of the providerSpec
+	// of the scope is the zone of the instance, which is recorded in the providerStatus when it differs.
+	specZone string
+
 	// origMachine captures original value of machine before it is updated (to
 	// skip object updated if nothing is changed)
 	origMachine *machinev1.Machine
@@ -52,7 +64,18 @@ type machineScope struct {
 	// tagService is for handling resource manager tags related operations.
 	tagService tagservice.TagService
 
+	// kmsService is for validating the KMS keys used to encrypt disks.
+	kmsService kmsservice.KMSService
+	// dnsService is for registering the machine in a Cloud DNS managed zone.
+	dnsService dnsservice.DNSService
+
+	// eventRecorder reports the problems which do not fail the reconciliation of the machine.
+	eventRecorder record.EventRecorder
+
 	featureGates featuregate.FeatureGate
+
+	// controlPlaneBackendServiceScope is the scope of the backend service of the internal API load balancer.
+	controlPlaneBackendServiceScope machinev1.GCPBackendServiceScope
 }
 
 // newMachineScope creates a new MachineScope from the supplied parameters.
@@ -72,6 +95,12 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		return nil, machineapierros.InvalidMachineConfiguration("failed to get machine provider status: %v", err.Error())
 	}
 
+	// The instance of a machine created by a regional bulk insert is in the zone recorded in the providerStatus
+	specZone := providerSpec.Zone
+	if providerStatus.Zone != "" {
+		providerSpec.Zone = providerStatus.Zone
+	}
+
 	serviceAccountJSON, err := util.GetCredentialsSecret(params.coreClient, params.machine.GetNamespace(), *providerSpec)
 	if err != nil {
 		return nil, err
@@ -95,6 +124,16 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		return nil, machineapierros.InvalidMachineConfiguration("error creating tag service: %v", err)
 	}
 
+	kmsService, err := params.kmsClientBuilder(params.Context, serviceAccountJSON)
+	if err != nil {
+		return nil, machineapierros.InvalidMachineConfiguration("error creating kms service: %v", err)
+	}
+
+	dnsService, err := params.dnsClientBuilder(params.Context, serviceAccountJSON)
+	if err != nil {
+		return nil, machineapierros.InvalidMachineConfiguration("error creating dns service: %v", err)
+	}
+
 	return &machineScope{
 		Context:    params.Context,
 		coreClient: params.coreClient,
@@ -108,6 +147,7 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		machine:        params.machine.DeepCopy(),
 		providerSpec:   providerSpec,
 		providerStatus: providerStatus,
+		specZone:       specZone,
 		// Once set, they can not be changed. Otherwise, status change computation
 		// might be invalid and result in skipping the status update.
 		origMachine:        params.machine.DeepCopy(),
@@ -115,6 +155,11 @@ func newMachineScope(params machineScopeParams) (*machineScope, error) {
 		machineToBePatched: controllerclient.MergeFrom(params.machine.DeepCopy()),
 		featureGates:       params.featureGates,
 		tagService:         tagService,
+		kmsService:         kmsService,
+		dnsService:         dnsService,
+		eventRecorder:      params.eventRecorder,
+
+		controlPlaneBackendServiceScope: params.controlPlaneBackendServiceScope,
 	}, nil
 }
 
@@ -148,7 +193,13 @@ func (s *machineScope) Close() error {
 }
 
 func (s *machineScope) setMachineSpec() error {
-	ext, err := util.RawExtensionFromProviderSpec(s.providerSpec)
+	providerSpec := s.providerSpec
+	if s.providerStatus.Zone != "" {
+		// Keep the zone of the provider spec, the zone of the instance is stored in the providerStatus
+		providerSpec = providerSpec.DeepCopy()
+		providerSpec.Zone = s.specZone
+	}
+	ext, err := util.RawExtensionFromProviderSpec(providerSpec)
 	if err != nil {
 		return err
 	}
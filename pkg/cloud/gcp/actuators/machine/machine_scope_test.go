@@ -12,6 +12,8 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	dnsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/dns"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
 	corev1 "k8s.io/api/core/v1"
@@ -71,10 +73,14 @@ func TestNewMachineScope(t *testing.T) {
 	})
 	g.Expect(err).ToNot(HaveOccurred())
 
+	instanceZoneStatus, err := util.RawExtensionFromProviderStatus(&machinev1.GCPMachineProviderStatus{Zone: "zone2"})
+	g.Expect(err).ToNot(HaveOccurred())
+
 	cases := []struct {
-		name          string
-		params        machineScopeParams
-		expectedError error
+		name               string
+		params             machineScopeParams
+		expectedProviderID string
+		expectedError      error
 	}{
 		{
 			name: "successfully create machine scope",
@@ -96,6 +102,31 @@ func TestNewMachineScope(t *testing.T) {
 					}},
 			},
 		},
+		{
+			name: "successfully create machine scope in the zone of the instance",
+			params: machineScopeParams{
+				coreClient:           fakeClient,
+				computeClientBuilder: computeservice.MockBuilderFuncType,
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: defaultNamespaceName,
+						Labels: map[string]string{
+							machinev1.MachineClusterIDLabel: "CLUSTERID",
+						},
+					},
+					Spec: machinev1.MachineSpec{
+						ProviderSpec: machinev1.ProviderSpec{
+							Value: validProviderSpec,
+						},
+					},
+					Status: machinev1.MachineStatus{
+						ProviderStatus: instanceZoneStatus,
+					},
+				},
+			},
+			expectedProviderID: "gce://test/zone2/test",
+		},
 		{
 			name: "fail to get provider spec",
 			params: machineScopeParams{
@@ -217,6 +248,8 @@ func TestNewMachineScope(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			gs := NewWithT(t)
 			tc.params.tagsClientBuilder = tagservice.NewMockTagServiceBuilder
+			tc.params.kmsClientBuilder = kmsservice.NewMockKMSServiceBuilder
+			tc.params.dnsClientBuilder = dnsservice.NewFakeDNSServiceBuilder(dnsservice.NewFakeDNSService())
 
 			gate, err := NewDefaultMutableFeatureGate(nil)
 			gs.Expect(err).To(Not(HaveOccurred()))
@@ -230,7 +263,11 @@ func TestNewMachineScope(t *testing.T) {
 			} else {
 				gs.Expect(err).ToNot(HaveOccurred())
 				gs.Expect(scope.Context).To(Equal(context.Background()))
-				gs.Expect(scope.providerID).To(Equal("gce://test//test"))
+				expectedProviderID := "gce://test//test"
+				if tc.expectedProviderID != "" {
+					expectedProviderID = tc.expectedProviderID
+				}
+				gs.Expect(scope.providerID).To(Equal(expectedProviderID))
 			}
 		})
 	}
@@ -425,6 +462,8 @@ func TestPatchMachine(t *testing.T) {
 				Context:              ctx,
 				computeClientBuilder: computeservice.MockBuilderFuncType,
 				tagsClientBuilder:    tagservice.NewMockTagServiceBuilder,
+				kmsClientBuilder:     kmsservice.NewMockKMSServiceBuilder,
+				dnsClientBuilder:     dnsservice.NewFakeDNSServiceBuilder(dnsservice.NewFakeDNSService()),
 				featureGates:         gate,
 			})
 
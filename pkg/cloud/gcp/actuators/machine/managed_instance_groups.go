@@ -0,0 +1,467 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/klog/v2"
+)
+
+const (
+	managedInstanceGroupNameSuffix = "-mig"
+
+	// managedInstanceGroupUpdateOpportunistic applies a new instance template to the new instances only,
+	// rather than replacing the instances of the machines.
+	managedInstanceGroupUpdateOpportunistic = "OPPORTUNISTIC"
+	// managedInstanceGroupRedistributionNone keeps the instances of a regional group in their zone. Proactive
+	// redistribution deletes instances to rebalance the zones and recreates them in other zones, which would
+	// break the one to one mapping of the machines to their instances, whose zone is in their provider status.
+	managedInstanceGroupRedistributionNone = "NONE"
+
+	managedInstanceActionDeleting = "DELETING"
+)
+
+func validateManagedInstanceGroup(machine machinev1.Machine, providerSpec machinev1.GCPMachineProviderSpec) error {
+	group := providerSpec.ManagedInstanceGroup
+	if group == nil {
+		return nil
+	}
+
+	if providerSpec.SourceInstanceTemplate == "" {
+		return fmt.Errorf("managed instance group requires a source instance template")
+	}
+	if providerSpec.BulkInsert != nil {
+		return fmt.Errorf("managed instance group cannot be used together with bulk insert")
+	}
+	switch group.Type {
+	case "", machinev1.ManagedInstanceGroupZonal:
+		if len(group.Zones) > 0 {
+			return fmt.Errorf("managed instance group zones can only be set with the %q type", machinev1.ManagedInstanceGroupRegional)
+		}
+	case machinev1.ManagedInstanceGroupRegional:
+	default:
+		return fmt.Errorf("unknown managed instance group type %q, valid values are %q and %q", group.Type, machinev1.ManagedInstanceGroupZonal, machinev1.ManagedInstanceGroupRegional)
+	}
+	if _, err := managedInstanceGroupName(&machine, group); err != nil {
+		return err
+	}
+
+	// The instances of the group are deleted by the group, and their addresses are the ones of the template
+	if providerSpec.DeletionProtection {
+		return fmt.Errorf("managed instance group does not support deletion protection")
+	}
+	for _, nic := range providerSpec.NetworkInterfaces {
+		if nic.InternalAddress != nil || nic.ExternalAddress != nil {
+			return fmt.Errorf("managed instance group does not support static addresses")
+		}
+	}
+	return nil
+}
+
+// managedInstanceGroupName returns the name of the managed instance group of the machine, which is either
+// the existing group it references or the group created for its machine set.
+func managedInstanceGroupName(machine *machinev1.Machine, group *machinev1.GCPManagedInstanceGroup) (string, error) {
+	if group.Name != "" {
+		return group.Name, nil
+	}
+
+	machineSet := machineSetName(machine)
+	if machineSet == "" {
+		return "", fmt.Errorf("managed instance group name is required for machines that are not part of a machine set")
+	}
+	name := machineSet + managedInstanceGroupNameSuffix
+	if !resourceNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("managed instance group name %q derived from the machine set is not a valid resource name, set the managed instance group name", name)
+	}
+	return name, nil
+}
+
+// isRegionalManagedInstanceGroup tells whether the managed instance group of the machine is regional.
+func (r *Reconciler) isRegionalManagedInstanceGroup() bool {
+	return r.providerSpec.ManagedInstanceGroup.Type == machinev1.ManagedInstanceGroupRegional
+}
+
+// managedInstanceGroupLocation returns the zone or the region of the managed instance group of the machine.
+func (r *Reconciler) managedInstanceGroupLocation() string {
+	if r.isRegionalManagedInstanceGroup() {
+		return r.providerSpec.Region
+	}
+	return r.providerSpec.Zone
+}
+
+// awaitManagedInstanceGroupOperation returns once an operation on the managed instance group of the machine is done.
+func (r *Reconciler) awaitManagedInstanceGroupOperation(op *compute.Operation) error {
+	if r.isRegionalManagedInstanceGroup() {
+		return r.awaitRegionOperation(op)
+	}
+	return r.awaitZoneOperation(op)
+}
+
+// ensureManagedInstanceGroup returns the name of the managed instance group of the instance, creating the
+// group of the machine set from the source instance template when it does not exist yet.
+func (r *Reconciler) ensureManagedInstanceGroup(sourceInstanceTemplate string) (string, error) {
+	group := r.providerSpec.ManagedInstanceGroup
+	name, err := managedInstanceGroupName(r.machine, group)
+	if err != nil {
+		return "", machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+
+	var existing *compute.InstanceGroupManager
+	if r.isRegionalManagedInstanceGroup() {
+		existing, err = r.computeService.RegionInstanceGroupManagersGet(r.projectID, r.providerSpec.Region, name)
+	} else {
+		existing, err = r.computeService.InstanceGroupManagersGet(r.projectID, r.providerSpec.Zone, name)
+	}
+	if err == nil {
+		if err := r.reconcileManagedInstanceGroupTemplate(existing, sourceInstanceTemplate); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+	if !isNotFoundError(err) {
+		return "", fmt.Errorf("failed to get managed instance group %s: %w", name, err)
+	}
+	if group.Name != "" {
+		return "", machinecontroller.InvalidMachineConfiguration("managed instance group %s not found in %s", name, r.managedInstanceGroupLocation())
+	}
+
+	machineSet := machineSetName(r.machine)
+	instanceGroupManager := &compute.InstanceGroupManager{
+		Name:             name,
+		Description:      fmt.Sprintf("Managed instance group of machine set %s", machineSet),
+		BaseInstanceName: machineSet,
+		InstanceTemplate: sourceInstanceTemplate,
+		// The instances are only created and deleted with the machines
+		TargetSize:      0,
+		ForceSendFields: []string{"TargetSize"},
+		UpdatePolicy: &compute.InstanceGroupManagerUpdatePolicy{
+			Type: managedInstanceGroupUpdateOpportunistic,
+		},
+	}
+
+	klog.Infof("%s: creating managed instance group %s", r.machine.Name, name)
+	var op *compute.Operation
+	if r.isRegionalManagedInstanceGroup() {
+		instanceGroupManager.UpdatePolicy.InstanceRedistributionType = managedInstanceGroupRedistributionNone
+		if len(group.Zones) > 0 {
+			instanceGroupManager.DistributionPolicy = &compute.DistributionPolicy{}
+			for _, zone := range group.Zones {
+				instanceGroupManager.DistributionPolicy.Zones = append(instanceGroupManager.DistributionPolicy.Zones, &compute.DistributionPolicyZoneConfiguration{
+					Zone: fmt.Sprintf("zones/%s", zone),
+				})
+			}
+		}
+		op, err = r.computeService.RegionInstanceGroupManagersInsert(r.projectID, r.providerSpec.Region, instanceGroupManager)
+	} else {
+		op, err = r.computeService.InstanceGroupManagersInsert(r.projectID, r.providerSpec.Zone, instanceGroupManager)
+	}
+	// Another machine of the machine set may have created the group in the meantime
+	if err != nil && !isAlreadyExistsError(err) {
+		return "", fmt.Errorf("failed to create managed instance group %s: %w", name, err)
+	}
+	if err := r.awaitManagedInstanceGroupOperation(op); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// reconcileManagedInstanceGroupTemplate makes the managed instance group of the machine set create its new
+// instances from the source instance template of the machine, which changes with the machine set. The instances
+// of the other machines are kept, as the group only applies the template to new instances. A group referenced
+// by name is not changed, its instance template must be the one of the machine.
+func (r *Reconciler) reconcileManagedInstanceGroupTemplate(instanceGroupManager *compute.InstanceGroupManager, sourceInstanceTemplate string) error {
+	if instanceTemplatePath(instanceGroupManager.InstanceTemplate) == instanceTemplatePath(sourceInstanceTemplate) {
+		return nil
+	}
+	name := instanceGroupManager.Name
+	if r.providerSpec.ManagedInstanceGroup.Name != "" {
+		return machinecontroller.InvalidMachineConfiguration("managed instance group %s creates its instances from instance template %s, not from the source instance template %s of the machine", name, instanceTemplatePath(instanceGroupManager.InstanceTemplate), sourceInstanceTemplate)
+	}
+
+	klog.Infof("%s: setting the instance template of managed instance group %s to %s", r.machine.Name, name, sourceInstanceTemplate)
+	var op *compute.Operation
+	var err error
+	if r.isRegionalManagedInstanceGroup() {
+		op, err = r.computeService.RegionInstanceGroupManagersSetInstanceTemplate(r.projectID, r.providerSpec.Region, name, sourceInstanceTemplate)
+	} else {
+		op, err = r.computeService.InstanceGroupManagersSetInstanceTemplate(r.projectID, r.providerSpec.Zone, name, sourceInstanceTemplate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set the instance template of managed instance group %s: %w", name, err)
+	}
+	return r.awaitManagedInstanceGroupOperation(op)
+}
+
+// instanceTemplatePath returns the path of the instance template referenced by URL, starting with its project.
+func instanceTemplatePath(url string) string {
+	if i := strings.Index(url, "projects/"); i >= 0 {
+		return url[i:]
+	}
+	return url
+}
+
+// managedInstance returns the instance of the machine in the managed instance group, nil when the group
+// does not manage it or does not exist.
+func (r *Reconciler) managedInstance(groupName string) (*compute.ManagedInstance, error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var instances []*compute.ManagedInstance
+	var err error
+	if r.isRegionalManagedInstanceGroup() {
+		instances, err = r.computeService.RegionInstanceGroupManagersListManagedInstances(ctx, r.projectID, r.providerSpec.Region, groupName)
+	} else {
+		instances, err = r.computeService.InstanceGroupManagersListManagedInstances(ctx, r.projectID, r.providerSpec.Zone, groupName)
+	}
+	if isNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the instances of managed instance group %s: %w", groupName, err)
+	}
+	for _, instance := range instances {
+		if instance.Name == r.machine.Name || (instance.Instance != "" && resourceName(instance.Instance) == r.machine.Name) {
+			return instance, nil
+		}
+	}
+	return nil, nil
+}
+
+// createManagedInstance creates the instance of the machine in the managed instance group of the machine set.
+// The group creates the instance asynchronously, the machine is requeued until the instance exists and is
+// then moved to the zone the group created it in. The instance is created from the template of the group,
+// with the metadata of the machine, such as its user data, and is given the labels of the machine once created.
+func (r *Reconciler) createManagedInstance(instance *compute.Instance, sourceInstanceTemplate string) error {
+	groupName, err := r.ensureManagedInstanceGroup(sourceInstanceTemplate)
+	if err != nil {
+		return err
+	}
+
+	managed, err := r.managedInstance(groupName)
+	if err != nil {
+		return err
+	}
+	if managed == nil {
+		klog.Infof("%s: creating instance in managed instance group %s", r.machine.Name, groupName)
+		instances := []*compute.PerInstanceConfig{{
+			Name: r.machine.Name,
+			PreservedState: &compute.PreservedState{
+				Metadata: metadataMap(instance.Metadata),
+			},
+		}}
+		var op *compute.Operation
+		if r.isRegionalManagedInstanceGroup() {
+			op, err = r.computeService.RegionInstanceGroupManagersCreateInstances(r.projectID, r.providerSpec.Region, groupName, instances)
+		} else {
+			op, err = r.computeService.InstanceGroupManagersCreateInstances(r.projectID, r.providerSpec.Zone, groupName, instances)
+		}
+		if err == nil {
+			err = r.awaitManagedInstanceGroupOperation(op)
+		}
+		if isRequeueAfterError(err) {
+			return err
+		}
+		if err != nil {
+			r.reportCreationFailure(err)
+			if googleError, ok := err.(*googleapi.Error); ok && googleError.Code >= 400 && googleError.Code < 500 {
+				return machinecontroller.InvalidMachineConfiguration("error launching instance in managed instance group %s: %v", groupName, googleError.Error())
+			}
+			return fmt.Errorf("failed to create instance in managed instance group %s: %w", groupName, err)
+		}
+
+		managed, err = r.managedInstance(groupName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if managed == nil || managed.Instance == "" || managed.InstanceStatus == "" {
+		if lastErrors := managedInstanceErrors(managed); lastErrors != "" {
+			klog.Warningf("%s: managed instance group %s failed to create the instance, retrying: %s", r.machine.Name, groupName, lastErrors)
+		}
+		klog.Infof("%s: instance is being created by managed instance group %s, requeuing...", r.machine.Name, groupName)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+
+	r.setInstanceZone(&compute.Instance{Zone: managedInstanceZone(managed.Instance)})
+	if err := r.setManagedInstanceLabels(instance.Labels); err != nil {
+		return err
+	}
+	return r.reconcileMachineWithCloudState(nil)
+}
+
+// metadataMap returns the metadata items by key.
+func metadataMap(metadata *compute.Metadata) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	items := map[string]string{}
+	for _, item := range metadata.Items {
+		if item.Value != nil {
+			items[item.Key] = *item.Value
+		}
+	}
+	return items
+}
+
+// setManagedInstanceLabels adds the labels of the machine to the instance created by the managed instance group,
+// which only gives it the labels of its template.
+func (r *Reconciler) setManagedInstanceLabels(labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	instance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.machine.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get instance via compute service: %w", err)
+	}
+
+	merged := map[string]string{}
+	for key, value := range instance.Labels {
+		merged[key] = value
+	}
+	changed := false
+	for key, value := range labels {
+		if current, ok := merged[key]; !ok || current != value {
+			merged[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	op, err := r.computeService.InstancesSetLabels(r.projectID, r.providerSpec.Zone, r.machine.Name, merged, instance.LabelFingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to set the labels of the instance: %w", err)
+	}
+	return r.awaitZoneOperation(op)
+}
+
+// managedInstanceZone returns the zone of the instance referenced by URL.
+func managedInstanceZone(url string) string {
+	parts := strings.Split(url, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "zones" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// managedInstanceErrors summarizes the errors of the last attempt of the managed instance group to create the instance.
+func managedInstanceErrors(managed *compute.ManagedInstance) string {
+	if managed == nil || managed.LastAttempt == nil || managed.LastAttempt.Errors == nil {
+		return ""
+	}
+	messages := []string{}
+	for _, err := range managed.LastAttempt.Errors.Errors {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+// deleteManagedInstance deletes the instance of the machine through its managed instance group, which
+// reduces the target size of the group so that it does not recreate the instance. It returns whether the
+// group still manages the instance, the machine is requeued until it does not anymore.
+func (r *Reconciler) deleteManagedInstance() (bool, error) {
+	groupName, err := managedInstanceGroupName(r.machine, r.providerSpec.ManagedInstanceGroup)
+	if err != nil {
+		// The group could not have been created
+		return false, nil
+	}
+
+	managed, err := r.managedInstance(groupName)
+	if err != nil {
+		return false, err
+	}
+	if managed == nil {
+		return false, nil
+	}
+	if managed.CurrentAction == managedInstanceActionDeleting {
+		return true, nil
+	}
+
+	// The instance of a regional group is in the zone the group created it in
+	zone := managedInstanceZone(managed.Instance)
+	if zone == "" {
+		zone = r.providerSpec.Zone
+	}
+	instances := []string{fmt.Sprintf("zones/%s/instances/%s", zone, r.machine.Name)}
+	klog.Infof("%s: deleting instance of managed instance group %s", r.machine.Name, groupName)
+	var op *compute.Operation
+	if r.isRegionalManagedInstanceGroup() {
+		op, err = r.computeService.RegionInstanceGroupManagersDeleteInstances(r.projectID, r.providerSpec.Region, groupName, instances)
+	} else {
+		op, err = r.computeService.InstanceGroupManagersDeleteInstances(r.projectID, r.providerSpec.Zone, groupName, instances)
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to delete instance of managed instance group %s: %w", groupName, err)
+	}
+	return true, r.awaitManagedInstanceGroupOperation(op)
+}
+
+// releaseManagedInstanceGroup deletes the managed instance group created for the machine set once the
+// instance is deleted, unless other machines of the machine set use it or it still has instances.
+func (r *Reconciler) releaseManagedInstanceGroup() error {
+	group := r.providerSpec.ManagedInstanceGroup
+	if group == nil || group.Name != "" {
+		return nil
+	}
+	name, err := managedInstanceGroupName(r.machine, group)
+	if err != nil {
+		// The group could not have been created
+		return nil
+	}
+
+	inUse, err := r.isUsedByMachineSet(func(providerSpec *machinev1.GCPMachineProviderSpec) bool {
+		return providerSpec.ManagedInstanceGroup != nil && providerSpec.ManagedInstanceGroup.Name == ""
+	})
+	if err != nil {
+		return err
+	}
+	if inUse {
+		klog.Infof("%s: managed instance group %s is used by other machines of the machine set, keeping it", r.machine.Name, name)
+		return nil
+	}
+
+	var instanceGroupManager *compute.InstanceGroupManager
+	if r.isRegionalManagedInstanceGroup() {
+		instanceGroupManager, err = r.computeService.RegionInstanceGroupManagersGet(r.projectID, r.providerSpec.Region, name)
+	} else {
+		instanceGroupManager, err = r.computeService.InstanceGroupManagersGet(r.projectID, r.providerSpec.Zone, name)
+	}
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get managed instance group %s: %w", name, err)
+	}
+	if instanceGroupManager.TargetSize > 0 {
+		// Deleting the group would delete the instances still in it
+		klog.Infof("%s: managed instance group %s still has %d instances, keeping it", r.machine.Name, name, instanceGroupManager.TargetSize)
+		return nil
+	}
+
+	klog.Infof("%s: deleting managed instance group %s", r.machine.Name, name)
+	var op *compute.Operation
+	if r.isRegionalManagedInstanceGroup() {
+		op, err = r.computeService.RegionInstanceGroupManagersDelete(r.projectID, r.providerSpec.Region, name)
+	} else {
+		op, err = r.computeService.InstanceGroupManagersDelete(r.projectID, r.providerSpec.Zone, name)
+	}
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete managed instance group %s: %w", name, err)
+	}
+	return r.awaitManagedInstanceGroupOperation(op)
+}
@@ -0,0 +1,569 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/client-go/kubernetes/scheme"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateManagedInstanceGroup(t *testing.T) {
+	cases := []struct {
+		name          string
+		machineSet    string
+		providerSpec  machinev1.GCPMachineProviderSpec
+		expectedError string
+	}{
+		{
+			name:       "Zonal managed instance group of the machine set",
+			machineSet: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{},
+			},
+		},
+		{
+			name: "Existing regional managed instance group in some zones",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup: &machinev1.GCPManagedInstanceGroup{
+					Name:  "workers",
+					Type:  machinev1.ManagedInstanceGroupRegional,
+					Zones: []string{"zone1", "zone2"},
+				},
+			},
+		},
+		{
+			name:       "Managed instance group without source instance template",
+			machineSet: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				ManagedInstanceGroup: &machinev1.GCPManagedInstanceGroup{},
+			},
+			expectedError: "managed instance group requires a source instance template",
+		},
+		{
+			name:       "Managed instance group and bulk insert",
+			machineSet: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{},
+				BulkInsert:             &machinev1.GCPBulkInsert{},
+			},
+			expectedError: "managed instance group cannot be used together with bulk insert",
+		},
+		{
+			name:       "Zones of a zonal managed instance group",
+			machineSet: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{Zones: []string{"zone1"}},
+			},
+			expectedError: "managed instance group zones can only be set with the \"Regional\" type",
+		},
+		{
+			name:       "Unknown managed instance group type",
+			machineSet: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{Type: "Global"},
+			},
+			expectedError: "unknown managed instance group type \"Global\", valid values are \"Zonal\" and \"Regional\"",
+		},
+		{
+			name: "Machine without machine set",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{},
+			},
+			expectedError: "managed instance group name is required for machines that are not part of a machine set",
+		},
+		{
+			name:       "Deletion protection",
+			machineSet: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{},
+				DeletionProtection:     true,
+			},
+			expectedError: "managed instance group does not support deletion protection",
+		},
+		{
+			name:       "Static addresses",
+			machineSet: "workers",
+			providerSpec: machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{},
+				NetworkInterfaces:      []*machinev1.GCPNetworkInterface{{InternalAddress: &machinev1.GCPStaticAddress{}}},
+			},
+			expectedError: "managed instance group does not support static addresses",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			machine := newBulkInsertMachine(t, "workers-0", tc.machineSet, &tc.providerSpec)
+			err := validateManagedInstanceGroup(*machine, tc.providerSpec)
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateManagedInstance(t *testing.T) {
+	cases := []struct {
+		name              string
+		groupType         machinev1.GCPManagedInstanceGroupType
+		existingGroup     bool
+		groupName         string
+		groupTemplate     string
+		existingInstance  *compute.ManagedInstance
+		createdInstance   *compute.ManagedInstance
+		createErr         error
+		expectGroup       bool
+		expectCreate      bool
+		expectTemplateSet bool
+		expectLabels      bool
+		expectedZone      string
+		expectRequeue     bool
+		expectInvalidSpec bool
+	}{
+		{
+			name:            "Create the managed instance group and the instance",
+			createdInstance: &compute.ManagedInstance{Name: "workers-0", Instance: "projects/test/zones/zone1/instances/workers-0", InstanceStatus: "RUNNING"},
+			expectGroup:     true,
+			expectCreate:    true,
+			expectLabels:    true,
+			expectedZone:    "zone1",
+		},
+		{
+			name:            "Regional managed instance group moves the machine to the zone of its instance",
+			groupType:       machinev1.ManagedInstanceGroupRegional,
+			existingGroup:   true,
+			createdInstance: &compute.ManagedInstance{Name: "workers-0", Instance: "projects/test/zones/zone2/instances/workers-0", InstanceStatus: "RUNNING"},
+			expectCreate:    true,
+			expectLabels:    true,
+			expectedZone:    "zone2",
+		},
+		{
+			name:            "Instance being created by the managed instance group",
+			existingGroup:   true,
+			createdInstance: &compute.ManagedInstance{Name: "workers-0", CurrentAction: "CREATING"},
+			expectCreate:    true,
+			expectedZone:    "zone1",
+			expectRequeue:   true,
+		},
+		{
+			name:             "Instance already created by the managed instance group",
+			groupType:        machinev1.ManagedInstanceGroupRegional,
+			existingGroup:    true,
+			existingInstance: &compute.ManagedInstance{Name: "workers-0", Instance: "projects/test/zones/zone3/instances/workers-0", InstanceStatus: "RUNNING"},
+			expectLabels:     true,
+			expectedZone:     "zone3",
+		},
+		{
+			name:              "Instance template of the managed instance group of the machine set updated",
+			existingGroup:     true,
+			groupTemplate:     "https://www.googleapis.com/compute/v1/projects/test/global/instanceTemplates/workers-v1",
+			createdInstance:   &compute.ManagedInstance{Name: "workers-0", Instance: "projects/test/zones/zone1/instances/workers-0", InstanceStatus: "RUNNING"},
+			expectCreate:      true,
+			expectTemplateSet: true,
+			expectLabels:      true,
+			expectedZone:      "zone1",
+		},
+		{
+			name:              "Instance template of an existing managed instance group differs",
+			existingGroup:     true,
+			groupName:         "workers-mig",
+			groupTemplate:     "https://www.googleapis.com/compute/v1/projects/test/global/instanceTemplates/workers-v1",
+			expectedZone:      "zone1",
+			expectInvalidSpec: true,
+		},
+		{
+			name:              "Failure to create the instance",
+			existingGroup:     true,
+			createErr:         &googleapi.Error{Code: http.StatusBadRequest},
+			expectCreate:      true,
+			expectedZone:      "zone1",
+			expectInvalidSpec: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &machinev1.GCPMachineProviderSpec{
+				Region:                 "region1",
+				Zone:                   "zone1",
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{Type: tc.groupType, Name: tc.groupName},
+			}
+			machine := newBulkInsertMachine(t, "workers-0", "workers", spec)
+			groupTemplate := tc.groupTemplate
+			if groupTemplate == "" {
+				groupTemplate = "https://www.googleapis.com/compute/v1/projects/test/global/instanceTemplates/workers"
+			}
+
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			groupCreated := false
+			getGroup := func(name string) (*compute.InstanceGroupManager, error) {
+				if name != "workers-mig" {
+					t.Errorf("Unexpected managed instance group %s", name)
+				}
+				if !tc.existingGroup {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+				return &compute.InstanceGroupManager{Name: name, InstanceTemplate: groupTemplate}, nil
+			}
+			insertGroup := func(group *compute.InstanceGroupManager) (*compute.Operation, error) {
+				groupCreated = true
+				if group.InstanceTemplate != "projects/test/global/instanceTemplates/workers" || group.TargetSize != 0 {
+					t.Errorf("Expected an empty group of template workers, got: %s of %d instances", group.InstanceTemplate, group.TargetSize)
+				}
+				if group.UpdatePolicy == nil || group.UpdatePolicy.Type != "OPPORTUNISTIC" {
+					t.Errorf("Expected the instances of the group not to be replaced on template changes, got: %v", group.UpdatePolicy)
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			templateSet := false
+			setTemplate := func(name string, template string) (*compute.Operation, error) {
+				templateSet = true
+				if name != "workers-mig" || template != "projects/test/global/instanceTemplates/workers" {
+					t.Errorf("Expected template workers to be set on group workers-mig, got: %s on %s", template, name)
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			instances := []*compute.ManagedInstance{}
+			if tc.existingInstance != nil {
+				instances = append(instances, tc.existingInstance)
+			}
+			listInstances := func() ([]*compute.ManagedInstance, error) {
+				return instances, nil
+			}
+			created := false
+			createInstances := func(configs []*compute.PerInstanceConfig) (*compute.Operation, error) {
+				created = true
+				if len(configs) != 1 || configs[0].Name != "workers-0" {
+					t.Errorf("Expected instance workers-0 to be created, got: %v", configs)
+				}
+				if configs[0].PreservedState == nil || !reflect.DeepEqual(configs[0].PreservedState.Metadata, map[string]string{"user-data": "ignition"}) {
+					t.Errorf("Expected instance workers-0 to be created with the metadata of the machine, got: %v", configs[0].PreservedState)
+				}
+				if tc.createErr != nil {
+					return nil, tc.createErr
+				}
+				if tc.createdInstance != nil {
+					instances = append(instances, tc.createdInstance)
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			if tc.groupType == machinev1.ManagedInstanceGroupRegional {
+				mockComputeService.MockRegionInstanceGroupManagersGet = func(_ string, _ string, name string) (*compute.InstanceGroupManager, error) {
+					return getGroup(name)
+				}
+				mockComputeService.MockRegionInstanceGroupManagersInsert = func(_ string, _ string, group *compute.InstanceGroupManager) (*compute.Operation, error) {
+					return insertGroup(group)
+				}
+				mockComputeService.MockRegionInstanceGroupManagersListManagedInstances = func(_ context.Context, _ string, _ string, _ string) ([]*compute.ManagedInstance, error) {
+					return listInstances()
+				}
+				mockComputeService.MockRegionInstanceGroupManagersCreateInstances = func(_ string, _ string, _ string, configs []*compute.PerInstanceConfig) (*compute.Operation, error) {
+					return createInstances(configs)
+				}
+				mockComputeService.MockRegionInstanceGroupManagersSetInstanceTemplate = func(_ string, _ string, name string, template string) (*compute.Operation, error) {
+					return setTemplate(name, template)
+				}
+			} else {
+				mockComputeService.MockInstanceGroupManagersGet = func(_ string, _ string, name string) (*compute.InstanceGroupManager, error) {
+					return getGroup(name)
+				}
+				mockComputeService.MockInstanceGroupManagersInsert = func(_ string, _ string, group *compute.InstanceGroupManager) (*compute.Operation, error) {
+					return insertGroup(group)
+				}
+				mockComputeService.MockInstanceGroupManagersListManagedInstances = func(_ context.Context, _ string, _ string, _ string) ([]*compute.ManagedInstance, error) {
+					return listInstances()
+				}
+				mockComputeService.MockInstanceGroupManagersCreateInstances = func(_ string, _ string, _ string, configs []*compute.PerInstanceConfig) (*compute.Operation, error) {
+					return createInstances(configs)
+				}
+				mockComputeService.MockInstanceGroupManagersSetInstanceTemplate = func(_ string, _ string, name string, template string) (*compute.Operation, error) {
+					return setTemplate(name, template)
+				}
+			}
+			mockComputeService.MockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+				return &compute.Instance{
+					Name:              instance,
+					Zone:              zone,
+					Status:            "RUNNING",
+					NetworkInterfaces: []*compute.NetworkInterface{{NetworkIP: "10.0.0.2"}},
+					Labels:            map[string]string{"template": "workers"},
+					LabelFingerprint:  "fingerprint",
+				}, nil
+			}
+			var labels map[string]string
+			mockComputeService.MockInstancesSetLabels = func(project string, zone string, instance string, instanceLabels map[string]string, labelFingerprint string) (*compute.Operation, error) {
+				if zone != tc.expectedZone || labelFingerprint != "fingerprint" {
+					t.Errorf("Expected the labels of the instance in zone %s to be set with its fingerprint, got: zone %s, fingerprint %s", tc.expectedZone, zone, labelFingerprint)
+				}
+				labels = instanceLabels
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine:        machine,
+				coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(machine).Build(),
+				providerSpec:   spec,
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				specZone:       spec.Zone,
+				projectID:      "test",
+				providerID:     "gce://test/zone1/workers-0",
+				computeService: mockComputeService,
+			})
+
+			userData := "ignition"
+			err := r.createManagedInstance(&compute.Instance{
+				Name:     "workers-0",
+				Labels:   map[string]string{"machine": "workers-0"},
+				Metadata: &compute.Metadata{Items: []*compute.MetadataItems{{Key: "user-data", Value: &userData}}},
+			}, "projects/test/global/instanceTemplates/workers")
+			_, isRequeue := err.(*machinecontroller.RequeueAfterError)
+			switch {
+			case tc.expectRequeue:
+				if !isRequeue {
+					t.Errorf("Expected the machine to be requeued, got: %v", err)
+				}
+			case tc.expectInvalidSpec:
+				if !isInvalidMachineConfigurationError(err) {
+					t.Errorf("Expected invalid machine configuration, got: %v", err)
+				}
+			case err != nil:
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if groupCreated != tc.expectGroup {
+				t.Errorf("Expected managed instance group to be created: %v, got: %v", tc.expectGroup, groupCreated)
+			}
+			if created != tc.expectCreate {
+				t.Errorf("Expected instance to be created: %v, got: %v", tc.expectCreate, created)
+			}
+			if templateSet != tc.expectTemplateSet {
+				t.Errorf("Expected instance template to be set: %v, got: %v", tc.expectTemplateSet, templateSet)
+			}
+			var expectedLabels map[string]string
+			if tc.expectLabels {
+				expectedLabels = map[string]string{"template": "workers", "machine": "workers-0"}
+			}
+			if !reflect.DeepEqual(labels, expectedLabels) {
+				t.Errorf("Expected instance labels %v, got: %v", expectedLabels, labels)
+			}
+			if r.providerSpec.Zone != tc.expectedZone {
+				t.Errorf("Expected machine in zone %s, got: %s", tc.expectedZone, r.providerSpec.Zone)
+			}
+			if expectedProviderID := "gce://test/" + tc.expectedZone + "/workers-0"; r.providerID != expectedProviderID {
+				t.Errorf("Expected provider ID %s, got: %s", expectedProviderID, r.providerID)
+			}
+		})
+	}
+}
+
+func TestDeleteManagedInstance(t *testing.T) {
+	cases := []struct {
+		name            string
+		groupType       machinev1.GCPManagedInstanceGroupType
+		instances       []*compute.ManagedInstance
+		listErr         error
+		expectedDeleted []string
+		expectManaged   bool
+	}{
+		{
+			name:            "Instance of a zonal managed instance group",
+			groupType:       machinev1.ManagedInstanceGroupZonal,
+			instances:       []*compute.ManagedInstance{{Name: "workers-0", Instance: "projects/test/zones/zone2/instances/workers-0"}},
+			expectedDeleted: []string{"zones/zone2/instances/workers-0"},
+			expectManaged:   true,
+		},
+		{
+			name:            "Instance of a regional managed instance group in another zone",
+			groupType:       machinev1.ManagedInstanceGroupRegional,
+			instances:       []*compute.ManagedInstance{{Name: "workers-0", Instance: "projects/test/zones/zone3/instances/workers-0"}},
+			expectedDeleted: []string{"zones/zone3/instances/workers-0"},
+			expectManaged:   true,
+		},
+		{
+			name:            "Instance being created by the managed instance group",
+			groupType:       machinev1.ManagedInstanceGroupRegional,
+			instances:       []*compute.ManagedInstance{{Name: "workers-0", CurrentAction: "CREATING"}},
+			expectedDeleted: []string{"zones/zone2/instances/workers-0"},
+			expectManaged:   true,
+		},
+		{
+			name:          "Instance being deleted by the managed instance group",
+			groupType:     machinev1.ManagedInstanceGroupZonal,
+			instances:     []*compute.ManagedInstance{{Name: "workers-0", Instance: "projects/test/zones/zone2/instances/workers-0", CurrentAction: "DELETING"}},
+			expectManaged: true,
+		},
+		{
+			name:      "Instance no longer managed by the managed instance group",
+			groupType: machinev1.ManagedInstanceGroupZonal,
+			instances: []*compute.ManagedInstance{{Name: "workers-1", Instance: "projects/test/zones/zone2/instances/workers-1"}},
+		},
+		{
+			name:      "Managed instance group deleted",
+			groupType: machinev1.ManagedInstanceGroupRegional,
+			listErr:   &googleapi.Error{Code: http.StatusNotFound},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &machinev1.GCPMachineProviderSpec{
+				Region:                 "region1",
+				Zone:                   "zone2",
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   &machinev1.GCPManagedInstanceGroup{Type: tc.groupType},
+			}
+
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			listInstances := func() ([]*compute.ManagedInstance, error) {
+				return tc.instances, tc.listErr
+			}
+			mockComputeService.MockInstanceGroupManagersListManagedInstances = func(_ context.Context, _ string, _ string, _ string) ([]*compute.ManagedInstance, error) {
+				return listInstances()
+			}
+			mockComputeService.MockRegionInstanceGroupManagersListManagedInstances = func(_ context.Context, _ string, _ string, _ string) ([]*compute.ManagedInstance, error) {
+				return listInstances()
+			}
+			var deleted []string
+			mockComputeService.MockInstanceGroupManagersDeleteInstances = func(_ string, zone string, name string, instances []string) (*compute.Operation, error) {
+				if tc.groupType != machinev1.ManagedInstanceGroupZonal || zone != "zone2" || name != "workers-mig" {
+					t.Errorf("Unexpected deletion from zonal managed instance group %s in zone %s", name, zone)
+				}
+				deleted = instances
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockRegionInstanceGroupManagersDeleteInstances = func(_ string, region string, name string, instances []string) (*compute.Operation, error) {
+				if tc.groupType != machinev1.ManagedInstanceGroupRegional || region != "region1" || name != "workers-mig" {
+					t.Errorf("Unexpected deletion from regional managed instance group %s in region %s", name, region)
+				}
+				deleted = instances
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine:        newBulkInsertMachine(t, "workers-0", "workers", spec),
+				providerSpec:   spec,
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			managed, err := r.deleteManagedInstance()
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if managed != tc.expectManaged {
+				t.Errorf("Expected the instance to be managed by the group: %v, got: %v", tc.expectManaged, managed)
+			}
+			assertStrings(t, "deleted instances", tc.expectedDeleted, deleted)
+		})
+	}
+}
+
+func TestReleaseManagedInstanceGroup(t *testing.T) {
+	createdGroup := &machinev1.GCPManagedInstanceGroup{}
+
+	cases := []struct {
+		name          string
+		group         *machinev1.GCPManagedInstanceGroup
+		otherMachines []controllerclient.Object
+		targetSize    int64
+		getErr        error
+		expectDelete  bool
+	}{
+		{
+			name: "No managed instance group",
+		},
+		{
+			name:  "Existing managed instance group is kept",
+			group: &machinev1.GCPManagedInstanceGroup{Name: "workers"},
+		},
+		{
+			name:         "Last machine of the machine set",
+			group:        createdGroup,
+			expectDelete: true,
+		},
+		{
+			name:  "Other machine of the machine set in the managed instance group",
+			group: createdGroup,
+			otherMachines: []controllerclient.Object{newBulkInsertMachine(t, "workers-1", "workers", &machinev1.GCPMachineProviderSpec{
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   createdGroup,
+			})},
+		},
+		{
+			name:       "Managed instance group with instances left",
+			group:      createdGroup,
+			targetSize: 2,
+		},
+		{
+			name:   "Managed instance group already deleted",
+			group:  createdGroup,
+			getErr: &googleapi.Error{Code: http.StatusNotFound},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &machinev1.GCPMachineProviderSpec{
+				Region:                 "region1",
+				Zone:                   "zone1",
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup:   tc.group,
+			}
+			machine := newBulkInsertMachine(t, "workers-0", "workers", spec)
+
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockInstanceGroupManagersGet = func(_ string, _ string, name string) (*compute.InstanceGroupManager, error) {
+				if tc.getErr != nil {
+					return nil, tc.getErr
+				}
+				return &compute.InstanceGroupManager{Name: name, TargetSize: tc.targetSize}, nil
+			}
+			deleted := false
+			mockComputeService.MockInstanceGroupManagersDelete = func(_ string, _ string, name string) (*compute.Operation, error) {
+				deleted = true
+				if name != "workers-mig" {
+					t.Errorf("Unexpected managed instance group %s deleted", name)
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine:        machine,
+				coreClient:     controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(tc.otherMachines, machine)...).Build(),
+				providerSpec:   spec,
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			if err := r.releaseManagedInstanceGroup(); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if deleted != tc.expectDelete {
+				t.Errorf("Expected managed instance group to be deleted: %v, got: %v", tc.expectDelete, deleted)
+			}
+		})
+	}
+}
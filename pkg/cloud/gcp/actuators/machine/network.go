@@ -0,0 +1,456 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	stackTypeIPv4Only = "IPV4_ONLY"
+	stackTypeIPv4IPv6 = "IPV4_IPV6"
+
+	ipv6AccessTypeInternal = "INTERNAL"
+	ipv6AccessTypeExternal = "EXTERNAL"
+
+	nicTypeGVNIC     = "GVNIC"
+	nicTypeVirtioNet = "VIRTIO_NET"
+
+	networkTierPremium  = "PREMIUM"
+	networkTierStandard = "STANDARD"
+
+	egressBandwidthTierDefault = "DEFAULT"
+	egressBandwidthTierTier1   = "TIER_1"
+
+	// directIPv6AccessConfigType is the only access config type supported for external IPv6 addresses.
+	directIPv6AccessConfigType   = "DIRECT_IPV6"
+	externalIPv6AccessConfigName = "external-ipv6"
+
+	subnetworksUsePermission = "compute.subnetworks.use"
+	// subnetworkReservedAddresses is the number of addresses GCP reserves in the primary range of every subnetwork.
+	subnetworkReservedAddresses = 4
+	// subnetworkLowCapacityPercent is the percentage of free addresses under which a subnetwork is reported as nearly exhausted.
+	subnetworkLowCapacityPercent = 10
+)
+
+// validateNetworkInterfaceStack makes sure the IP stack settings of a network interface are consistent.
+func validateNetworkInterfaceStack(nic *machinev1.GCPNetworkInterface) error {
+	switch nic.StackType {
+	case "", machinev1.NetworkStackTypeIPv4Only, machinev1.NetworkStackTypeIPv4IPv6:
+	default:
+		return fmt.Errorf("unsupported network interface stack type %q, valid values are %q and %q", nic.StackType,
+			machinev1.NetworkStackTypeIPv4Only, machinev1.NetworkStackTypeIPv4IPv6)
+	}
+
+	switch nic.IPv6AccessType {
+	case "":
+		return nil
+	case machinev1.IPv6AccessTypeInternal, machinev1.IPv6AccessTypeExternal:
+	default:
+		return fmt.Errorf("unsupported network interface IPv6 access type %q, valid values are %q and %q", nic.IPv6AccessType,
+			machinev1.IPv6AccessTypeInternal, machinev1.IPv6AccessTypeExternal)
+	}
+	if nic.StackType != machinev1.NetworkStackTypeIPv4IPv6 {
+		return fmt.Errorf("network interface IPv6 access type requires the %q stack type", machinev1.NetworkStackTypeIPv4IPv6)
+	}
+	return nil
+}
+
+// validateNetworkInterfaceOptions makes sure the NIC type and network tier of a network interface are supported.
+func validateNetworkInterfaceOptions(nic *machinev1.GCPNetworkInterface) error {
+	switch nic.NICType {
+	case "", machinev1.NICTypeGVNIC, machinev1.NICTypeVirtioNet:
+	default:
+		return fmt.Errorf("unsupported network interface NIC type %q, valid values are %q and %q", nic.NICType,
+			machinev1.NICTypeGVNIC, machinev1.NICTypeVirtioNet)
+	}
+
+	switch nic.NetworkTier {
+	case "", machinev1.NetworkTierPremium, machinev1.NetworkTierStandard:
+	default:
+		return fmt.Errorf("unsupported network interface network tier %q, valid values are %q and %q", nic.NetworkTier,
+			machinev1.NetworkTierPremium, machinev1.NetworkTierStandard)
+	}
+	return nil
+}
+
+// validateNetworkPerformanceConfig makes sure Tier_1 networking is only requested with gVNIC network interfaces.
+func validateNetworkPerformanceConfig(providerSpec machinev1.GCPMachineProviderSpec) error {
+	if providerSpec.NetworkPerformanceConfig == nil {
+		return nil
+	}
+	switch providerSpec.NetworkPerformanceConfig.TotalEgressBandwidthTier {
+	case "", machinev1.EgressBandwidthTierDefault:
+		return nil
+	case machinev1.EgressBandwidthTierTier1:
+	default:
+		return fmt.Errorf("unsupported total egress bandwidth tier %q, valid values are %q and %q", providerSpec.NetworkPerformanceConfig.TotalEgressBandwidthTier,
+			machinev1.EgressBandwidthTierDefault, machinev1.EgressBandwidthTierTier1)
+	}
+	for _, nic := range providerSpec.NetworkInterfaces {
+		if nic.NICType != machinev1.NICTypeGVNIC {
+			return fmt.Errorf("total egress bandwidth tier %q requires all network interfaces to use the %q NIC type", machinev1.EgressBandwidthTierTier1, machinev1.NICTypeGVNIC)
+		}
+	}
+	return nil
+}
+
+// networkTier returns the GCP network tier of the network interface, or an empty string for the project default.
+func networkTier(nic *machinev1.GCPNetworkInterface) string {
+	switch nic.NetworkTier {
+	case machinev1.NetworkTierPremium:
+		return networkTierPremium
+	case machinev1.NetworkTierStandard:
+		return networkTierStandard
+	}
+	return ""
+}
+
+// setNetworkPerformanceConfig sets the NIC types and egress bandwidth tier of the instance.
+// gVNIC network interfaces require the boot image to support the Google Virtual NIC.
+func (r *Reconciler) setNetworkPerformanceConfig(instance *compute.Instance, bootImage *compute.Image) error {
+	for i, nic := range r.providerSpec.NetworkInterfaces {
+		switch nic.NICType {
+		case machinev1.NICTypeGVNIC:
+			if bootImage != nil && !util.IsImageGVNICCompatible(bootImage) {
+				return machinecontroller.InvalidMachineConfiguration("image %q does not support the %q NIC type", bootImage.Name, machinev1.NICTypeGVNIC)
+			}
+			instance.NetworkInterfaces[i].NicType = nicTypeGVNIC
+		case machinev1.NICTypeVirtioNet:
+			instance.NetworkInterfaces[i].NicType = nicTypeVirtioNet
+		}
+	}
+
+	if r.providerSpec.NetworkPerformanceConfig == nil {
+		return nil
+	}
+	switch r.providerSpec.NetworkPerformanceConfig.TotalEgressBandwidthTier {
+	case machinev1.EgressBandwidthTierDefault:
+		instance.NetworkPerformanceConfig = &compute.NetworkPerformanceConfig{TotalEgressBandwidthTier: egressBandwidthTierDefault}
+	case machinev1.EgressBandwidthTierTier1:
+		instance.NetworkPerformanceConfig = &compute.NetworkPerformanceConfig{TotalEgressBandwidthTier: egressBandwidthTierTier1}
+	}
+	return nil
+}
+
+// setNetworkInterfaceStack sets the IP stack of the network interface, checking dual-stack
+// interfaces against the stack and IPv6 access types of their subnetwork. A nil subnetwork
+// for a network interface specifying one is a subnetwork which could not be read, such as a
+// Shared VPC subnetwork, the checks against it are left to the instance insert.
+func setNetworkInterfaceStack(nic *machinev1.GCPNetworkInterface, computeNIC *compute.NetworkInterface, subnetwork *compute.Subnetwork) error {
+	switch nic.StackType {
+	case "":
+		return nil
+	case machinev1.NetworkStackTypeIPv4Only:
+		computeNIC.StackType = stackTypeIPv4Only
+		return nil
+	}
+
+	if nic.Subnetwork == "" {
+		return machinecontroller.InvalidMachineConfiguration("network interface with %q stack type must specify a subnetwork", nic.StackType)
+	}
+	if subnetwork != nil && subnetwork.StackType != stackTypeIPv4IPv6 {
+		return machinecontroller.InvalidMachineConfiguration("subnetwork %s has stack type %q, it does not support %q network interfaces",
+			nic.Subnetwork, subnetwork.StackType, nic.StackType)
+	}
+	computeNIC.StackType = stackTypeIPv4IPv6
+
+	switch nic.IPv6AccessType {
+	case machinev1.IPv6AccessTypeInternal:
+		computeNIC.Ipv6AccessType = ipv6AccessTypeInternal
+	case machinev1.IPv6AccessTypeExternal:
+		computeNIC.Ipv6AccessType = ipv6AccessTypeExternal
+		computeNIC.Ipv6AccessConfigs = []*compute.AccessConfig{
+			{
+				Name: externalIPv6AccessConfigName,
+				Type: directIPv6AccessConfigType,
+			},
+		}
+	default:
+		return nil
+	}
+	if subnetwork != nil && computeNIC.Ipv6AccessType != subnetwork.Ipv6AccessType {
+		return machinecontroller.InvalidMachineConfiguration("subnetwork %s has IPv6 access type %q, it does not support %q network interfaces",
+			nic.Subnetwork, subnetwork.Ipv6AccessType, nic.IPv6AccessType)
+	}
+	return nil
+}
+
+// validateAliasIPRanges makes sure the alias IP ranges of a network interface are either
+// an explicit range, a single IP address or a netmask size.
+func validateAliasIPRanges(nic *machinev1.GCPNetworkInterface) error {
+	for _, aliasRange := range nic.AliasIPRanges {
+		if aliasRange.IPCIDRRange == "" {
+			return fmt.Errorf("alias IP range must specify an ipCIDRRange")
+		}
+		if _, _, err := parseAliasIPRange(aliasRange.IPCIDRRange); err != nil {
+			return fmt.Errorf("invalid alias IP range %q: %v", aliasRange.IPCIDRRange, err)
+		}
+	}
+	return nil
+}
+
+// parseAliasIPRange parses an explicit alias IP range or a single IP address into a prefix and returns
+// its netmask size. Netmask sizes such as "/24" are returned with an invalid prefix.
+func parseAliasIPRange(ipCIDRRange string) (netip.Prefix, int, error) {
+	if size, ok := strings.CutPrefix(ipCIDRRange, "/"); ok {
+		bits, err := strconv.Atoi(size)
+		if err != nil || bits < 0 || bits > 128 {
+			return netip.Prefix{}, 0, fmt.Errorf("netmask size must be between /0 and /128")
+		}
+		return netip.Prefix{}, bits, nil
+	}
+	if !strings.Contains(ipCIDRRange, "/") {
+		addr, err := netip.ParseAddr(ipCIDRRange)
+		if err != nil {
+			return netip.Prefix{}, 0, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), addr.BitLen(), nil
+	}
+	prefix, err := netip.ParsePrefix(ipCIDRRange)
+	if err != nil {
+		return netip.Prefix{}, 0, err
+	}
+	return prefix.Masked(), prefix.Bits(), nil
+}
+
+// aliasIPRangeMatches tells whether an alias IP range allocated to an instance satisfies
+// the range requested in the providerSpec.
+func aliasIPRangeMatches(aliasRange machinev1.GCPAliasIPRange, allocated *compute.AliasIpRange) bool {
+	if aliasRange.SubnetworkRangeName != allocated.SubnetworkRangeName {
+		return false
+	}
+	requested, requestedBits, err := parseAliasIPRange(aliasRange.IPCIDRRange)
+	if err != nil {
+		return false
+	}
+	actual, actualBits, err := parseAliasIPRange(allocated.IpCidrRange)
+	if err != nil {
+		return false
+	}
+	if !requested.IsValid() {
+		return requestedBits == actualBits
+	}
+	return requested == actual
+}
+
+// missingAliasIPRanges returns the alias IP ranges of the providerSpec network interface
+// which are not allocated to the instance network interface yet.
+func missingAliasIPRanges(nic *machinev1.GCPNetworkInterface, computeNIC *compute.NetworkInterface) []*compute.AliasIpRange {
+	matched := make([]bool, len(computeNIC.AliasIpRanges))
+	missing := []*compute.AliasIpRange{}
+	for _, aliasRange := range nic.AliasIPRanges {
+		found := false
+		for i, allocated := range computeNIC.AliasIpRanges {
+			if !matched[i] && aliasIPRangeMatches(aliasRange, allocated) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, &compute.AliasIpRange{
+				IpCidrRange:         aliasRange.IPCIDRRange,
+				SubnetworkRangeName: aliasRange.SubnetworkRangeName,
+			})
+		}
+	}
+	return missing
+}
+
+// aliasIPRangeStatus returns the alias IP ranges allocated to the network interfaces of the instance.
+func aliasIPRangeStatus(instance *compute.Instance) []machinev1.GCPAliasIPRangeStatus {
+	var status []machinev1.GCPAliasIPRangeStatus
+	for _, nic := range instance.NetworkInterfaces {
+		for _, aliasRange := range nic.AliasIpRanges {
+			status = append(status, machinev1.GCPAliasIPRangeStatus{
+				NetworkInterface:    nic.Name,
+				IPCIDRRange:         aliasRange.IpCidrRange,
+				SubnetworkRangeName: aliasRange.SubnetworkRangeName,
+			})
+		}
+	}
+	return status
+}
+
+// reconcileAliasIPRanges adds the alias IP ranges of the providerSpec which are missing
+// from the network interfaces of the instance. Existing ranges are never removed.
+func (r *Reconciler) reconcileAliasIPRanges() error {
+	hasAliasIPRanges := false
+	for _, nic := range r.providerSpec.NetworkInterfaces {
+		if len(nic.AliasIPRanges) > 0 {
+			hasAliasIPRanges = true
+			break
+		}
+	}
+	if !hasAliasIPRanges {
+		return nil
+	}
+
+	instance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.machine.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get instance via compute service: %v", err)
+	}
+
+	// Network interfaces are created in the order they are listed in the providerSpec.
+	for i, nic := range r.providerSpec.NetworkInterfaces {
+		if len(nic.AliasIPRanges) == 0 || i >= len(instance.NetworkInterfaces) {
+			continue
+		}
+		computeNIC := instance.NetworkInterfaces[i]
+		missing := missingAliasIPRanges(nic, computeNIC)
+		if len(missing) == 0 {
+			continue
+		}
+
+		klog.Infof("%s: adding alias IP ranges to network interface %s", r.machine.Name, computeNIC.Name)
+		patch := &compute.NetworkInterface{
+			AliasIpRanges: append(append([]*compute.AliasIpRange{}, computeNIC.AliasIpRanges...), missing...),
+			Fingerprint:   computeNIC.Fingerprint,
+		}
+		op, err := r.computeService.InstancesUpdateNetworkInterface(r.projectID, r.providerSpec.Zone, r.machine.Name, computeNIC.Name, patch)
+		if err != nil {
+			return fmt.Errorf("failed to add alias IP ranges to network interface %s: %w", computeNIC.Name, err)
+		}
+		if err := r.awaitZoneOperation(op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateNetworks checks the networks and subnetworks of the network interfaces exist in the
+// project hosting them, which is the host project for Shared VPC, that the subnetworks are in the
+// region of the machine and can be used by the credentials, and warns about nearly exhausted subnetworks.
+// It returns the subnetwork of each network interface, nil when the network interface does not specify
+// one or it can not be read.
+func (r *Reconciler) validateNetworks() ([]*compute.Subnetwork, error) {
+	subnetworks := make([]*compute.Subnetwork, len(r.providerSpec.NetworkInterfaces))
+	for i, nic := range r.providerSpec.NetworkInterfaces {
+		projectID := nic.ProjectID
+		if projectID == "" {
+			projectID = r.projectID
+		}
+
+		if nic.Network != "" {
+			if _, err := r.computeService.NetworksGet(projectID, nic.Network); err != nil {
+				if isNotFoundError(err) {
+					return nil, machinecontroller.InvalidMachineConfiguration("network %s not found in project %s", nic.Network, projectID)
+				}
+				if !isForbiddenError(err) {
+					return nil, fmt.Errorf("failed to get network %s: %w", nic.Network, err)
+				}
+				klog.Warningf("%s: not allowed to get network %s in project %s, skipping its validation: %v", r.machine.Name, nic.Network, projectID, err)
+			}
+		}
+
+		if nic.Subnetwork == "" {
+			continue
+		}
+		subnetwork, err := r.computeService.SubnetworksGet(projectID, r.providerSpec.Region, nic.Subnetwork)
+		if err != nil {
+			if isNotFoundError(err) {
+				return nil, machinecontroller.InvalidMachineConfiguration("subnetwork %s not found in region %s of project %s", nic.Subnetwork, r.providerSpec.Region, projectID)
+			}
+			if !isForbiddenError(err) {
+				return nil, fmt.Errorf("failed to get subnetwork %s: %w", nic.Subnetwork, err)
+			}
+			klog.Warningf("%s: not allowed to get subnetwork %s in project %s, skipping its validation: %v", r.machine.Name, nic.Subnetwork, projectID, err)
+			subnetwork = nil
+		}
+		if subnetwork != nil && subnetwork.Region != "" && path.Base(subnetwork.Region) != r.providerSpec.Region {
+			return nil, machinecontroller.InvalidMachineConfiguration("subnetwork %s is in region %s, expected region %s", nic.Subnetwork, path.Base(subnetwork.Region), r.providerSpec.Region)
+		}
+		if subnetwork != nil && nic.Network != "" && subnetwork.Network != "" && path.Base(subnetwork.Network) != nic.Network {
+			return nil, machinecontroller.InvalidMachineConfiguration("subnetwork %s belongs to network %s, not %s", nic.Subnetwork, path.Base(subnetwork.Network), nic.Network)
+		}
+
+		granted, err := r.computeService.SubnetworksTestIamPermissions(projectID, r.providerSpec.Region, nic.Subnetwork, []string{subnetworksUsePermission})
+		if err != nil {
+			return nil, fmt.Errorf("failed to test permissions on subnetwork %s: %w", nic.Subnetwork, err)
+		}
+		if !slices.Contains(granted, subnetworksUsePermission) {
+			return nil, machinecontroller.InvalidMachineConfiguration("permission %s is not granted on subnetwork %s in project %s", subnetworksUsePermission, nic.Subnetwork, projectID)
+		}
+
+		subnetworks[i] = subnetwork
+	}
+
+	r.warnOnLowSubnetworkCapacity(subnetworks)
+	return subnetworks, nil
+}
+
+// warnOnLowSubnetworkCapacity logs a warning for each subnetwork whose primary IPv4 range is nearly exhausted.
+// The network interfaces of the project are listed once for all the subnetworks. Only the instances of the
+// machine project are accounted for, instances of other Shared VPC service projects using the subnetwork
+// are not visible.
+func (r *Reconciler) warnOnLowSubnetworkCapacity(subnetworks []*compute.Subnetwork) {
+	checked := sets.NewString()
+	var networkInterfaces []*compute.NetworkInterface
+	for _, subnetwork := range subnetworks {
+		if subnetwork == nil || checked.Has(subnetwork.Name) {
+			continue
+		}
+		checked.Insert(subnetwork.Name)
+
+		if networkInterfaces == nil {
+			ctx := r.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			var err error
+			networkInterfaces, err = r.computeService.InstancesAggregatedNetworkInterfaces(ctx, r.projectID)
+			if err != nil {
+				klog.Warningf("%s: failed to list instances, skipping the capacity check of the subnetworks: %v", r.machine.Name, err)
+				return
+			}
+		}
+
+		free, capacity := subnetworkFreeAddresses(subnetwork, networkInterfaces)
+		if capacity > 0 && free*100 < capacity*subnetworkLowCapacityPercent {
+			klog.Warningf("%s: subnetwork %s is nearly exhausted, %d of %d IP addresses are free", r.machine.Name, subnetwork.Name, free, capacity)
+		}
+	}
+}
+
+// subnetworkFreeAddresses returns the number of free and usable addresses in the primary IPv4 range
+// of the subnetwork, given the network interfaces of the instances.
+func subnetworkFreeAddresses(subnetwork *compute.Subnetwork, networkInterfaces []*compute.NetworkInterface) (int, int) {
+	prefix, err := netip.ParsePrefix(subnetwork.IpCidrRange)
+	if err != nil || !prefix.Addr().Is4() {
+		return 0, 0
+	}
+	capacity := (1 << (32 - prefix.Bits())) - subnetworkReservedAddresses
+	if capacity <= 0 {
+		return 0, 0
+	}
+
+	used := 0
+	for _, nic := range networkInterfaces {
+		if nic.Subnetwork == "" || path.Base(nic.Subnetwork) != subnetwork.Name {
+			continue
+		}
+		used++
+		for _, aliasRange := range nic.AliasIpRanges {
+			if aliasRange.SubnetworkRangeName != "" {
+				continue
+			}
+			if _, bits, err := parseAliasIPRange(aliasRange.IpCidrRange); err == nil && bits <= 32 {
+				used += 1 << (32 - bits)
+			}
+		}
+	}
+	return max(capacity-used, 0), capacity
+}
@@ -0,0 +1,107 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
+)
+
+// processNetworkEndpointGroups makes the instance an endpoint of the network endpoint groups of
+// the provider spec when desired is true, and removes its endpoints from them otherwise.
+func (r *Reconciler) processNetworkEndpointGroups(desired bool) error {
+	// NetworkEndpointGroups may be empty/nil, and that's okay.
+	for _, neg := range r.providerSpec.NetworkEndpointGroups {
+		endpoints, err := r.instanceNetworkEndpoints(neg.Name)
+		if err != nil {
+			return err
+		}
+
+		if desired {
+			if networkEndpointsHavePort(endpoints, neg.Port) {
+				continue
+			}
+			klog.Infof("%v: attaching instance to network endpoint group %s", r.machine.Name, neg.Name)
+			if err := r.attachToNetworkEndpointGroup(neg); err != nil {
+				return err
+			}
+		} else if len(endpoints) > 0 {
+			klog.Infof("%v: detaching instance from network endpoint group %s", r.machine.Name, neg.Name)
+			if err := r.detachFromNetworkEndpointGroup(neg.Name, endpoints); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// instanceNetworkEndpoints returns the endpoints of the instance in the network endpoint group.
+// A deleted network endpoint group has no endpoints, attaching to it fails instead.
+func (r *Reconciler) instanceNetworkEndpoints(neg string) ([]*compute.NetworkEndpoint, error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	endpoints, err := r.computeService.NetworkEndpointGroupsListNetworkEndpoints(ctx, r.projectID, r.providerSpec.Zone, neg)
+	if isNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpoints of network endpoint group %s: %w", neg, err)
+	}
+
+	var instanceEndpoints []*compute.NetworkEndpoint
+	for _, endpoint := range endpoints {
+		// The instance of an endpoint is either its name or its URL
+		if path.Base(endpoint.Instance) == r.machine.Name {
+			instanceEndpoints = append(instanceEndpoints, endpoint)
+		}
+	}
+	return instanceEndpoints, nil
+}
+
+// networkEndpointsHavePort returns whether one of the endpoints is on the port.
+// Any endpoint matches when the port is 0, i.e. the default port of the network endpoint group.
+func networkEndpointsHavePort(endpoints []*compute.NetworkEndpoint, port int32) bool {
+	for _, endpoint := range endpoints {
+		if port == 0 || endpoint.Port == int64(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reconciler) attachToNetworkEndpointGroup(neg machinev1.GCPNetworkEndpointGroupAttachment) error {
+	endpoint := &compute.NetworkEndpoint{
+		Instance: r.machine.Name,
+		Port:     int64(neg.Port),
+	}
+	// Probably safe to disregard the returned operation, as for target pools; the endpoint
+	// is looked up again on the next reconciliation.
+	if _, err := r.computeService.NetworkEndpointGroupsAttachNetworkEndpoints(r.projectID, r.providerSpec.Zone, neg.Name, []*compute.NetworkEndpoint{endpoint}); err != nil {
+		return fmt.Errorf("failed to attach instance to network endpoint group %s: %w", neg.Name, err)
+	}
+	return nil
+}
+
+func (r *Reconciler) detachFromNetworkEndpointGroup(neg string, endpoints []*compute.NetworkEndpoint) error {
+	if _, err := r.computeService.NetworkEndpointGroupsDetachNetworkEndpoints(r.projectID, r.providerSpec.Zone, neg, endpoints); err != nil {
+		return fmt.Errorf("failed to detach instance from network endpoint group %s: %w", neg, err)
+	}
+	return nil
+}
+
+func validateNetworkEndpointGroupAttachment(neg machinev1.GCPNetworkEndpointGroupAttachment) error {
+	if neg.Name == "" {
+		return fmt.Errorf("network endpoint group must have a valid name")
+	}
+	// A port of 0 is the default port of the network endpoint group
+	if neg.Port < 0 || neg.Port > 65535 {
+		return fmt.Errorf("network endpoint group %s port %d must be between 1 and 65535, or omitted", neg.Name, neg.Port)
+	}
+	return nil
+}
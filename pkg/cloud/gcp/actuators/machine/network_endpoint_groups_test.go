@@ -0,0 +1,142 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestProcessNetworkEndpointGroups(t *testing.T) {
+	instanceURL := "https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instances/machine-0"
+
+	cases := []struct {
+		name             string
+		desired          bool
+		port             int32
+		endpoints        []*compute.NetworkEndpoint
+		listErr          error
+		expectedAttached []*compute.NetworkEndpoint
+		expectedErr      bool
+		expectedDetached []*compute.NetworkEndpoint
+		noEndpointGroups bool
+	}{
+		{
+			name:             "Attach when absent",
+			desired:          true,
+			endpoints:        []*compute.NetworkEndpoint{{Instance: "machine-1", IpAddress: "10.0.0.2"}},
+			expectedAttached: []*compute.NetworkEndpoint{{Instance: "machine-0"}},
+		},
+		{
+			name:      "Attach when present",
+			desired:   true,
+			endpoints: []*compute.NetworkEndpoint{{Instance: instanceURL, IpAddress: "10.0.0.1"}},
+		},
+		{
+			name:             "Attach when present on another port",
+			desired:          true,
+			port:             8443,
+			endpoints:        []*compute.NetworkEndpoint{{Instance: instanceURL, IpAddress: "10.0.0.1", Port: 6443}},
+			expectedAttached: []*compute.NetworkEndpoint{{Instance: "machine-0", Port: 8443}},
+		},
+		{
+			name:    "Detach when present",
+			desired: false,
+			endpoints: []*compute.NetworkEndpoint{
+				{Instance: instanceURL, IpAddress: "10.0.0.1", Port: 6443},
+				{Instance: "machine-1", IpAddress: "10.0.0.2", Port: 6443},
+			},
+			expectedDetached: []*compute.NetworkEndpoint{{Instance: instanceURL, IpAddress: "10.0.0.1", Port: 6443}},
+		},
+		{
+			name:      "Detach when absent",
+			desired:   false,
+			endpoints: []*compute.NetworkEndpoint{{Instance: "machine-1", IpAddress: "10.0.0.2"}},
+		},
+		{
+			name:    "Detach from a deleted network endpoint group",
+			desired: false,
+			listErr: &googleapi.Error{Code: http.StatusNotFound},
+		},
+		{
+			name:             "Attach to a deleted network endpoint group",
+			desired:          true,
+			listErr:          &googleapi.Error{Code: http.StatusNotFound},
+			expectedAttached: []*compute.NetworkEndpoint{{Instance: "machine-0"}},
+			expectedErr:      true,
+		},
+		{
+			name:        "Fail to list the endpoints",
+			desired:     false,
+			listErr:     &googleapi.Error{Code: http.StatusForbidden},
+			expectedErr: true,
+		},
+		{
+			name:             "Return early when there are no network endpoint groups",
+			desired:          true,
+			noEndpointGroups: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockNetworkEndpointGroupsListNetworkEndpoints = func(ctx context.Context, project string, zone string, networkEndpointGroup string) ([]*compute.NetworkEndpoint, error) {
+				if networkEndpointGroup != "api" {
+					t.Errorf("Unexpected network endpoint group %s", networkEndpointGroup)
+				}
+				return tc.endpoints, tc.listErr
+			}
+			var attached, detached []*compute.NetworkEndpoint
+			mockComputeService.MockNetworkEndpointGroupsAttachNetworkEndpoints = func(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+				attached = endpoints
+				if tc.listErr != nil {
+					return nil, tc.listErr
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+			mockComputeService.MockNetworkEndpointGroupsDetachNetworkEndpoints = func(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+				detached = endpoints
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			providerSpec := &machinev1.GCPMachineProviderSpec{
+				Region: "region1",
+				Zone:   "zone1",
+			}
+			if !tc.noEndpointGroups {
+				providerSpec.NetworkEndpointGroups = []machinev1.GCPNetworkEndpointGroupAttachment{{Name: "api", Port: tc.port}}
+			}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "machine-0",
+					},
+				},
+				coreClient:     controllerfake.NewFakeClient(),
+				providerSpec:   providerSpec,
+				projectID:      "test",
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+			})
+
+			err := r.processNetworkEndpointGroups(tc.desired)
+			if tc.expectedErr != (err != nil) {
+				t.Errorf("Expected error: %v, got: %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(attached, tc.expectedAttached) {
+				t.Errorf("Expected attached endpoints: %v, got: %v", tc.expectedAttached, attached)
+			}
+			if !reflect.DeepEqual(detached, tc.expectedDetached) {
+				t.Errorf("Expected detached endpoints: %v, got: %v", tc.expectedDetached, detached)
+			}
+		})
+	}
+}
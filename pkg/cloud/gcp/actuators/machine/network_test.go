@@ -0,0 +1,205 @@
+package machine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileAliasIPRanges(t *testing.T) {
+	cases := []struct {
+		name            string
+		aliasIPRanges   []machinev1.GCPAliasIPRange
+		allocatedRanges []*compute.AliasIpRange
+		expectedRanges  []*compute.AliasIpRange
+	}{
+		{
+			name: "No alias IP ranges",
+		},
+		{
+			name: "Add missing alias IP ranges",
+			aliasIPRanges: []machinev1.GCPAliasIPRange{
+				{IPCIDRRange: "/24", SubnetworkRangeName: "pods"},
+				{IPCIDRRange: "10.0.2.5"},
+			},
+			allocatedRanges: []*compute.AliasIpRange{
+				{IpCidrRange: "10.0.2.5/32"},
+			},
+			expectedRanges: []*compute.AliasIpRange{
+				{IpCidrRange: "10.0.2.5/32"},
+				{IpCidrRange: "/24", SubnetworkRangeName: "pods"},
+			},
+		},
+		{
+			name: "Alias IP ranges already allocated",
+			aliasIPRanges: []machinev1.GCPAliasIPRange{
+				{IPCIDRRange: "/24", SubnetworkRangeName: "pods"},
+				{IPCIDRRange: "10.0.2.0/28"},
+			},
+			allocatedRanges: []*compute.AliasIpRange{
+				{IpCidrRange: "10.8.1.0/24", SubnetworkRangeName: "pods"},
+				{IpCidrRange: "10.0.2.0/28"},
+			},
+		},
+		{
+			name: "Range allocated from another secondary range does not match",
+			aliasIPRanges: []machinev1.GCPAliasIPRange{
+				{IPCIDRRange: "/24", SubnetworkRangeName: "pods"},
+			},
+			allocatedRanges: []*compute.AliasIpRange{
+				{IpCidrRange: "10.9.1.0/24", SubnetworkRangeName: "services"},
+			},
+			expectedRanges: []*compute.AliasIpRange{
+				{IpCidrRange: "10.9.1.0/24", SubnetworkRangeName: "services"},
+				{IpCidrRange: "/24", SubnetworkRangeName: "pods"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+				return &compute.Instance{
+					Name: instance,
+					NetworkInterfaces: []*compute.NetworkInterface{
+						{
+							Name:          "nic0",
+							Fingerprint:   "fingerprint",
+							AliasIpRanges: tc.allocatedRanges,
+						},
+					},
+				}, nil
+			}
+			var updatedRanges []*compute.AliasIpRange
+			mockComputeService.MockInstancesUpdateNetworkInterface = func(project string, zone string, instance string, networkInterface string, nic *compute.NetworkInterface) (*compute.Operation, error) {
+				if networkInterface != "nic0" || nic.Fingerprint != "fingerprint" {
+					t.Errorf("Expected network interface nic0 to be updated with its fingerprint, got: %s, %q", networkInterface, nic.Fingerprint)
+				}
+				updatedRanges = nic.AliasIpRanges
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "machine-0",
+					},
+				},
+				coreClient: controllerfake.NewFakeClient(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Region: "region1",
+					Zone:   "zone1",
+					NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+						{
+							Network:       "network",
+							AliasIPRanges: tc.aliasIPRanges,
+						},
+					},
+				},
+				projectID:      "test",
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				computeService: mockComputeService,
+			})
+
+			if err := r.reconcileAliasIPRanges(); err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if !reflect.DeepEqual(updatedRanges, tc.expectedRanges) {
+				t.Errorf("Expected alias IP ranges: %v, got: %v", tc.expectedRanges, updatedRanges)
+			}
+		})
+	}
+}
+
+func TestSubnetworkFreeAddresses(t *testing.T) {
+	subnetworkURL := "https://www.googleapis.com/compute/v1/projects/host/regions/region1/subnetworks/workers"
+
+	cases := []struct {
+		name              string
+		ipCIDRRange       string
+		networkInterfaces []*compute.NetworkInterface
+		expectedFree      int
+		expectedCapacity  int
+	}{
+		{
+			name:             "Empty subnetwork",
+			ipCIDRRange:      "10.0.0.0/24",
+			expectedFree:     252,
+			expectedCapacity: 252,
+		},
+		{
+			name:        "Network interfaces and primary range aliases are accounted for",
+			ipCIDRRange: "10.0.0.0/28",
+			networkInterfaces: []*compute.NetworkInterface{
+				{
+					Subnetwork: subnetworkURL,
+					AliasIpRanges: []*compute.AliasIpRange{
+						{IpCidrRange: "10.0.0.8/30"},
+						{IpCidrRange: "10.8.0.0/24", SubnetworkRangeName: "pods"},
+					},
+				},
+				{Subnetwork: "https://www.googleapis.com/compute/v1/projects/host/regions/region1/subnetworks/other"},
+				{Subnetwork: subnetworkURL},
+			},
+			expectedFree:     6,
+			expectedCapacity: 12,
+		},
+		{
+			name:        "Exhausted subnetwork",
+			ipCIDRRange: "10.0.0.0/29",
+			networkInterfaces: []*compute.NetworkInterface{
+				{Subnetwork: subnetworkURL},
+				{Subnetwork: subnetworkURL},
+				{Subnetwork: subnetworkURL},
+				{Subnetwork: subnetworkURL},
+				{Subnetwork: subnetworkURL},
+			},
+			expectedFree:     0,
+			expectedCapacity: 4,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			free, capacity := subnetworkFreeAddresses(&compute.Subnetwork{Name: "workers", IpCidrRange: tc.ipCIDRRange}, tc.networkInterfaces)
+			if free != tc.expectedFree || capacity != tc.expectedCapacity {
+				t.Errorf("Expected %d of %d free addresses, got: %d of %d", tc.expectedFree, tc.expectedCapacity, free, capacity)
+			}
+		})
+	}
+}
+
+func TestWarnOnLowSubnetworkCapacity(t *testing.T) {
+	_, mockComputeService := computeservice.NewComputeServiceMock()
+	lists := 0
+	mockComputeService.MockInstancesAggregatedNetworkInterfaces = func(_ context.Context, project string) ([]*compute.NetworkInterface, error) {
+		lists++
+		return []*compute.NetworkInterface{{Subnetwork: "projects/project/regions/region1/subnetworks/workers"}}, nil
+	}
+
+	r := newReconciler(&machineScope{
+		machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine-0"}},
+		providerSpec:   &machinev1.GCPMachineProviderSpec{},
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		projectID:      "project",
+		computeService: mockComputeService,
+	})
+
+	workers := &compute.Subnetwork{Name: "workers", IpCidrRange: "10.0.0.0/29"}
+	r.warnOnLowSubnetworkCapacity([]*compute.Subnetwork{workers, nil, workers, {Name: "other", IpCidrRange: "10.1.0.0/24"}})
+	if lists != 1 {
+		t.Errorf("Expected the network interfaces to be listed once for all the subnetworks, got: %d lists", lists)
+	}
+
+	r.warnOnLowSubnetworkCapacity([]*compute.Subnetwork{nil})
+	if lists != 1 {
+		t.Errorf("Expected the network interfaces not to be listed without a subnetwork, got: %d lists", lists)
+	}
+}
@@ -0,0 +1,123 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nodeGroupNameAffinityKey is the affinity label of sole-tenant nodes with the name of their node group.
+	nodeGroupNameAffinityKey = "compute.googleapis.com/node-group-name"
+	// nodeNameAffinityKey is the affinity label of sole-tenant nodes with their name.
+	nodeNameAffinityKey = "compute.googleapis.com/node-name"
+)
+
+// toComputeNodeAffinities converts the node affinities of the provider spec to the node affinities of the instance scheduling.
+func toComputeNodeAffinities(affinities []machinev1.GCPNodeAffinity) []*compute.SchedulingNodeAffinity {
+	var nodeAffinities []*compute.SchedulingNodeAffinity
+	for _, affinity := range affinities {
+		operator := "IN"
+		if affinity.Operator == machinev1.NodeAffinityOperatorNotIn {
+			operator = "NOT_IN"
+		}
+		nodeAffinities = append(nodeAffinities, &compute.SchedulingNodeAffinity{
+			Key:      affinity.Key,
+			Operator: operator,
+			Values:   affinity.Values,
+		})
+	}
+	return nodeAffinities
+}
+
+func validateNodeAffinity(affinity machinev1.GCPNodeAffinity) error {
+	if affinity.Key == "" {
+		return fmt.Errorf("node affinity must have a key")
+	}
+
+	switch affinity.Operator {
+	case machinev1.NodeAffinityOperatorIn, machinev1.NodeAffinityOperatorNotIn:
+	default:
+		return fmt.Errorf("unknown operator %q of node affinity %s, valid values are %q and %q", affinity.Operator, affinity.Key, machinev1.NodeAffinityOperatorIn, machinev1.NodeAffinityOperatorNotIn)
+	}
+
+	if len(affinity.Values) == 0 {
+		return fmt.Errorf("node affinity %s must have at least one value", affinity.Key)
+	}
+	return nil
+}
+
+// validateNodeAffinities checks the node groups and nodes the instance is scheduled on with the In operator
+// exist in the zone of the machine, so that the instance is not inserted with an affinity no node matches.
+// Node groups shared by another project are not listed in the project of the machine, so the validation
+// is skipped when the project has no node group of its own.
+func (r *Reconciler) validateNodeAffinities() error {
+	var selectsNodeGroups, selectsNodes bool
+	for _, affinity := range r.providerSpec.NodeAffinities {
+		if affinity.Operator != machinev1.NodeAffinityOperatorIn {
+			continue
+		}
+		switch affinity.Key {
+		case nodeGroupNameAffinityKey:
+			selectsNodeGroups = true
+		case nodeNameAffinityKey:
+			selectsNodes = true
+		}
+	}
+	if !selectsNodeGroups && !selectsNodes {
+		return nil
+	}
+
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	nodeGroups, err := r.computeService.NodeGroupsList(ctx, r.projectID, r.providerSpec.Zone)
+	if err != nil {
+		if isForbiddenError(err) {
+			klog.Warningf("%s: not allowed to list node groups in zone %s, skipping the validation of node affinities: %v", r.machine.Name, r.providerSpec.Zone, err)
+			return nil
+		}
+		return fmt.Errorf("failed to list node groups in zone %s: %w", r.providerSpec.Zone, err)
+	}
+	if len(nodeGroups) == 0 {
+		klog.Warningf("%s: no node group in zone %s of project %s, the sole-tenant nodes may be shared by another project, skipping the validation of node affinities", r.machine.Name, r.providerSpec.Zone, r.projectID)
+		return nil
+	}
+
+	existingNodeGroups := sets.NewString()
+	existingNodes := sets.NewString()
+	for _, nodeGroup := range nodeGroups {
+		existingNodeGroups.Insert(nodeGroup.Name)
+		if !selectsNodes {
+			continue
+		}
+
+		nodes, err := r.computeService.NodeGroupsListNodes(ctx, r.projectID, r.providerSpec.Zone, nodeGroup.Name)
+		if err != nil {
+			return fmt.Errorf("failed to list nodes of node group %s: %w", nodeGroup.Name, err)
+		}
+		for _, node := range nodes {
+			existingNodes.Insert(node.Name)
+		}
+	}
+
+	// An instance with several values for the same key is scheduled on a node matching any of them
+	for _, affinity := range r.providerSpec.NodeAffinities {
+		if affinity.Operator != machinev1.NodeAffinityOperatorIn {
+			continue
+		}
+		switch {
+		case affinity.Key == nodeGroupNameAffinityKey && !existingNodeGroups.HasAny(affinity.Values...):
+			return machinecontroller.InvalidMachineConfiguration("node groups %v not found in zone %s", affinity.Values, r.providerSpec.Zone)
+		case affinity.Key == nodeNameAffinityKey && !existingNodes.HasAny(affinity.Values...):
+			return machinecontroller.InvalidMachineConfiguration("sole-tenant nodes %v not found in zone %s", affinity.Values, r.providerSpec.Zone)
+		}
+	}
+	return nil
+}
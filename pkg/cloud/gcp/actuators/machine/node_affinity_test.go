@@ -0,0 +1,137 @@
+package machine
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateNodeAffinities(t *testing.T) {
+	nodeGroups := map[string][]*compute.NodeGroupNode{
+		"licensed": {{Name: "licensed-node-1"}, {Name: "licensed-node-2"}},
+		"shared":   {{Name: "shared-node-1"}},
+	}
+
+	cases := []struct {
+		name              string
+		affinities        []machinev1.GCPNodeAffinity
+		sharedNodeGroups  bool
+		listErr           error
+		expectListNodes   bool
+		expectedError     string
+		expectInvalidSpec bool
+	}{
+		{
+			name: "No sole-tenant node selected",
+			affinities: []machinev1.GCPNodeAffinity{
+				{Key: "workload", Operator: machinev1.NodeAffinityOperatorIn, Values: []string{"licensed"}},
+				{Key: nodeGroupNameAffinityKey, Operator: machinev1.NodeAffinityOperatorNotIn, Values: []string{"missing"}},
+			},
+		},
+		{
+			name: "Existing node group",
+			affinities: []machinev1.GCPNodeAffinity{
+				{Key: nodeGroupNameAffinityKey, Operator: machinev1.NodeAffinityOperatorIn, Values: []string{"missing", "licensed"}},
+			},
+		},
+		{
+			name: "Missing node group",
+			affinities: []machinev1.GCPNodeAffinity{
+				{Key: nodeGroupNameAffinityKey, Operator: machinev1.NodeAffinityOperatorIn, Values: []string{"missing"}},
+			},
+			expectedError:     "node groups [missing] not found in zone zone1",
+			expectInvalidSpec: true,
+		},
+		{
+			name: "Existing node",
+			affinities: []machinev1.GCPNodeAffinity{
+				{Key: nodeNameAffinityKey, Operator: machinev1.NodeAffinityOperatorIn, Values: []string{"shared-node-1"}},
+			},
+			expectListNodes: true,
+		},
+		{
+			name: "Missing node",
+			affinities: []machinev1.GCPNodeAffinity{
+				{Key: nodeNameAffinityKey, Operator: machinev1.NodeAffinityOperatorIn, Values: []string{"licensed-node-3"}},
+			},
+			expectListNodes:   true,
+			expectedError:     "sole-tenant nodes [licensed-node-3] not found in zone zone1",
+			expectInvalidSpec: true,
+		},
+		{
+			name: "Skip the validation when node groups are shared by another project",
+			affinities: []machinev1.GCPNodeAffinity{
+				{Key: nodeNameAffinityKey, Operator: machinev1.NodeAffinityOperatorIn, Values: []string{"owner-node-1"}},
+			},
+			sharedNodeGroups: true,
+		},
+		{
+			name: "Skip the validation when node groups cannot be listed",
+			affinities: []machinev1.GCPNodeAffinity{
+				{Key: nodeGroupNameAffinityKey, Operator: machinev1.NodeAffinityOperatorIn, Values: []string{"missing"}},
+			},
+			listErr: &googleapi.Error{Code: http.StatusForbidden},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockNodeGroupsList = func(ctx context.Context, project string, zone string) ([]*compute.NodeGroup, error) {
+				if tc.listErr != nil {
+					return nil, tc.listErr
+				}
+				var groups []*compute.NodeGroup
+				if tc.sharedNodeGroups {
+					return groups, nil
+				}
+				for name := range nodeGroups {
+					groups = append(groups, &compute.NodeGroup{Name: name})
+				}
+				return groups, nil
+			}
+			listedNodes := false
+			mockComputeService.MockNodeGroupsListNodes = func(ctx context.Context, project string, zone string, nodeGroup string) ([]*compute.NodeGroupNode, error) {
+				listedNodes = true
+				return nodeGroups[nodeGroup], nil
+			}
+
+			r := newReconciler(&machineScope{
+				Context: context.Background(),
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "machine-0",
+					},
+				},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Region:         "region1",
+					Zone:           "zone1",
+					NodeAffinities: tc.affinities,
+				},
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			err := r.validateNodeAffinities()
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if isInvalidMachineConfigurationError(err) != tc.expectInvalidSpec {
+				t.Errorf("Expected invalid machine configuration: %v, got: %v", tc.expectInvalidSpec, err)
+			}
+			if listedNodes != tc.expectListNodes {
+				t.Errorf("Expected nodes to be listed: %v, got: %v", tc.expectListNodes, listedNodes)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	operationStatusDone = "DONE"
+
+	// operationRequeueAfter is the time given to a started operation before the machine is reconciled again.
+	operationRequeueAfter = 5 * time.Second
+)
+
+// awaitZoneOperation returns once a zonal operation is done. A running operation is recorded in the
+// providerStatus and checked on the next reconciliation, rather than blocking the reconcile worker.
+func (r *Reconciler) awaitZoneOperation(op *compute.Operation) error {
+	return r.awaitOperation(op, machinev1.GCPOperationReference{Zone: r.providerSpec.Zone})
+}
+
+// awaitRegionOperation returns once a regional operation, such as an address reservation, is done.
+func (r *Reconciler) awaitRegionOperation(op *compute.Operation) error {
+	return r.awaitOperation(op, machinev1.GCPOperationReference{Region: r.providerSpec.Region})
+}
+
+// awaitGlobalOperation returns once a global operation, such as a global backend service patch, is done.
+func (r *Reconciler) awaitGlobalOperation(op *compute.Operation) error {
+	return r.awaitOperation(op, machinev1.GCPOperationReference{})
+}
+
+// awaitOperation returns the error of an operation which is already done. Otherwise it records
+// the operation as pending and returns a RequeueAfterError.
+func (r *Reconciler) awaitOperation(op *compute.Operation, ref machinev1.GCPOperationReference) error {
+	if op == nil || op.Status == operationStatusDone {
+		return operationError(op)
+	}
+
+	ref.Name = op.Name
+	r.providerStatus.PendingOperations = append(r.providerStatus.PendingOperations, ref)
+	klog.Infof("%s: waiting for %s operation %s on %s, requeuing...", r.machine.Name, op.OperationType, op.Name, resourceName(op.TargetLink))
+	return &machinecontroller.RequeueAfterError{RequeueAfter: operationRequeueAfter}
+}
+
+// checkPendingOperations checks the operations recorded in the providerStatus. It returns a RequeueAfterError
+// as long as one of them is running and the error of the first failed one, which is then forgotten so that
+// the failed step is retried.
+func (r *Reconciler) checkPendingOperations() error {
+	if len(r.providerStatus.PendingOperations) == 0 {
+		return nil
+	}
+
+	var pending []machinev1.GCPOperationReference
+	var failed error
+	for i, ref := range r.providerStatus.PendingOperations {
+		op, err := r.getOperation(ref)
+		if isNotFoundError(err) {
+			// Operations are garbage collected some time after they are done
+			continue
+		}
+		if err != nil {
+			pending = append(pending, r.providerStatus.PendingOperations[i:]...)
+			r.providerStatus.PendingOperations = pending
+			return fmt.Errorf("failed to get operation %s: %w", ref.Name, err)
+		}
+		if op != nil && op.Status != operationStatusDone {
+			pending = append(pending, ref)
+			continue
+		}
+		if err := operationError(op); err != nil && failed == nil {
+			failed = err
+		}
+	}
+	r.providerStatus.PendingOperations = pending
+
+	if failed != nil {
+		return failed
+	}
+	if len(pending) > 0 {
+		klog.Infof("%s: waiting for %d operations, requeuing...", r.machine.Name, len(pending))
+		return &machinecontroller.RequeueAfterError{RequeueAfter: operationRequeueAfter}
+	}
+	return nil
+}
+
+// getOperation returns the zonal, regional or global operation referenced.
+func (r *Reconciler) getOperation(ref machinev1.GCPOperationReference) (*compute.Operation, error) {
+	switch {
+	case ref.Zone != "":
+		return r.computeService.ZoneOperationsGet(r.projectID, ref.Zone, ref.Name)
+	case ref.Region != "":
+		return r.computeService.RegionOperationsGet(r.projectID, ref.Region, ref.Name)
+	}
+	return r.computeService.GlobalOperationsGet(r.projectID, ref.Name)
+}
+
+// operationError returns the first error of a done operation.
+func operationError(op *compute.Operation) error {
+	if op == nil || op.Error == nil || len(op.Error.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("operation %s failed: %s", op.Name, op.Error.Errors[0].Message)
+}
+
+// isRequeueAfterError tells whether the error only asks for the machine to be reconciled again.
+func isRequeueAfterError(err error) bool {
+	var requeueAfterError *machinecontroller.RequeueAfterError
+	return errors.As(err, &requeueAfterError)
+}
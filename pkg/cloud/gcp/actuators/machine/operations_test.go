@@ -0,0 +1,157 @@
+package machine
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAwaitOperation(t *testing.T) {
+	cases := []struct {
+		name            string
+		op              *compute.Operation
+		expectedError   string
+		expectRequeue   bool
+		expectedPending []machinev1.GCPOperationReference
+	}{
+		{
+			name: "Done operation",
+			op:   &compute.Operation{Name: "op-1", Status: "DONE"},
+		},
+		{
+			name: "Failed operation",
+			op: &compute.Operation{Name: "op-1", Status: "DONE", Error: &compute.OperationError{
+				Errors: []*compute.OperationErrorErrors{{Message: "quota exceeded"}},
+			}},
+			expectedError: "operation op-1 failed: quota exceeded",
+		},
+		{
+			name:            "Running operation",
+			op:              &compute.Operation{Name: "op-1", Status: "RUNNING"},
+			expectRequeue:   true,
+			expectedPending: []machinev1.GCPOperationReference{{Name: "op-1", Region: "region1"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			r := newReconciler(&machineScope{
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine-0"}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "region1", Zone: "zone1"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{},
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			err := r.awaitRegionOperation(tc.op)
+			switch {
+			case tc.expectRequeue:
+				if !isRequeueAfterError(err) {
+					t.Errorf("Expected a requeue, got: %v", err)
+				}
+			case tc.expectedError != "":
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			case err != nil:
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if !reflect.DeepEqual(r.providerStatus.PendingOperations, tc.expectedPending) {
+				t.Errorf("Expected pending operations %v, got: %v", tc.expectedPending, r.providerStatus.PendingOperations)
+			}
+		})
+	}
+}
+
+func TestCheckPendingOperations(t *testing.T) {
+	operations := map[string]*compute.Operation{
+		"running": {Name: "running", Status: "RUNNING"},
+		"done":    {Name: "done", Status: "DONE"},
+		"failed": {Name: "failed", Status: "DONE", Error: &compute.OperationError{
+			Errors: []*compute.OperationErrorErrors{{Message: "resource not ready"}},
+		}},
+	}
+
+	cases := []struct {
+		name            string
+		pending         []machinev1.GCPOperationReference
+		expectedError   string
+		expectRequeue   bool
+		expectedPending []machinev1.GCPOperationReference
+	}{
+		{
+			name: "No pending operations",
+		},
+		{
+			name:    "Done operations are forgotten",
+			pending: []machinev1.GCPOperationReference{{Name: "done", Region: "region1"}, {Name: "done"}},
+		},
+		{
+			name:    "Garbage collected operations are forgotten",
+			pending: []machinev1.GCPOperationReference{{Name: "missing", Region: "region1"}},
+		},
+		{
+			name:            "Running operations are kept",
+			pending:         []machinev1.GCPOperationReference{{Name: "done", Region: "region1"}, {Name: "running"}},
+			expectRequeue:   true,
+			expectedPending: []machinev1.GCPOperationReference{{Name: "running"}},
+		},
+		{
+			name:            "Failed operations are reported once",
+			pending:         []machinev1.GCPOperationReference{{Name: "failed", Region: "region1"}, {Name: "running", Region: "region1"}},
+			expectedError:   "operation failed failed: resource not ready",
+			expectedPending: []machinev1.GCPOperationReference{{Name: "running", Region: "region1"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			getOperation := func(name string) (*compute.Operation, error) {
+				op, ok := operations[name]
+				if !ok {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+				return op, nil
+			}
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockRegionOperationsGet = func(project string, region string, operation string) (*compute.Operation, error) {
+				return getOperation(operation)
+			}
+			mockComputeService.MockGlobalOperationsGet = func(project string, operation string) (*compute.Operation, error) {
+				return getOperation(operation)
+			}
+
+			r := newReconciler(&machineScope{
+				machine:        &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine-0"}},
+				providerSpec:   &machinev1.GCPMachineProviderSpec{Region: "region1", Zone: "zone1"},
+				providerStatus: &machinev1.GCPMachineProviderStatus{PendingOperations: tc.pending},
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			err := r.checkPendingOperations()
+			switch {
+			case tc.expectRequeue:
+				if !isRequeueAfterError(err) {
+					t.Errorf("Expected a requeue, got: %v", err)
+				}
+			case tc.expectedError != "":
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			case err != nil:
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if !reflect.DeepEqual(r.providerStatus.PendingOperations, tc.expectedPending) {
+				t.Errorf("Expected pending operations %v, got: %v", tc.expectedPending, r.providerStatus.PendingOperations)
+			}
+		})
+	}
+}
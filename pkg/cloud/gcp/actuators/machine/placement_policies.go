@@ -0,0 +1,213 @@
+package machine
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	placementPolicyNameSuffix = "-placement"
+	placementPolicyCollocated = "COLLOCATED"
+
+	machineSetKind = "MachineSet"
+)
+
+// resourceNameRegexp matches the names of GCP resources.
+var resourceNameRegexp = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// machineSetName returns the name of the machine set controlling the machine, empty when the
+// machine is not part of a machine set.
+func machineSetName(machine *machinev1.Machine) string {
+	owner := metav1.GetControllerOf(machine)
+	if owner == nil || owner.Kind != machineSetKind {
+		return ""
+	}
+	return owner.Name
+}
+
+// placementPolicyName returns the name of the group placement policy of the machine, which is either
+// the existing policy it references or the compact placement policy created for its machine set.
+// The name of the created policy is scoped to the cluster by its infrastructure ID, which machine set
+// names usually start with already.
+func placementPolicyName(machine *machinev1.Machine, policy *machinev1.GCPPlacementPolicy) (string, error) {
+	if policy.Name != "" {
+		return policy.Name, nil
+	}
+
+	machineSet := machineSetName(machine)
+	if machineSet == "" {
+		return "", fmt.Errorf("placement policy name is required for machines that are not part of a machine set")
+	}
+	clusterID := machine.Labels[machinev1.MachineClusterIDLabel]
+	if clusterID == "" {
+		return "", fmt.Errorf("machine is missing %q label", machinev1.MachineClusterIDLabel)
+	}
+	name := machineSet + placementPolicyNameSuffix
+	if !strings.HasPrefix(machineSet, clusterID+"-") {
+		name = clusterID + "-" + name
+	}
+	if !resourceNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("placement policy name %q derived from the machine set is not a valid resource name, set the placement policy name", name)
+	}
+	return name, nil
+}
+
+// ensurePlacementPolicy returns the URL of the group placement policy of the instance, creating the
+// compact placement policy of the machine set when it does not exist yet.
+func (r *Reconciler) ensurePlacementPolicy() (string, error) {
+	policy := r.providerSpec.PlacementPolicy
+	if policy == nil {
+		return "", nil
+	}
+
+	name, err := placementPolicyName(r.machine, policy)
+	if err != nil {
+		return "", machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+	url := fmt.Sprintf(resourcePolicyFmt, r.projectID, r.providerSpec.Region, name)
+
+	resourcePolicy, err := r.computeService.ResourcePoliciesGet(r.projectID, r.providerSpec.Region, name)
+	if err == nil {
+		if resourcePolicy.GroupPlacementPolicy == nil {
+			return "", machinecontroller.InvalidMachineConfiguration("resource policy %s is not a group placement policy", name)
+		}
+		return url, nil
+	}
+	if !isNotFoundError(err) {
+		return "", fmt.Errorf("failed to get resource policy %s: %w", name, err)
+	}
+	if policy.Name != "" {
+		return "", machinecontroller.InvalidMachineConfiguration("resource policy %s not found in region %s", name, r.providerSpec.Region)
+	}
+
+	klog.Infof("%s: creating compact placement policy %s", r.machine.Name, name)
+	op, err := r.computeService.ResourcePoliciesInsert(r.projectID, r.providerSpec.Region, &compute.ResourcePolicy{
+		Name:        name,
+		Description: fmt.Sprintf("Compact placement policy of machine set %s", machineSetName(r.machine)),
+		GroupPlacementPolicy: &compute.ResourcePolicyGroupPlacementPolicy{
+			Collocation: placementPolicyCollocated,
+		},
+	})
+	// Another machine of the machine set may have created the policy in the meantime
+	if err != nil && !isAlreadyExistsError(err) {
+		return "", fmt.Errorf("failed to create placement policy %s: %w", name, err)
+	}
+	if err := r.awaitRegionOperation(op); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// placementPolicies returns the resource policies of the instance placed in the group placement policy.
+func placementPolicies(url string) []string {
+	if url == "" {
+		return nil
+	}
+	return []string{url}
+}
+
+// releasePlacementPolicy deletes the compact placement policy created for the machine set once the
+// instance is deleted, unless other machines of the machine set are still placed in it. It requeues
+// while the instances of other machines being deleted still use the policy.
+func (r *Reconciler) releasePlacementPolicy() error {
+	policy := r.providerSpec.PlacementPolicy
+	if policy == nil || policy.Name != "" {
+		return nil
+	}
+	name, err := placementPolicyName(r.machine, policy)
+	if err != nil {
+		// The policy could not have been created
+		return nil
+	}
+
+	inUse, err := r.isUsedByMachineSet(func(providerSpec *machinev1.GCPMachineProviderSpec) bool {
+		return providerSpec.PlacementPolicy != nil && providerSpec.PlacementPolicy.Name == ""
+	})
+	if err != nil {
+		return err
+	}
+	if inUse {
+		klog.Infof("%s: placement policy %s is used by other machines of the machine set, keeping it", r.machine.Name, name)
+		return nil
+	}
+
+	klog.Infof("%s: deleting placement policy %s", r.machine.Name, name)
+	op, err := r.computeService.ResourcePoliciesDelete(r.projectID, r.providerSpec.Region, name)
+	if isNotFoundError(err) {
+		return nil
+	}
+	if isResourceInUseError(err) {
+		// The instances of the other machines being deleted still use the policy. Every machine being deleted
+		// retries until the policy is deleted by one of them, once the last instance is gone.
+		klog.Infof("%s: placement policy %s is still used by instances, requeuing...", r.machine.Name, name)
+		return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete placement policy %s: %w", name, err)
+	}
+	return r.awaitRegionOperation(op)
+}
+
+// isUsedByMachineSet tells whether other machines of the machine set, which are not being deleted,
+// use the resource created for the machine set, according to their provider spec.
+func (r *Reconciler) isUsedByMachineSet(uses func(providerSpec *machinev1.GCPMachineProviderSpec) bool) (bool, error) {
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	machines := &machinev1.MachineList{}
+	if err := r.coreClient.List(ctx, machines, controllerclient.InNamespace(r.machine.Namespace)); err != nil {
+		return false, fmt.Errorf("failed to list machines: %w", err)
+	}
+
+	machineSet := machineSetName(r.machine)
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		if machine.Name == r.machine.Name || machine.DeletionTimestamp != nil || machineSetName(machine) != machineSet {
+			continue
+		}
+		providerSpec, err := util.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+		if err != nil {
+			// Keep the resource rather than deleting it from under a machine that may use it
+			return true, nil
+		}
+		if uses(providerSpec) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func isAlreadyExistsError(err error) bool {
+	switch t := err.(type) {
+	case *googleapi.Error:
+		return t.Code == http.StatusConflict
+	}
+	return false
+}
+
+func isResourceInUseError(err error) bool {
+	switch t := err.(type) {
+	case *googleapi.Error:
+		for _, item := range t.Errors {
+			if item.Reason == "resourceInUseByAnotherResource" {
+				return true
+			}
+		}
+	}
+	return false
+}
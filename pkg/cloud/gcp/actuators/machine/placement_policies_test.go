@@ -0,0 +1,289 @@
+package machine
+
+import (
+	"net/http"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	controllerclient "sigs.k8s.io/controller-runtime/pkg/client"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newPlacementPolicyMachine(t *testing.T, name string, machineSet string, policy *machinev1.GCPPlacementPolicy) *machinev1.Machine {
+	providerSpec, err := util.RawExtensionFromProviderSpec(&machinev1.GCPMachineProviderSpec{PlacementPolicy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "openshift-machine-api",
+			Labels:    map[string]string{machinev1.MachineClusterIDLabel: "cluster-1"},
+		},
+		Spec: machinev1.MachineSpec{
+			ProviderSpec: machinev1.ProviderSpec{Value: providerSpec},
+		},
+	}
+	if machineSet != "" {
+		machine.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: machinev1.GroupVersion.String(),
+			Kind:       machineSetKind,
+			Name:       machineSet,
+			Controller: ptr.To(true),
+		}}
+	}
+	return machine
+}
+
+func TestEnsurePlacementPolicy(t *testing.T) {
+	cases := []struct {
+		name              string
+		machineSet        string
+		policy            *machinev1.GCPPlacementPolicy
+		existingPolicy    *compute.ResourcePolicy
+		insertErr         error
+		expectedURL       string
+		expectedCreated   string
+		expectedError     string
+		expectInvalidSpec bool
+	}{
+		{
+			name: "No placement policy",
+		},
+		{
+			name:           "Existing placement policy",
+			policy:         &machinev1.GCPPlacementPolicy{Name: "hpc"},
+			existingPolicy: &compute.ResourcePolicy{Name: "hpc", GroupPlacementPolicy: &compute.ResourcePolicyGroupPlacementPolicy{Collocation: "COLLOCATED"}},
+			expectedURL:    "projects/test/regions/region1/resourcePolicies/hpc",
+		},
+		{
+			name:              "Missing placement policy",
+			policy:            &machinev1.GCPPlacementPolicy{Name: "hpc"},
+			expectedError:     "resource policy hpc not found in region region1",
+			expectInvalidSpec: true,
+		},
+		{
+			name:              "Resource policy which is not a placement policy",
+			policy:            &machinev1.GCPPlacementPolicy{Name: "snapshots"},
+			existingPolicy:    &compute.ResourcePolicy{Name: "snapshots", SnapshotSchedulePolicy: &compute.ResourcePolicySnapshotSchedulePolicy{}},
+			expectedError:     "resource policy snapshots is not a group placement policy",
+			expectInvalidSpec: true,
+		},
+		{
+			name:            "Create the placement policy of the machine set",
+			machineSet:      "gpus",
+			policy:          &machinev1.GCPPlacementPolicy{},
+			expectedURL:     "projects/test/regions/region1/resourcePolicies/cluster-1-gpus-placement",
+			expectedCreated: "cluster-1-gpus-placement",
+		},
+		{
+			name:            "Placement policy of the machine set created by another machine",
+			machineSet:      "gpus",
+			policy:          &machinev1.GCPPlacementPolicy{},
+			insertErr:       &googleapi.Error{Code: http.StatusConflict},
+			expectedURL:     "projects/test/regions/region1/resourcePolicies/cluster-1-gpus-placement",
+			expectedCreated: "cluster-1-gpus-placement",
+		},
+		{
+			name:            "Machine set named after the cluster",
+			machineSet:      "cluster-1-gpus",
+			policy:          &machinev1.GCPPlacementPolicy{},
+			expectedURL:     "projects/test/regions/region1/resourcePolicies/cluster-1-gpus-placement",
+			expectedCreated: "cluster-1-gpus-placement",
+		},
+		{
+			name:           "Existing placement policy of the machine set",
+			machineSet:     "gpus",
+			policy:         &machinev1.GCPPlacementPolicy{},
+			existingPolicy: &compute.ResourcePolicy{Name: "cluster-1-gpus-placement", GroupPlacementPolicy: &compute.ResourcePolicyGroupPlacementPolicy{Collocation: "COLLOCATED"}},
+			expectedURL:    "projects/test/regions/region1/resourcePolicies/cluster-1-gpus-placement",
+		},
+		{
+			name:              "Machine without machine set",
+			policy:            &machinev1.GCPPlacementPolicy{},
+			expectedError:     "placement policy name is required for machines that are not part of a machine set",
+			expectInvalidSpec: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockResourcePoliciesGet = func(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error) {
+				if tc.existingPolicy == nil || tc.existingPolicy.Name != resourcePolicy {
+					return nil, &googleapi.Error{Code: http.StatusNotFound}
+				}
+				return tc.existingPolicy, nil
+			}
+			var created *compute.ResourcePolicy
+			mockComputeService.MockResourcePoliciesInsert = func(project string, region string, resourcePolicy *compute.ResourcePolicy) (*compute.Operation, error) {
+				created = resourcePolicy
+				if tc.insertErr != nil {
+					return nil, tc.insertErr
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newReconciler(&machineScope{
+				machine: newPlacementPolicyMachine(t, "machine-0", tc.machineSet, tc.policy),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Region:          "region1",
+					Zone:            "zone1",
+					PlacementPolicy: tc.policy,
+				},
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			url, err := r.ensurePlacementPolicy()
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if isInvalidMachineConfigurationError(err) != tc.expectInvalidSpec {
+				t.Errorf("Expected invalid machine configuration: %v, got: %v", tc.expectInvalidSpec, err)
+			}
+			if url != tc.expectedURL {
+				t.Errorf("Expected placement policy %q, got: %q", tc.expectedURL, url)
+			}
+
+			switch {
+			case tc.expectedCreated == "" && created != nil:
+				t.Errorf("Expected no placement policy to be created, got: %s", created.Name)
+			case tc.expectedCreated != "" && created == nil:
+				t.Errorf("Expected placement policy %s to be created", tc.expectedCreated)
+			case created != nil:
+				if created.Name != tc.expectedCreated {
+					t.Errorf("Expected placement policy %s to be created, got: %s", tc.expectedCreated, created.Name)
+				}
+				if created.GroupPlacementPolicy == nil || created.GroupPlacementPolicy.Collocation != "COLLOCATED" {
+					t.Errorf("Expected a compact placement policy, got: %v", created.GroupPlacementPolicy)
+				}
+			}
+		})
+	}
+}
+
+func TestReleasePlacementPolicy(t *testing.T) {
+	createdPolicy := &machinev1.GCPPlacementPolicy{}
+
+	deletingMachine := newPlacementPolicyMachine(t, "gpus-2", "gpus", createdPolicy)
+	deletingMachine.DeletionTimestamp = ptr.To(metav1.Now())
+	deletingMachine.Finalizers = []string{"machine.machine.openshift.io"}
+
+	cases := []struct {
+		name          string
+		policy        *machinev1.GCPPlacementPolicy
+		otherMachines []controllerclient.Object
+		deleteErr     error
+		expectDelete  bool
+		expectRequeue bool
+		expectedError string
+	}{
+		{
+			name: "No placement policy",
+		},
+		{
+			name:   "Existing placement policy is kept",
+			policy: &machinev1.GCPPlacementPolicy{Name: "hpc"},
+		},
+		{
+			name:          "Last machine of the machine set",
+			policy:        createdPolicy,
+			otherMachines: []controllerclient.Object{newPlacementPolicyMachine(t, "cpus-0", "cpus", createdPolicy)},
+			expectDelete:  true,
+		},
+		{
+			name:          "Other machine of the machine set in the placement policy",
+			policy:        createdPolicy,
+			otherMachines: []controllerclient.Object{newPlacementPolicyMachine(t, "gpus-1", "gpus", createdPolicy)},
+		},
+		{
+			name:          "Other machine of the machine set without placement policy",
+			policy:        createdPolicy,
+			otherMachines: []controllerclient.Object{newPlacementPolicyMachine(t, "gpus-1", "gpus", nil)},
+			expectDelete:  true,
+		},
+		{
+			name:          "Other machine of the machine set being deleted",
+			policy:        createdPolicy,
+			otherMachines: []controllerclient.Object{deletingMachine},
+			deleteErr: &googleapi.Error{
+				Code:   http.StatusBadRequest,
+				Errors: []googleapi.ErrorItem{{Reason: "resourceInUseByAnotherResource"}},
+			},
+			expectDelete:  true,
+			expectRequeue: true,
+		},
+		{
+			name:         "Placement policy already deleted",
+			policy:       createdPolicy,
+			deleteErr:    &googleapi.Error{Code: http.StatusNotFound},
+			expectDelete: true,
+		},
+		{
+			name:          "Failure to delete the placement policy",
+			policy:        createdPolicy,
+			deleteErr:     &googleapi.Error{Code: http.StatusInternalServerError},
+			expectDelete:  true,
+			expectedError: "failed to delete placement policy cluster-1-gpus-placement: googleapi: got HTTP response code 500 with body: ",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			deleted := false
+			mockComputeService.MockResourcePoliciesDelete = func(project string, region string, resourcePolicy string) (*compute.Operation, error) {
+				deleted = true
+				if resourcePolicy != "cluster-1-gpus-placement" {
+					t.Errorf("Unexpected placement policy %s deleted", resourcePolicy)
+				}
+				if tc.deleteErr != nil {
+					return nil, tc.deleteErr
+				}
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			machine := newPlacementPolicyMachine(t, "gpus-0", "gpus", tc.policy)
+			r := newReconciler(&machineScope{
+				machine:    machine,
+				coreClient: controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(tc.otherMachines, machine)...).Build(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Region:          "region1",
+					Zone:            "zone1",
+					PlacementPolicy: tc.policy,
+				},
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			err := r.releasePlacementPolicy()
+			if tc.expectRequeue {
+				if !isRequeueAfterError(err) {
+					t.Errorf("Expected a requeue, got: %v", err)
+				}
+			} else if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if deleted != tc.expectDelete {
+				t.Errorf("Expected placement policy to be deleted: %v, got: %v", tc.expectDelete, deleted)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -156,6 +157,10 @@ func (r *Reconciler) validateGuestAccelerators() error {
 	// machine types that have GPUs included would skip this function. The ultimate result for users it that new GPU
 	// machine types will not have accurate quota reporting. If machines are being pathologically deleted and recreated
 	// it may be a sign of a quota issue.
+	// The machine type and accelerators of a source instance template are validated on insert.
+	if r.providerSpec.MachineType == "" {
+		return nil
+	}
 	if len(r.providerSpec.GPUs) == 0 && !strings.HasPrefix(r.providerSpec.MachineType, "a2-") && !strings.HasPrefix(r.providerSpec.MachineType, "a3-") || strings.HasPrefix(r.providerSpec.MachineType, "a3-ultragpu-") {
 		// no accelerators to validate so return nil
 		return nil
@@ -191,6 +196,37 @@ func (r *Reconciler) create() error {
 		return machinecontroller.InvalidMachineConfiguration("failed validating machine provider spec: %v", err)
 	}
 
+	// Wait for the operations started by the previous reconciliation
+	if err := r.checkPendingOperations(); err != nil {
+		return err
+	}
+
+	// Fail early instead of on insert when a KMS key cannot be used
+	if err := r.validateKMSKeys(); err != nil {
+		return err
+	}
+
+	// Fail early instead of on insert when a network or subnetwork cannot be used
+	subnetworks, err := r.validateNetworks()
+	if err != nil {
+		return err
+	}
+
+	// Fail early instead of on insert when no sole-tenant node matches the node affinities
+	if err := r.validateNodeAffinities(); err != nil {
+		return err
+	}
+
+	// Fail early instead of on insert when the specific reservation cannot be consumed
+	if err := r.validateReservation(); err != nil {
+		return err
+	}
+
+	placementPolicy, err := r.ensurePlacementPolicy()
+	if err != nil {
+		return err
+	}
+
 	labels, err := util.GetLabelsList(r.coreClient, r.machine.Labels[machinev1.MachineClusterIDLabel], r.providerSpec.Labels)
 	if err != nil {
 		return fmt.Errorf("error getting user-defined labels for machine %s: %w", r.machine.Name, err)
@@ -203,12 +239,18 @@ func (r *Reconciler) create() error {
 		return fmt.Errorf("failed to convert provisioning model: %w", err)
 	}
 
+	hostname, err := instanceHostname(*r.machine, *r.providerSpec)
+	if err != nil {
+		return machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+
 	instance := &compute.Instance{
 		CanIpForward:       r.providerSpec.CanIPForward,
 		DeletionProtection: r.providerSpec.DeletionProtection,
 		Labels:             labels,
 		MachineType:        fmt.Sprintf(machineTypeFmt, zone, r.providerSpec.MachineType),
 		Name:               r.machine.Name,
+		Hostname:           hostname,
 		Tags: &compute.Tags{
 			Items: r.providerSpec.Tags,
 		},
@@ -216,7 +258,10 @@ func (r *Reconciler) create() error {
 			Preemptible:       r.providerSpec.Preemptible,
 			OnHostMaintenance: string(r.providerSpec.OnHostMaintenance),
 			ProvisioningModel: provisioningModel,
+			NodeAffinities:    toComputeNodeAffinities(r.providerSpec.NodeAffinities),
 		},
+		ReservationAffinity: toComputeReservationAffinity(r.providerSpec.ReservationAffinity, r.projectID),
+		ResourcePolicies:    placementPolicies(placementPolicy),
 		ShieldedInstanceConfig: &compute.ShieldedInstanceConfig{
 			EnableSecureBoot:          false,
 			EnableVtpm:                true,
@@ -238,6 +283,11 @@ func (r *Reconciler) create() error {
 		instance.Scheduling.AutomaticRestart = automaticRestart
 	}
 
+	bootImage, err := r.inspectBootImage()
+	if err != nil {
+		return err
+	}
+
 	// This is mostly to smooth off a rough edge, and hopefully should not be a
 	// case that is hit often. If an existing machineset has a non UEFI
 	// compatible disk, the check in the machineset controller should explicitly
@@ -247,13 +297,14 @@ func (r *Reconciler) create() error {
 	// machineset reconciliation in MAPG.
 	//
 	// If the providerSpec sets any ShieldedInstanceConfig, we won't check UEFI
-	// disk compatibility.
-	if r.providerSpec.ShieldedInstanceConfig == (machinev1.GCPShieldedInstanceConfig{}) {
+	// disk compatibility. Neither do we when the boot disk is the one of the
+	// source instance template, the template sets its own ShieldedInstanceConfig.
+	if r.providerSpec.ShieldedInstanceConfig == (machinev1.GCPShieldedInstanceConfig{}) && !usesInstanceTemplateDisks(r.providerSpec) {
 		klog.V(3).Infof("No ShieldedInstanceConfig set for machine: %s, checking if disk is UEFI compatible", r.machine.Name)
-		uefiCompatible, err := util.IsUEFICompatible(r.computeService, r.providerSpec)
-		if err != nil {
-			return fmt.Errorf("error fetching disk information: %w", err)
+		if bootImage == nil {
+			return fmt.Errorf("error fetching disk information: no boot disk found")
 		}
+		uefiCompatible := util.IsImageUEFICompatible(bootImage)
 
 		// We do this here so we piggyback defaulting and ForceSendFields below
 		// will still work nicely.
@@ -313,7 +364,7 @@ func (r *Reconciler) create() error {
 
 	// disks
 	var disks = []*compute.AttachedDisk{}
-	for _, disk := range r.providerSpec.Disks {
+	for i, disk := range r.providerSpec.Disks {
 		srcImage := ""
 		if disk.Image != "" {
 			srcImage = disk.Image
@@ -329,11 +380,27 @@ func (r *Reconciler) create() error {
 			return fmt.Errorf("error getting user-defined labels for machine disk %s: %w", r.machine.Name, err)
 		}
 
+		resourcePolicies, err := r.resourcePolicyURLs(disk.ResourcePolicies)
+		if err != nil {
+			return err
+		}
+
+		encryptionKey, err := r.diskEncryptionKey(disk.EncryptionKey)
+		if err != nil {
+			return err
+		}
+
+		diskSizeGB := disk.SizeGB
+		if disk.Boot {
+			diskSizeGB = util.BootDiskSizeGB(bootImage, disk)
+		}
+
 		initParams := &compute.AttachedDiskInitializeParams{
-			DiskSizeGb:          disk.SizeGB,
+			DiskSizeGb:          diskSizeGB,
 			DiskType:            fmt.Sprintf("zones/%s/diskTypes/%s", zone, disk.Type),
 			Labels:              labels,
 			ResourceManagerTags: userTags,
+			ResourcePolicies:    resourcePolicies,
 		}
 		// Only set SourceImage if it's not empty (blank disk if empty)
 		if srcImage != "" {
@@ -343,8 +410,9 @@ func (r *Reconciler) create() error {
 		disks = append(disks, &compute.AttachedDisk{
 			AutoDelete:        disk.AutoDelete,
 			Boot:              disk.Boot,
+			DeviceName:        diskDeviceName(i),
 			InitializeParams:  initParams,
-			DiskEncryptionKey: generateDiskEncryptionKey(disk.EncryptionKey, r.projectID),
+			DiskEncryptionKey: encryptionKey,
 		})
 	}
 	instance.Disks = disks
@@ -352,10 +420,10 @@ func (r *Reconciler) create() error {
 	// networking
 	var networkInterfaces = []*compute.NetworkInterface{}
 
-	for _, nic := range r.providerSpec.NetworkInterfaces {
+	for i, nic := range r.providerSpec.NetworkInterfaces {
 		accessConfigs := []*compute.AccessConfig{}
-		if nic.PublicIP {
-			accessConfigs = append(accessConfigs, &compute.AccessConfig{})
+		if nic.PublicIP || nic.ExternalAddress != nil {
+			accessConfigs = append(accessConfigs, &compute.AccessConfig{NetworkTier: networkTier(nic)})
 		}
 		computeNIC := &compute.NetworkInterface{
 			AccessConfigs: accessConfigs,
@@ -370,9 +438,35 @@ func (r *Reconciler) create() error {
 		if len(nic.Subnetwork) != 0 {
 			computeNIC.Subnetwork = fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", projectID, r.providerSpec.Region, nic.Subnetwork)
 		}
+		if err := setNetworkInterfaceStack(nic, computeNIC, subnetworks[i]); err != nil {
+			return err
+		}
+		for _, aliasRange := range nic.AliasIPRanges {
+			computeNIC.AliasIpRanges = append(computeNIC.AliasIpRanges, &compute.AliasIpRange{
+				IpCidrRange:         aliasRange.IPCIDRRange,
+				SubnetworkRangeName: aliasRange.SubnetworkRangeName,
+			})
+		}
+		if nic.InternalAddress != nil {
+			address, err := r.ensureStaticAddress(i, nic.InternalAddress, addressTypeInternal, computeNIC.Subnetwork, "")
+			if err != nil {
+				return err
+			}
+			computeNIC.NetworkIP = address
+		}
+		if nic.ExternalAddress != nil {
+			address, err := r.ensureStaticAddress(i, nic.ExternalAddress, addressTypeExternal, "", networkTier(nic))
+			if err != nil {
+				return err
+			}
+			computeNIC.AccessConfigs[0].NatIP = address
+		}
 		networkInterfaces = append(networkInterfaces, computeNIC)
 	}
 	instance.NetworkInterfaces = networkInterfaces
+	if err := r.setNetworkPerformanceConfig(instance, bootImage); err != nil {
+		return err
+	}
 
 	// serviceAccounts
 	var serviceAccounts = []*compute.ServiceAccount{}
@@ -420,21 +514,26 @@ func (r *Reconciler) create() error {
 		Items: metadataItems,
 	}
 
-	_, err = r.computeService.InstancesInsert(r.projectID, zone, instance)
+	sourceInstanceTemplate, err := r.applyInstanceTemplate(instance)
 	if err != nil {
-		metrics.RegisterFailedInstanceCreate(&metrics.MachineLabels{
-			Name:      r.machine.Name,
-			Namespace: r.machine.Namespace,
-			Reason:    "failed to create instance via compute service",
-		})
-		if reconcileWithCloudError := r.reconcileMachineWithCloudState(&metav1.Condition{
-			Type:    string(machinev1.MachineCreated),
-			Reason:  machineCreationFailedReason,
-			Message: err.Error(),
-			Status:  metav1.ConditionFalse,
-		}); reconcileWithCloudError != nil {
-			klog.Errorf("Failed to reconcile machine with cloud state: %v", reconcileWithCloudError)
-		}
+		return err
+	}
+
+	if r.providerSpec.BulkInsert != nil {
+		return r.bulkInsert(instance, sourceInstanceTemplate)
+	}
+
+	if r.providerSpec.ManagedInstanceGroup != nil {
+		return r.createManagedInstance(instance, sourceInstanceTemplate)
+	}
+
+	if sourceInstanceTemplate != "" {
+		_, err = r.computeService.InstancesInsertFromTemplate(r.projectID, zone, instance, sourceInstanceTemplate)
+	} else {
+		_, err = r.computeService.InstancesInsert(r.projectID, zone, instance)
+	}
+	if err != nil {
+		r.reportCreationFailure(err)
 		if googleError, ok := err.(*googleapi.Error); ok {
 			// we return InvalidMachineConfiguration for 4xx errors which by convention signal client misconfiguration
 			// https://tools.ietf.org/html/rfc2616#section-6.1.1
@@ -448,22 +547,63 @@ func (r *Reconciler) create() error {
 	return r.reconcileMachineWithCloudState(nil)
 }
 
+// reportCreationFailure records the failure to create the instance in the metrics and the machine conditions.
+func (r *Reconciler) reportCreationFailure(err error) {
+	metrics.RegisterFailedInstanceCreate(&metrics.MachineLabels{
+		Name:      r.machine.Name,
+		Namespace: r.machine.Namespace,
+		Reason:    "failed to create instance via compute service",
+	})
+	if reconcileWithCloudError := r.reconcileMachineWithCloudState(&metav1.Condition{
+		Type:    string(machinev1.MachineCreated),
+		Reason:  machineCreationFailedReason,
+		Message: err.Error(),
+		Status:  metav1.ConditionFalse,
+	}); reconcileWithCloudError != nil {
+		klog.Errorf("Failed to reconcile machine with cloud state: %v", reconcileWithCloudError)
+	}
+}
+
 func (r *Reconciler) update() error {
 	if err := validateMachine(*r.machine, *r.providerSpec); err != nil {
 		return machinecontroller.InvalidMachineConfiguration("failed validating machine provider spec: %v", err)
 	}
 
+	// Wait for the operations started by the previous reconciliation
+	if err := r.checkPendingOperations(); err != nil {
+		return err
+	}
+
 	// Add target pools, if necessary
 	if err := r.processTargetPools(true, r.addInstanceToTargetPool); err != nil {
 		return err
 	}
 
-	// Add control plane machines to instance group, if necessary
-	if r.machineScope.machine.ObjectMeta.Labels[openshiftMachineRoleLabel] == masterMachineRole {
-		if err := r.registerInstanceToControlPlaneInstanceGroup(); err != nil {
-			return fmt.Errorf("failed to register instance to instance group: %v", err)
+	// Add network endpoint groups, if necessary
+	if err := r.processNetworkEndpointGroups(true); err != nil {
+		return err
+	}
+
+	// Attach resource policies to existing disks, if necessary
+	if err := r.reconcileDiskResourcePolicies(); err != nil {
+		return err
+	}
+
+	// Add missing alias IP ranges to the network interfaces, if necessary
+	if err := r.reconcileAliasIPRanges(); err != nil {
+		return err
+	}
+
+	// Add the machine to the instance groups of its load balancers, if necessary
+	for _, attachment := range r.instanceGroupAttachments() {
+		if err := r.registerInstanceToInstanceGroup(attachment); err != nil {
+			return fmt.Errorf("failed to register instance to instance group: %w", err)
 		}
 	}
+
+	// Report whether the load balancer considers control plane machines healthy
+	r.reconcileBackendHealth()
+
 	return r.reconcileMachineWithCloudState(nil)
 }
 
@@ -484,11 +624,15 @@ func (r *Reconciler) reconcileMachineWithCloudState(failedCondition *metav1.Cond
 		if len(freshInstance.NetworkInterfaces) < 1 {
 			return fmt.Errorf("could not find network interfaces for instance %q", freshInstance.Name)
 		}
-		networkInterface := freshInstance.NetworkInterfaces[0]
 
-		nodeAddresses := []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: networkInterface.NetworkIP}}
-		for _, config := range networkInterface.AccessConfigs {
-			nodeAddresses = append(nodeAddresses, corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: config.NatIP})
+		nodeAddresses := instanceNodeAddresses(freshInstance)
+		// The custom hostname is the name the node registers with, report it first so that
+		// its certificate signing requests can be approved, ahead of the default internal DNS names.
+		if freshInstance.Hostname != "" {
+			nodeAddresses = append(nodeAddresses, corev1.NodeAddress{
+				Type:    corev1.NodeInternalDNS,
+				Address: freshInstance.Hostname,
+			})
 		}
 		// Since we don't know when the project was created, we must account for
 		// both types of internal-dns:
@@ -513,8 +657,17 @@ func (r *Reconciler) reconcileMachineWithCloudState(failedCondition *metav1.Cond
 
 		r.machine.Spec.ProviderID = &r.providerID
 		r.machine.Status.Addresses = nodeAddresses
+		// The addresses are set first, the records of the machine are not required for it to join the cluster
+		if dnsRecordName := r.registerDNSRecords(freshInstance); dnsRecordName != "" {
+			r.machine.Status.Addresses = append(r.machine.Status.Addresses, corev1.NodeAddress{
+				Type:    corev1.NodeInternalDNS,
+				Address: dnsRecordName,
+			})
+		}
 		r.providerStatus.InstanceState = &freshInstance.Status
 		r.providerStatus.InstanceID = &freshInstance.Name
+		r.providerStatus.AliasIPRanges = aliasIPRangeStatus(freshInstance)
+		r.providerStatus.ConsumedReservation = consumedReservation(freshInstance)
 		succeedCondition := metav1.Condition{
 			Type:    string(machinev1.MachineCreated),
 			Reason:  machineCreationSucceedReason,
@@ -547,6 +700,10 @@ func (r *Reconciler) setMachineCloudProviderSpecifics(instance *compute.Instance
 	// TODO(jchaloup): detect all three from instance rather than
 	// always assuming it's the same as what is specified in the provider spec
 	r.machine.Labels[machinecontroller.MachineInstanceTypeLabelName] = r.providerSpec.MachineType
+	if r.providerSpec.MachineType == "" {
+		// The machine type of the source instance template
+		r.machine.Labels[machinecontroller.MachineInstanceTypeLabelName] = path.Base(instance.MachineType)
+	}
 	r.machine.Labels[machinecontroller.MachineRegionLabelName] = r.providerSpec.Region
 	r.machine.Labels[machinecontroller.MachineAZLabelName] = r.providerSpec.Zone
 
@@ -609,6 +766,72 @@ func validateMachine(machine machinev1.Machine, providerSpec machinev1.GCPMachin
 		return machinecontroller.InvalidMachineConfiguration("preemptible cannot be used together with 'Spot' provisioning model")
 	}
 
+	for _, nic := range providerSpec.NetworkInterfaces {
+		if err := validateNetworkInterfaceStack(nic); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+		if err := validateAliasIPRanges(nic); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+		if err := validateStaticAddresses(nic); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+		if err := validateNetworkInterfaceOptions(nic); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+	}
+	if err := validateNetworkPerformanceConfig(providerSpec); err != nil {
+		return machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+	if _, err := instanceHostname(machine, providerSpec); err != nil {
+		return machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+
+	for _, affinity := range providerSpec.NodeAffinities {
+		if err := validateNodeAffinity(affinity); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+	}
+
+	if err := validateReservationAffinity(providerSpec.ReservationAffinity); err != nil {
+		return machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+
+	if err := validateBulkInsert(machine, providerSpec); err != nil {
+		return machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+
+	if err := validateManagedInstanceGroup(machine, providerSpec); err != nil {
+		return machinecontroller.InvalidMachineConfiguration("%v", err)
+	}
+
+	if providerSpec.PlacementPolicy != nil {
+		if _, err := placementPolicyName(&machine, providerSpec.PlacementPolicy); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+	}
+
+	for _, neg := range providerSpec.NetworkEndpointGroups {
+		if err := validateNetworkEndpointGroupAttachment(neg); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+	}
+
+	for _, attachment := range providerSpec.LoadBalancers {
+		if err := validateLoadBalancerAttachment(attachment); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+	}
+
+	for _, disk := range providerSpec.Disks {
+		if err := validateDiskDeletionPolicy(disk.DeletionPolicy); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+		if err := validateDiskEncryptionKey(disk.EncryptionKey); err != nil {
+			return machinecontroller.InvalidMachineConfiguration("%v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -654,11 +877,21 @@ func (r *Reconciler) exists() (bool, error) {
 
 // Returns true if machine exists.
 func (r *Reconciler) delete() error {
+	// Wait for the operations started by the previous reconciliation
+	if err := r.checkPendingOperations(); err != nil {
+		return err
+	}
+
 	// Remove instance from target pools, if necessary
 	if err := r.processTargetPools(false, r.deleteInstanceFromTargetPool); err != nil {
 		return err
 	}
 
+	// Remove instance from network endpoint groups, if necessary
+	if err := r.processNetworkEndpointGroups(false); err != nil {
+		return err
+	}
+
 	// Make sure that the machine exists.
 	// Also check that we have a machine with valid configuration.
 	exists, err := r.exists()
@@ -669,18 +902,68 @@ func (r *Reconciler) delete() error {
 		return err
 	}
 	if !exists {
-		klog.Infof("%s: Machine not found during delete, skipping", r.machine.Name)
-		return nil
+		// The managed instance group may still be creating the instance, or not be done deleting it
+		if r.providerSpec.ManagedInstanceGroup != nil {
+			managed, err := r.deleteManagedInstance()
+			if err != nil {
+				return err
+			}
+			if managed {
+				klog.Infof("%s: instance is still managed by its managed instance group, requeuing...", r.machine.Name)
+				return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
+			}
+		}
+		klog.Infof("%s: Machine not found during delete, releasing its resources", r.machine.Name)
+		return r.releaseInstanceResources()
+	}
+
+	// Remove control plane instances from their instance group right away, rather than waiting for the
+	// control plane controller, so that the load balancer starts draining the connections
+	if r.machine.Labels[openshiftMachineRoleLabel] == masterMachineRole {
+		if err := r.unregisterInstanceFromInstanceGroup(r.controlPlaneGroupName()); err != nil {
+			return fmt.Errorf("%s: failed to unregister instance from instance group: %v", r.machine.Name, err)
+		}
 	}
 
-	// Remove instance from instance group, if necessary
-	if r.machineScope.machine.Labels[openshiftMachineRoleLabel] == masterMachineRole {
-		if err := r.unregisterInstanceFromControlPlaneInstanceGroup(); err != nil {
+	// Remove instance from the instance groups of its load balancers, if necessary
+	for _, attachment := range r.instanceGroupAttachments() {
+		if err := r.unregisterInstanceFromInstanceGroup(attachment.instanceGroup); err != nil {
 			return fmt.Errorf("%s: failed to unregister instance from instance group: %v", r.machine.Name, err)
 		}
 	}
 
-	if _, err = r.computeService.InstancesDelete(string(r.machine.UID), r.projectID, r.providerSpec.Zone, r.machine.Name); err != nil {
+	// Let the load balancer drain the connections to control plane machines
+	if err := r.waitForBackendDrain(); err != nil {
+		return err
+	}
+
+	instance, err := r.computeService.InstancesGet(r.projectID, r.providerSpec.Zone, r.machine.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get instance via compute service: %v", err)
+	}
+
+	// Snapshot, retain or delete the disks according to their deletion policy
+	if err := r.reconcileDisksBeforeDelete(instance); err != nil {
+		return err
+	}
+
+	// The resources of the instance are released by a later reconciliation, once the instance is gone
+	managed := false
+	if r.providerSpec.ManagedInstanceGroup != nil {
+		// The instance of a managed instance group is deleted through the group, which would recreate it otherwise
+		managed, err = r.deleteManagedInstance()
+	}
+	if !managed && err == nil {
+		var op *compute.Operation
+		op, err = r.computeService.InstancesDelete(string(r.machine.UID), r.projectID, r.providerSpec.Zone, r.machine.Name)
+		if err == nil {
+			err = r.awaitZoneOperation(op)
+		}
+	}
+	if isRequeueAfterError(err) {
+		return err
+	}
+	if err != nil {
 		metrics.RegisterFailedInstanceDelete(&metrics.MachineLabels{
 			Name:      r.machine.Name,
 			Namespace: r.machine.Namespace,
@@ -692,6 +975,22 @@ func (r *Reconciler) delete() error {
 	return &machinecontroller.RequeueAfterError{RequeueAfter: requeueAfterSeconds * time.Second}
 }
 
+// releaseInstanceResources removes the DNS records and releases the static addresses, the placement policy
+// and the managed instance group of the machine once its instance is gone. Each step returns an error or a
+// RequeueAfterError until its resource is released, so that the machine keeps its finalizer until then.
+func (r *Reconciler) releaseInstanceResources() error {
+	if err := r.deleteDNSRecords(); err != nil {
+		return err
+	}
+	if err := r.releaseStaticAddresses(); err != nil {
+		return err
+	}
+	if err := r.releasePlacementPolicy(); err != nil {
+		return err
+	}
+	return r.releaseManagedInstanceGroup()
+}
+
 func isNotFoundError(err error) bool {
 	switch t := err.(type) {
 	case *googleapi.Error:
@@ -700,6 +999,14 @@ func isNotFoundError(err error) bool {
 	return false
 }
 
+func isForbiddenError(err error) bool {
+	switch t := err.(type) {
+	case *googleapi.Error:
+		return t.Code == 403
+	}
+	return false
+}
+
 func isProjectNotFoundError(err error, projectID string) bool {
 	switch t := err.(type) {
 	case *googleapi.Error:
@@ -768,122 +1075,121 @@ func (r *Reconciler) processTargetPools(desired bool, poolFunc poolProcessor) er
 	return nil
 }
 
-// ensureInstanceGroup ensures that the instance group exists.
+// ensureInstanceGroup ensures that the instance group of the attachment exists.
 // If the instance group doesn't exist, we try and register it and also assign
 // it to a backend service correctly.
-func (r *Reconciler) ensureInstanceGroup(instanceGroupName string) error {
+func (r *Reconciler) ensureInstanceGroup(attachment instanceGroupAttachment) error {
+	instanceGroupName := attachment.instanceGroup
+
 	// Get an instance group so we can check that it does in fact exist
 	_, err := r.computeService.InstanceGroupGet(r.projectID, r.providerSpec.Zone, instanceGroupName)
 	if isNotFoundError(err) {
 		// Handle the creation of a new instance group
-		if err := r.registerNewInstanceGroup(); err != nil {
-			return fmt.Errorf("failed to register the new instance group named %s: %v", instanceGroupName, err)
+		if err := r.registerNewInstanceGroup(instanceGroupName); err != nil {
+			return fmt.Errorf("failed to register the new instance group named %s: %w", instanceGroupName, err)
 		}
 	} else if err != nil {
 		return fmt.Errorf("instanceGroupGet request failed: %v", err)
 	}
 
-	registered, err := r.checkRegistrationOfBackend()
+	registered, err := r.checkRegistrationOfBackend(attachment)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve the backend service: %v", err)
 	}
 
 	if !registered {
 		// Handle the registration of backend to backend service
-		if err := r.updateBackendServiceWithInstanceGroup(); err != nil {
-			return fmt.Errorf("failed to update the backend service with new instance group %s: %v", instanceGroupName, err)
+		if err := r.updateBackendServiceWithInstanceGroup(attachment); err != nil {
+			return fmt.Errorf("failed to update the backend service with new instance group %s: %w", instanceGroupName, err)
 		}
 	}
 
 	return nil
 }
 
-// ensureCorrectNetworkAndSubnetName makes sure that we are working with the correct network and subnet.
-// In a case of VPC, we have to look whether the expect name of the network and subnet resource
-// matches the one, that is actually up in the cluster.
-func (r *Reconciler) ensureCorrectNetworkAndSubnetName() (string, string) {
-	actualNetworkName := fmt.Sprintf("%s-network", r.machine.Labels[machinev1.MachineClusterIDLabel])
-	actualSubnetworkName := fmt.Sprintf("%s-%s-subnet", r.machine.Labels[machinev1.MachineClusterIDLabel], r.machineScope.machine.ObjectMeta.Labels[openshiftMachineRoleLabel])
-
-	for _, network := range r.providerSpec.NetworkInterfaces {
-		if network.Network == actualNetworkName && network.Subnetwork == actualSubnetworkName {
-			return actualNetworkName, actualSubnetworkName
+// registerNewInstanceGroup registers an instance group when there is an instance
+// that is using that unkown instance group. Instances can only be members of the instance
+// groups of the network of their first network interface, which may be in a Shared VPC host project.
+func (r *Reconciler) registerNewInstanceGroup(instanceGroupName string) error {
+	instanceGroup := &compute.InstanceGroup{
+		Name:   instanceGroupName,
+		Region: r.providerSpec.Region,
+		Zone:   r.providerSpec.Zone,
+	}
+	if len(r.providerSpec.NetworkInterfaces) > 0 {
+		nic := r.providerSpec.NetworkInterfaces[0]
+		networkProjectID := nic.ProjectID
+		if networkProjectID == "" {
+			networkProjectID = r.projectID
 		}
+		instanceGroup.Network = r.instanceGroupNetworkName(networkProjectID, nic.Network)
+		instanceGroup.Subnetwork = r.instanceGroupSubNetworkName(networkProjectID, nic.Subnetwork)
 	}
-	actualNetworkName = r.providerSpec.NetworkInterfaces[0].Network
-	actualSubnetworkName = r.providerSpec.NetworkInterfaces[0].Subnetwork
-
-	return actualNetworkName, actualSubnetworkName
-}
 
-// registerNewInstanceGroup registers an instance group when there is an instance
-// that is using that unkown instance group.
-func (r *Reconciler) registerNewInstanceGroup() error {
-	actualNetworkName, actualSubnetworkName := r.ensureCorrectNetworkAndSubnetName()
-
-	_, err := r.computeService.InstanceGroupInsert(r.projectID, r.providerSpec.Zone, &compute.InstanceGroup{
-		Name:       r.controlPlaneGroupName(),
-		Region:     r.providerSpec.Region,
-		Zone:       r.providerSpec.Zone,
-		Network:    r.instanceGroupNetworkName(actualNetworkName),
-		Subnetwork: r.instanceGroupSubNetworkName(actualSubnetworkName),
-	})
+	op, err := r.computeService.InstanceGroupInsert(r.projectID, r.providerSpec.Zone, instanceGroup)
 	if err != nil {
 		return fmt.Errorf("instanceGroupInsert request failed: %w", err)
 	}
 
-	return nil
+	// The instance group can only get members and become a backend once it has been created
+	return r.awaitZoneOperation(op)
 }
 
 // ensureInstanceGroupInBackendService checks whether an instancegroup is assigned to a backend service.
-func (r *Reconciler) checkRegistrationOfBackend() (bool, error) {
-	backendService, err := r.computeService.BackendServiceGet(r.projectID, r.providerSpec.Region, r.backendServiceName())
+func (r *Reconciler) checkRegistrationOfBackend(attachment instanceGroupAttachment) (bool, error) {
+	backendService, err := r.getBackendService(attachment)
 	if err != nil {
 		return false, fmt.Errorf("backendServiceGet request failed: %v", err)
 	}
 
-	for _, backend := range backendService.Backends {
-		if backend.Group == r.FQDNInstanceGroup() {
-			return true, nil
-		}
-	}
-
-	// We didn't find the backend, but will try
+	// If we don't find the backend, we will try
 	// and patch it to the backend service
-	return false, nil
+	return hasBackend(backendService, r.FQDNInstanceGroup(attachment.instanceGroup)), nil
 }
 
 // updateBackendServiceWithInstanceGroup patches a backend service the newly created instance group.
-func (r *Reconciler) updateBackendServiceWithInstanceGroup() error {
-	backendServiceName := r.backendServiceName()
-
-	backendService, err := r.computeService.BackendServiceGet(r.projectID, r.providerSpec.Region, backendServiceName)
-	if err != nil {
-		return fmt.Errorf("backendServiceGet request failed: %v", err)
-	}
-
-	// Create backend that serves the backend service
-	backend := &compute.Backend{
-		BalancingMode: "CONNECTION",
-		Group:         r.FQDNInstanceGroup(),
-	}
-	backendService.Backends = append(backendService.Backends, backend)
+// The patch is serialized with the other changes of the backend service in the manager and retried
+// when the backend service was modified since it was read.
+func (r *Reconciler) updateBackendServiceWithInstanceGroup(attachment instanceGroupAttachment) error {
+	region := r.providerSpec.Region
+	if attachment.global {
+		region = ""
+	}
+
+	group := r.FQDNInstanceGroup(attachment.instanceGroup)
+	op, err := util.PatchBackendService(r.computeService, r.projectID, region, attachment.backendService, func(backendService *compute.BackendService) *compute.BackendService {
+		// The instance group may have been added while waiting for the lock
+		if hasBackend(backendService, group) {
+			return nil
+		}
 
-	_, err = r.computeService.AddInstanceGroupToBackendService(r.projectID, r.providerSpec.Region, backendServiceName, backendService)
+		// Create backend that serves the backend service
+		backend := &compute.Backend{
+			BalancingMode: attachment.balancingMode,
+			Group:         group,
+		}
+		return &compute.BackendService{
+			Backends: append(backendService.Backends, backend),
+		}
+	})
 	if err != nil {
 		return fmt.Errorf("addInstanceGroupToBackendService request failed: %v", err)
 	}
 
-	return nil
+	// A patch which is not applied yet requeues the machine, the next change reads the new fingerprint once it is
+	if attachment.global {
+		return r.awaitGlobalOperation(op)
+	}
+	return r.awaitRegionOperation(op)
 }
 
-// registerInstanceToControlPlaneInstanceGroup ensures that the instance is assigned to the control plane instance group of its zone.
-func (r *Reconciler) registerInstanceToControlPlaneInstanceGroup() error {
+// registerInstanceToInstanceGroup ensures that the instance is assigned to the instance group of the attachment.
+func (r *Reconciler) registerInstanceToInstanceGroup(attachment instanceGroupAttachment) error {
 	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.machine.Name)
-	instanceGroupName := r.controlPlaneGroupName()
+	instanceGroupName := attachment.instanceGroup
 
-	if err := r.ensureInstanceGroup(instanceGroupName); err != nil {
-		return fmt.Errorf("failed to ensure that instance group %s is a proper instance group: %v", instanceGroupName, err)
+	if err := r.ensureInstanceGroup(attachment); err != nil {
+		return fmt.Errorf("failed to ensure that instance group %s is a proper instance group: %w", instanceGroupName, err)
 	}
 
 	instanceSets, err := r.fetchRunningInstancesInInstanceGroup(r.projectID, r.providerSpec.Zone, instanceGroupName)
@@ -906,10 +1212,9 @@ func (r *Reconciler) registerInstanceToControlPlaneInstanceGroup() error {
 	return nil
 }
 
-// unregisterInstanceFromControlPlaneInstanceGroup ensures that the instance is removed from the control plane instance group.
-func (r *Reconciler) unregisterInstanceFromControlPlaneInstanceGroup() error {
+// unregisterInstanceFromInstanceGroup ensures that the instance is removed from the instance group.
+func (r *Reconciler) unregisterInstanceFromInstanceGroup(instanceGroupName string) error {
 	instanceSelfLink := fmtInstanceSelfLink(r.projectID, r.providerSpec.Zone, r.machine.Name)
-	instanceGroupName := r.controlPlaneGroupName()
 
 	instanceSets, err := r.fetchRunningInstancesInInstanceGroup(r.projectID, r.providerSpec.Zone, instanceGroupName)
 	if err != nil {
@@ -952,8 +1257,8 @@ func (r *Reconciler) fetchRunningInstancesInInstanceGroup(projectID string, zone
 
 // FQDNInstanceGroup generates a FQDN for our instance group.
 // It is neccessary for the addition of the instance group to the backend service.
-func (r *Reconciler) FQDNInstanceGroup() string {
-	return fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/zones/%s/instanceGroups/%s", r.projectID, r.providerSpec.Zone, r.controlPlaneGroupName())
+func (r *Reconciler) FQDNInstanceGroup(instanceGroupName string) string {
+	return fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/zones/%s/instanceGroups/%s", r.projectID, r.providerSpec.Zone, instanceGroupName)
 }
 
 // backendServiceName generates the name of a cluster's backend service
@@ -962,13 +1267,13 @@ func (r *Reconciler) backendServiceName() string {
 }
 
 // instanceGroupNetworkName generates the name of a instance groups' network
-func (r *Reconciler) instanceGroupNetworkName(networkName string) string {
-	return fmt.Sprintf("projects/%s/global/networks/%s", r.projectID, networkName)
+func (r *Reconciler) instanceGroupNetworkName(projectID, networkName string) string {
+	return fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
 }
 
 // instanceGroupSubNetworkName generates the name of a instance groups' subnetwork
-func (r *Reconciler) instanceGroupSubNetworkName(subnetworkName string) string {
-	return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", r.projectID, r.providerSpec.Region, subnetworkName)
+func (r *Reconciler) instanceGroupSubNetworkName(projectID, subnetworkName string) string {
+	return fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", projectID, r.providerSpec.Region, subnetworkName)
 }
 
 // ControlPlaneGroupName generates the name of the instance group that this instace should belong to.
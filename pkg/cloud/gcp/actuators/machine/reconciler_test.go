@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -13,7 +14,10 @@ import (
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
 	tagservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/tags"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	cloudkms "google.golang.org/api/cloudkms/v1"
 	tags "google.golang.org/api/cloudresourcemanager/v3"
 	compute "google.golang.org/api/compute/v1"
 	googleapi "google.golang.org/api/googleapi"
@@ -35,6 +39,14 @@ func TestCreate(t *testing.T) {
 		mockGPUCompatibleMachineTypesList func(project string, zone string, ctx context.Context) (map[string]computeservice.GpuInfo, []string)
 		mockInstancesInsert               func(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
 		mockRegionGet                     func(project string, region string) (*compute.Region, error)
+		mockResourcePoliciesGet           func(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error)
+		mockSubnetworksGet                func(project string, region string, subnetwork string) (*compute.Subnetwork, error)
+		mockNetworksGet                   func(project string, network string) (*compute.Network, error)
+		mockSubnetworksTestIamPermissions func(project string, region string, subnetwork string, permissions []string) ([]string, error)
+		mockNodeGroupsList                func(ctx context.Context, project string, zone string) ([]*compute.NodeGroup, error)
+		mockCryptoKeysGet                 func(ctx context.Context, name string) (*cloudkms.CryptoKey, error)
+		mockKeyRingsGetIamPolicy          func(ctx context.Context, resource string) (*cloudkms.Policy, error)
+		mockInstanceGroupManagersGet      func(project string, zone string, instanceGroupManager string) (*compute.InstanceGroupManager, error)
 		validateInstance                  func(t *testing.T, instance *compute.Instance)
 		expectedError                     error
 	}{
@@ -331,6 +343,16 @@ func TestCreate(t *testing.T) {
 					},
 				},
 			},
+			mockKeyRingsGetIamPolicy: func(ctx context.Context, resource string) (*cloudkms.Policy, error) {
+				return &cloudkms.Policy{
+					Bindings: []*cloudkms.Binding{
+						{
+							Role:    "roles/cloudkms.cryptoKeyEncrypterDecrypter",
+							Members: []string{"serviceAccount:kms-key-service-account"},
+						},
+					},
+				}, nil
+			},
 			validateInstance: func(t *testing.T, instance *compute.Instance) {
 				if len(instance.Disks) != 1 {
 					t.Errorf("expected one disk, got %d", len(instance.Disks))
@@ -427,6 +449,11 @@ func TestCreate(t *testing.T) {
 				if instance.Disks[1].InitializeParams.DiskSizeGb != 100 {
 					t.Errorf("Expected disk size 100, got: %d", instance.Disks[1].InitializeParams.DiskSizeGb)
 				}
+				for i, disk := range instance.Disks {
+					if expected := fmt.Sprintf("machine-disk-%d", i); disk.DeviceName != expected {
+						t.Errorf("Expected device name %s of disk %d, got: %s", expected, i, disk.DeviceName)
+					}
+				}
 			},
 		},
 		{
@@ -637,6 +664,26 @@ func TestCreate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "shieldedInstanceConfig with an image that can not be read",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/errImageNotFound/global/images/fooimage",
+					},
+				},
+				Region:                 "test-region",
+				Zone:                   "test-zone",
+				MachineType:            "n1-test-machineType",
+				ShieldedInstanceConfig: machinev1.GCPShieldedInstanceConfig{SecureBoot: machinev1.SecureBootPolicyEnabled},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.ShieldedInstanceConfig.EnableSecureBoot != true {
+					t.Errorf("Expected EnableSecureBoot to be true, Got: %t", instance.ShieldedInstanceConfig.EnableSecureBoot)
+				}
+			},
+		},
 		{
 			name: "shieldedInstanceConfig with vTPM disabled",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
@@ -670,208 +717,1316 @@ func TestCreate(t *testing.T) {
 						Image: "projects/fooproject/global/images/uefi-image",
 					},
 				},
-				ResourceManagerTags: []machinev1.ResourceManagerTag{
+				ResourceManagerTags: []machinev1.ResourceManagerTag{
+					{
+						ParentID: "openshift",
+						Key:      "key1",
+						Value:    "value1",
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
+					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
+				}
+			},
+		},
+		{
+			name: "confidential compute AMDEncryptedVirtualization",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:              "test-region",
+				Zone:                "test-zone",
+				MachineType:         "n2d-standard-4",
+				ConfidentialCompute: machinev1.ConfidentialComputePolicySEV,
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				ResourceManagerTags: []machinev1.ResourceManagerTag{
+					{
+						ParentID: "openshift",
+						Key:      "key1",
+						Value:    "value1",
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
+					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
+				}
+				if instance.ConfidentialInstanceConfig.ConfidentialInstanceType != "SEV" {
+					t.Errorf("Expected ConfidentialInstanceType to be SEV, Got: %s", instance.ConfidentialInstanceConfig.ConfidentialInstanceType)
+				}
+			},
+		},
+		{
+			name: "confidential compute AMDEncryptedVirtualizationNestedPaging",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:              "test-region",
+				Zone:                "test-zone",
+				MachineType:         "n2d-standard-4",
+				ConfidentialCompute: machinev1.ConfidentialComputePolicySEVSNP,
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				ResourceManagerTags: []machinev1.ResourceManagerTag{
+					{
+						ParentID: "openshift",
+						Key:      "key1",
+						Value:    "value1",
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
+					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
+				}
+				if instance.ConfidentialInstanceConfig.ConfidentialInstanceType != "SEV_SNP" {
+					t.Errorf("Expected ConfidentialInstanceType to be SEV_SNP, Got: %s", instance.ConfidentialInstanceConfig.ConfidentialInstanceType)
+				}
+			},
+		},
+		{
+			name: "confidential compute IntelTrustedDomainExtensions",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:              "test-region",
+				Zone:                "test-zone",
+				MachineType:         "c3-standard-4",
+				ConfidentialCompute: machinev1.ConfidentialComputePolicyTDX,
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				ResourceManagerTags: []machinev1.ResourceManagerTag{
+					{
+						ParentID: "openshift",
+						Key:      "key1",
+						Value:    "value1",
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
+					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
+				}
+				if instance.ConfidentialInstanceConfig.ConfidentialInstanceType != "TDX" {
+					t.Errorf("Expected ConfidentialInstanceType to be TDX, Got: %s", instance.ConfidentialInstanceConfig.ConfidentialInstanceType)
+				}
+			},
+		},
+		{
+			name: "failed to fetch resource manager tags",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				ResourceManagerTags: []machinev1.ResourceManagerTag{
+					{
+						ParentID: "openshift",
+						Key:      "key2",
+						Value:    "value2",
+					},
+				},
+			},
+			expectedError: errors.New("failed to fetch user-defined tags for : failed to fetch openshift/key2/value2 tag details: googleapi: Error 500: Internal error while fetching 'openshift/key2/value2'"),
+		},
+		{
+			name: "a3-ultragpu (H200 GPUs) instance create succeeds when quota is not available",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:      "test-region",
+				Zone:        "test-zone",
+				MachineType: "a3-ultragpu-8g",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+			},
+			mockGPUCompatibleMachineTypesList: func(project string, zone string, ctx context.Context) (map[string]computeservice.GpuInfo, []string) {
+				var compatibleMachineType = []string{}
+				var gpuInfo = map[string]computeservice.GpuInfo{
+					"a3-ultragpu-8g": {
+						Type:  "nvidia-h200-141gb",
+						Count: 1,
+					},
+				}
+				return gpuInfo, compatibleMachineType
+			},
+			mockRegionGet: func(project string, region string) (*compute.Region, error) {
+				var computeQuota = &compute.Quota{}
+				var computeRegion = &compute.Region{Quotas: []*compute.Quota{computeQuota}}
+				return computeRegion, nil
+			},
+		},
+		{
+			name: "Create spot instance successfully",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:            "test-region",
+				Zone:              "test-zone",
+				MachineType:       "n1-standard-4",
+				ProvisioningModel: ptr.To(machinev1.GCPSpotInstance),
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expectedProvisioningModel, err := toComputeProvisioningModel(ptr.To(machinev1.GCPSpotInstance))
+				if err != nil {
+					t.Fatalf("failed to get expected provisioning model: %v", err)
+				}
+				if instance.Scheduling.ProvisioningModel != expectedProvisioningModel {
+					t.Errorf("expected ProvisioningModel to be %q, Got: %s", expectedProvisioningModel, instance.Scheduling.ProvisioningModel)
+				}
+			},
+		},
+		{
+			name: "Fail on invalid provisioning model",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:            "test-region",
+				Zone:              "test-zone",
+				ProvisioningModel: ptr.To(machinev1.GCPProvisioningModelType("InvalidModel")),
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: Unsupported provisioning model \"InvalidModel\", Valid value is \"Spot\". Omit provisioningModel for standard."),
+		},
+		{
+			name: "Fail when both preemptible and spot are set",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:            "test-region",
+				Zone:              "test-zone",
+				Preemptible:       true,
+				ProvisioningModel: ptr.To(machinev1.GCPSpotInstance),
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: preemptible cannot be used together with 'Spot' provisioning model"),
+		},
+		{
+			name: "Attach resource policies to disks",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+					{
+						ResourcePolicies: []string{"daily-snapshots"},
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if len(instance.Disks) != 2 {
+					t.Fatalf("expected two disks, got %d", len(instance.Disks))
+				}
+				if instance.Disks[0].InitializeParams.ResourcePolicies != nil {
+					t.Errorf("Expected no resource policies on the boot disk, got: %v", instance.Disks[0].InitializeParams.ResourcePolicies)
+				}
+				expectedPolicy := "projects/project/regions/test-region/resourcePolicies/daily-snapshots"
+				if policies := instance.Disks[1].InitializeParams.ResourcePolicies; len(policies) != 1 || policies[0] != expectedPolicy {
+					t.Errorf("Expected resource policies: %v, got: %v", []string{expectedPolicy}, policies)
+				}
+			},
+		},
+		{
+			name: "Fail when a disk resource policy does not exist",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:             true,
+						Image:            "projects/fooproject/global/images/uefi-image",
+						ResourcePolicies: []string{"missing-policy"},
+					},
+				},
+			},
+			mockResourcePoliciesGet: func(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error) {
+				return nil, &googleapi.Error{Code: 404}
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("resource policy missing-policy not found in region test-region"),
+		},
+		{
+			name: "Fail on unknown disk deletion policy",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:           true,
+						Image:          "projects/fooproject/global/images/uefi-image",
+						DeletionPolicy: "Archive",
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: unsupported disk deletion policy \"Archive\", valid values are \"Delete\", \"Retain\" and \"Snapshot\""),
+		},
+		{
+			name: "Default boot disk size when not set",
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if size := instance.Disks[0].InitializeParams.DiskSizeGb; size != util.DefaultBootDiskSizeGB {
+					t.Errorf("Expected boot disk size %d, got: %d", util.DefaultBootDiskSizeGB, size)
+				}
+			},
+		},
+		{
+			name: "Default boot disk size to the image size when larger",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/" + computeservice.LargeImage,
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if size := instance.Disks[0].InitializeParams.DiskSizeGb; size != 200 {
+					t.Errorf("Expected boot disk size 200, got: %d", size)
+				}
+			},
+		},
+		{
+			name: "Fail when the boot disk is smaller than the image",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:   true,
+						Image:  "projects/fooproject/global/images/" + computeservice.LargeImage,
+						SizeGB: 100,
+					},
+				},
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("invalid boot disk: boot disk size 100GB is smaller than the 200GB required by image \"large-image\""),
+		},
+		{
+			name: "Fail when the image architecture does not match the machine type",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region:      "test-region",
+				Zone:        "test-zone",
+				MachineType: "n2-standard-4",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/" + computeservice.ARM64Image,
+					},
+				},
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("invalid boot disk: image \"arm64-image\" architecture arm64 does not match machine type \"n2-standard-4\" architecture amd64"),
+		},
+		{
+			name: "Encrypt disk with a customer-supplied key from a secret",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+						EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+							CustomerSuppliedKey: &machinev1.GCPCustomerSuppliedKeyReference{
+								SecretRef: corev1.LocalObjectReference{Name: "disk-key"},
+							},
+						},
+					},
+				},
+			},
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "disk-key",
+				},
+				Data: map[string][]byte{
+					csekRSAEncryptedKeySecretKey: []byte("cnNhLWtleQ==\n"),
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				key := instance.Disks[0].DiskEncryptionKey
+				if key == nil || key.RsaEncryptedKey != "cnNhLWtleQ==" || key.RawKey != "" || key.KmsKeyName != "" {
+					t.Errorf("Expected RSA-wrapped customer-supplied key, got: %v", key)
+				}
+			},
+		},
+		{
+			name: "Fail when the customer-supplied key secret has both keys",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+						EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+							CustomerSuppliedKey: &machinev1.GCPCustomerSuppliedKeyReference{
+								SecretRef: corev1.LocalObjectReference{Name: "disk-key"},
+							},
+						},
+					},
+				},
+			},
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "disk-key",
+				},
+				Data: map[string][]byte{
+					csekRawKeySecretKey:          []byte("cmF3LWtleQ=="),
+					csekRSAEncryptedKeySecretKey: []byte("cnNhLWtleQ=="),
+				},
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("secret /disk-key must not have both \"rawKey\" and \"rsaEncryptedKey\" fields set"),
+		},
+		{
+			name: "Fail when the customer-supplied key secret does not exist",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+						EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+							CustomerSuppliedKey: &machinev1.GCPCustomerSuppliedKeyReference{
+								SecretRef: corev1.LocalObjectReference{Name: "disk-key"},
+							},
+						},
+					},
+				},
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("encryption key secret \"disk-key\" in namespace \"\" not found: secrets \"disk-key\" not found"),
+		},
+		{
+			name: "Fail when both a KMS key and a customer-supplied key are set",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				Region: "test-region",
+				Zone:   "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+						EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+							KMSKey: &machinev1.GCPKMSKeyReference{
+								Name:     "key",
+								KeyRing:  "ring",
+								Location: "global",
+							},
+							CustomerSuppliedKey: &machinev1.GCPCustomerSuppliedKeyReference{
+								SecretRef: corev1.LocalObjectReference{Name: "disk-key"},
+							},
+						},
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: kmsKey and customerSuppliedKey are mutually exclusive"),
+		},
+		{
+			name: "Create dual-stack network interface with external IPv6",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:        "network",
+						Subnetwork:     "subnetwork",
+						StackType:      machinev1.NetworkStackTypeIPv4IPv6,
+						IPv6AccessType: machinev1.IPv6AccessTypeExternal,
+					},
+				},
+			},
+			mockSubnetworksGet: func(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+				return &compute.Subnetwork{Name: subnetwork, StackType: "IPV4_IPV6", Ipv6AccessType: "EXTERNAL"}, nil
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				nic := instance.NetworkInterfaces[0]
+				if nic.StackType != "IPV4_IPV6" {
+					t.Errorf("Expected stack type IPV4_IPV6, got: %q", nic.StackType)
+				}
+				if nic.Ipv6AccessType != "EXTERNAL" {
+					t.Errorf("Expected IPv6 access type EXTERNAL, got: %q", nic.Ipv6AccessType)
+				}
+				if len(nic.Ipv6AccessConfigs) != 1 || nic.Ipv6AccessConfigs[0].Type != "DIRECT_IPV6" {
+					t.Errorf("Expected one DIRECT_IPV6 access config, got: %v", nic.Ipv6AccessConfigs)
+				}
+			},
+		},
+		{
+			name: "Fail to create dual-stack network interface on IPv4 only subnetwork",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:    "network",
+						Subnetwork: "subnetwork",
+						StackType:  machinev1.NetworkStackTypeIPv4IPv6,
+					},
+				},
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("subnetwork subnetwork has stack type \"IPV4_ONLY\", it does not support \"IPv4IPv6\" network interfaces"),
+		},
+		{
+			name: "Fail when the IPv6 access type does not match the subnetwork",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:        "network",
+						Subnetwork:     "subnetwork",
+						StackType:      machinev1.NetworkStackTypeIPv4IPv6,
+						IPv6AccessType: machinev1.IPv6AccessTypeInternal,
+					},
+				},
+			},
+			mockSubnetworksGet: func(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+				return &compute.Subnetwork{Name: subnetwork, StackType: "IPV4_IPV6", Ipv6AccessType: "EXTERNAL"}, nil
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("subnetwork subnetwork has IPv6 access type \"EXTERNAL\", it does not support \"Internal\" network interfaces"),
+		},
+		{
+			name: "Fail when the IPv6 access type is set without dual-stack",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:        "network",
+						Subnetwork:     "subnetwork",
+						IPv6AccessType: machinev1.IPv6AccessTypeExternal,
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: network interface IPv6 access type requires the \"IPv4IPv6\" stack type"),
+		},
+		{
+			name: "Create network interface with alias IP ranges",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:    "network",
+						Subnetwork: "subnetwork",
+						AliasIPRanges: []machinev1.GCPAliasIPRange{
+							{IPCIDRRange: "/24", SubnetworkRangeName: "pods"},
+							{IPCIDRRange: "10.0.2.5"},
+						},
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := []*compute.AliasIpRange{
+					{IpCidrRange: "/24", SubnetworkRangeName: "pods"},
+					{IpCidrRange: "10.0.2.5"},
+				}
+				if !reflect.DeepEqual(instance.NetworkInterfaces[0].AliasIpRanges, expected) {
+					t.Errorf("Expected alias IP ranges: %v, got: %v", expected, instance.NetworkInterfaces[0].AliasIpRanges)
+				}
+			},
+		},
+		{
+			name: "Fail when the alias IP range is invalid",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:    "network",
+						Subnetwork: "subnetwork",
+						AliasIPRanges: []machinev1.GCPAliasIPRange{
+							{IPCIDRRange: "/200"},
+						},
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: invalid alias IP range \"/200\": netmask size must be between /0 and /128"),
+		},
+		{
+			name: "Create network interface with static addresses",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:         "network",
+						Subnetwork:      "subnetwork",
+						InternalAddress: &machinev1.GCPStaticAddress{Name: "edge-internal"},
+						ExternalAddress: &machinev1.GCPStaticAddress{Name: "edge-external"},
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				nic := instance.NetworkInterfaces[0]
+				if nic.NetworkIP != "10.0.0.100" {
+					t.Errorf("Expected network IP 10.0.0.100, got: %q", nic.NetworkIP)
+				}
+				if len(nic.AccessConfigs) != 1 || nic.AccessConfigs[0].NatIP != "10.0.0.100" {
+					t.Errorf("Expected one access config with NAT IP 10.0.0.100, got: %v", nic.AccessConfigs)
+				}
+			},
+		},
+		{
+			name: "Fail when the external address specifies an IP address",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:         "network",
+						Subnetwork:      "subnetwork",
+						ExternalAddress: &machinev1.GCPStaticAddress{Address: "35.1.2.3"},
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: external address cannot specify an IP address, it is chosen by GCP"),
+		},
+		{
+			name: "Create gVNIC network interface with Tier_1 networking",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/gvnic-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network:     "network",
+						Subnetwork:  "subnetwork",
+						PublicIP:    true,
+						NICType:     machinev1.NICTypeGVNIC,
+						NetworkTier: machinev1.NetworkTierStandard,
+					},
+				},
+				NetworkPerformanceConfig: &machinev1.GCPNetworkPerformanceConfig{
+					TotalEgressBandwidthTier: machinev1.EgressBandwidthTierTier1,
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				nic := instance.NetworkInterfaces[0]
+				if nic.NicType != "GVNIC" {
+					t.Errorf("Expected NIC type GVNIC, got: %q", nic.NicType)
+				}
+				if len(nic.AccessConfigs) != 1 || nic.AccessConfigs[0].NetworkTier != "STANDARD" {
+					t.Errorf("Expected one access config with the STANDARD network tier, got: %v", nic.AccessConfigs)
+				}
+				if instance.NetworkPerformanceConfig == nil || instance.NetworkPerformanceConfig.TotalEgressBandwidthTier != "TIER_1" {
+					t.Errorf("Expected TIER_1 total egress bandwidth tier, got: %v", instance.NetworkPerformanceConfig)
+				}
+			},
+		},
+		{
+			name: "Fail when the image does not support gVNIC",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network: "network",
+						NICType: machinev1.NICTypeGVNIC,
+					},
+				},
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("image \"uefi-image\" does not support the \"GVNIC\" NIC type"),
+		},
+		{
+			name: "Fail when Tier_1 networking is requested without gVNIC",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						Network: "network",
+					},
+				},
+				NetworkPerformanceConfig: &machinev1.GCPNetworkPerformanceConfig{
+					TotalEgressBandwidthTier: machinev1.EgressBandwidthTierTier1,
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: total egress bandwidth tier \"Tier1\" requires all network interfaces to use the \"GVNIC\" NIC type"),
+		},
+		{
+			name: "Create instance with a custom hostname",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Hostname:  "worker.nodes.example.com",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				if instance.Hostname != "worker.nodes.example.com" {
+					t.Errorf("Expected Hostname: %q, Got: %q", "worker.nodes.example.com", instance.Hostname)
+				}
+			},
+		},
+		{
+			name: "Fail when the custom hostname is not fully qualified",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Hostname:  "worker",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: hostname \"worker\" must be a fully qualified domain name with at least two labels"),
+		},
+		{
+			name: "Fail when the load balancer backend service scope is unknown",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				LoadBalancers: []machinev1.GCPLoadBalancerAttachment{
+					{
+						InstanceGroup:       "ingress",
+						BackendService:      "ingress",
+						BackendServiceScope: "Zonal",
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: unknown backend service scope \"Zonal\", valid values are \"Regional\" and \"Global\""),
+		},
+		{
+			name: "Create instance on a sole-tenant node group",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NodeAffinities: []machinev1.GCPNodeAffinity{
+					{
+						Key:      "compute.googleapis.com/node-group-name",
+						Operator: machinev1.NodeAffinityOperatorIn,
+						Values:   []string{"licensed"},
+					},
+					{
+						Key:      "workload",
+						Operator: machinev1.NodeAffinityOperatorNotIn,
+						Values:   []string{"frontend"},
+					},
+				},
+			},
+			mockNodeGroupsList: func(ctx context.Context, project string, zone string) ([]*compute.NodeGroup, error) {
+				return []*compute.NodeGroup{{Name: "licensed"}}, nil
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := []*compute.SchedulingNodeAffinity{
+					{Key: "compute.googleapis.com/node-group-name", Operator: "IN", Values: []string{"licensed"}},
+					{Key: "workload", Operator: "NOT_IN", Values: []string{"frontend"}},
+				}
+				if !reflect.DeepEqual(instance.Scheduling.NodeAffinities, expected) {
+					t.Errorf("Expected node affinities: %v, Got: %v", expected, instance.Scheduling.NodeAffinities)
+				}
+			},
+		},
+		{
+			name: "Fail when the node affinity operator is unknown",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NodeAffinities: []machinev1.GCPNodeAffinity{
+					{
+						Key:      "compute.googleapis.com/node-group-name",
+						Operator: "Exists",
+						Values:   []string{"licensed"},
+					},
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: unknown operator \"Exists\" of node affinity compute.googleapis.com/node-group-name, valid values are \"In\" and \"NotIn\""),
+		},
+		{
+			name: "Create instance consuming a specific reservation",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				ReservationAffinity: &machinev1.GCPReservationAffinity{
+					ConsumeReservationType: machinev1.ReservationAffinitySpecific,
+					Name:                   "gpus",
+				},
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := &compute.ReservationAffinity{
+					ConsumeReservationType: "SPECIFIC_RESERVATION",
+					Key:                    "compute.googleapis.com/reservation-name",
+					Values:                 []string{"gpus"},
+				}
+				if !reflect.DeepEqual(instance.ReservationAffinity, expected) {
+					t.Errorf("Expected reservation affinity: %v, Got: %v", expected, instance.ReservationAffinity)
+				}
+			},
+		},
+		{
+			name: "Fail when a reservation is named without the specific reservation affinity",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				ReservationAffinity: &machinev1.GCPReservationAffinity{
+					ConsumeReservationType: machinev1.ReservationAffinityAny,
+					Name:                   "gpus",
+				},
+			},
+			expectedError: errors.New("failed validating machine provider spec: reservation name and project can only be set with the \"Specific\" reservation affinity"),
+		},
+		{
+			name: "Create instance in a placement policy",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				PlacementPolicy: &machinev1.GCPPlacementPolicy{
+					Name: "hpc",
+				},
+			},
+			mockResourcePoliciesGet: func(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error) {
+				return &compute.ResourcePolicy{
+					Name:                 resourcePolicy,
+					GroupPlacementPolicy: &compute.ResourcePolicyGroupPlacementPolicy{Collocation: "COLLOCATED"},
+				}, nil
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := []string{"projects/project/regions/test-region/resourcePolicies/hpc"}
+				if !reflect.DeepEqual(instance.ResourcePolicies, expected) {
+					t.Errorf("Expected resource policies: %v, Got: %v", expected, instance.ResourcePolicies)
+				}
+			},
+		},
+		{
+			name: "Fail when the placement policy of a machine without machine set is not named",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				PlacementPolicy: &machinev1.GCPPlacementPolicy{},
+			},
+			expectedError: errors.New("failed validating machine provider spec: placement policy name is required for machines that are not part of a machine set"),
+		},
+		{
+			name: "Fail when instances of a machine without machine set are created in bulk",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				BulkInsert: &machinev1.GCPBulkInsert{},
+			},
+			expectedError: errors.New("failed validating machine provider spec: bulk insert is only supported for machines that are part of a machine set"),
+		},
+		{
+			name: "Create network interface on a Shared VPC subnetwork",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						ProjectID:  "host-project",
+						Network:    "network",
+						Subnetwork: "subnetwork",
+					},
+				},
+			},
+			mockSubnetworksGet: func(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+				if project != "host-project" {
+					return nil, &googleapi.Error{Code: 404}
+				}
+				return &compute.Subnetwork{
+					Name:    subnetwork,
+					Region:  "https://www.googleapis.com/compute/v1/projects/host-project/regions/test-region",
+					Network: "https://www.googleapis.com/compute/v1/projects/host-project/global/networks/network",
+				}, nil
+			},
+			validateInstance: func(t *testing.T, instance *compute.Instance) {
+				expected := "projects/host-project/regions/test-region/subnetworks/subnetwork"
+				if instance.NetworkInterfaces[0].Subnetwork != expected {
+					t.Errorf("Expected subnetwork %s, got: %s", expected, instance.NetworkInterfaces[0].Subnetwork)
+				}
+			},
+		},
+		{
+			name: "Fail when the network does not exist",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
+					{
+						ProjectID:  "host-project",
+						Network:    "network",
+						Subnetwork: "subnetwork",
+					},
+				},
+			},
+			mockNetworksGet: func(project string, network string) (*compute.Network, error) {
+				return nil, &googleapi.Error{Code: 404}
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("network network not found in project host-project"),
+		},
+		{
+			name: "Fail when the subnetwork belongs to another network",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
+					{
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+					},
+				},
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
 					{
-						ParentID: "openshift",
-						Key:      "key1",
-						Value:    "value1",
+						ProjectID:  "host-project",
+						Network:    "network",
+						Subnetwork: "subnetwork",
 					},
 				},
 			},
-			validateInstance: func(t *testing.T, instance *compute.Instance) {
-				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
-					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
-				}
+			mockSubnetworksGet: func(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+				return &compute.Subnetwork{
+					Name:    subnetwork,
+					Region:  "https://www.googleapis.com/compute/v1/projects/host-project/regions/test-region",
+					Network: "https://www.googleapis.com/compute/v1/projects/host-project/global/networks/other-network",
+				}, nil
 			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("subnetwork subnetwork belongs to network other-network, not network"),
 		},
 		{
-			name: "confidential compute AMDEncryptedVirtualization",
+			name: "Create instance when the Shared VPC subnetwork cannot be read",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region:              "test-region",
-				Zone:                "test-zone",
-				MachineType:         "n2d-standard-4",
-				ConfidentialCompute: machinev1.ConfidentialComputePolicySEV,
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
 				Disks: []*machinev1.GCPDisk{
 					{
 						Boot:  true,
 						Image: "projects/fooproject/global/images/uefi-image",
 					},
 				},
-				ResourceManagerTags: []machinev1.ResourceManagerTag{
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
 					{
-						ParentID: "openshift",
-						Key:      "key1",
-						Value:    "value1",
+						ProjectID:  "host-project",
+						Network:    "network",
+						Subnetwork: "subnetwork",
 					},
 				},
 			},
+			mockNetworksGet: func(project string, network string) (*compute.Network, error) {
+				return nil, &googleapi.Error{Code: 403}
+			},
+			mockSubnetworksGet: func(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+				return nil, &googleapi.Error{Code: 403}
+			},
 			validateInstance: func(t *testing.T, instance *compute.Instance) {
-				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
-					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
-				}
-				if instance.ConfidentialInstanceConfig.ConfidentialInstanceType != "SEV" {
-					t.Errorf("Expected ConfidentialInstanceType to be SEV, Got: %s", instance.ConfidentialInstanceConfig.ConfidentialInstanceType)
+				if len(instance.NetworkInterfaces) != 1 || instance.NetworkInterfaces[0].Subnetwork != "projects/host-project/regions/test-region/subnetworks/subnetwork" {
+					t.Errorf("Expected the Shared VPC subnetwork, got: %v", instance.NetworkInterfaces)
 				}
 			},
 		},
 		{
-			name: "confidential compute AMDEncryptedVirtualizationNestedPaging",
+			name: "Create dual-stack network interface when the Shared VPC subnetwork cannot be read",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region:              "test-region",
-				Zone:                "test-zone",
-				MachineType:         "n2d-standard-4",
-				ConfidentialCompute: machinev1.ConfidentialComputePolicySEVSNP,
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
 				Disks: []*machinev1.GCPDisk{
 					{
 						Boot:  true,
 						Image: "projects/fooproject/global/images/uefi-image",
 					},
 				},
-				ResourceManagerTags: []machinev1.ResourceManagerTag{
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
 					{
-						ParentID: "openshift",
-						Key:      "key1",
-						Value:    "value1",
+						ProjectID:      "host-project",
+						Network:        "network",
+						Subnetwork:     "subnetwork",
+						StackType:      machinev1.NetworkStackTypeIPv4IPv6,
+						IPv6AccessType: machinev1.IPv6AccessTypeExternal,
 					},
 				},
 			},
+			mockSubnetworksGet: func(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+				return nil, &googleapi.Error{Code: 403}
+			},
 			validateInstance: func(t *testing.T, instance *compute.Instance) {
-				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
-					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
+				nic := instance.NetworkInterfaces[0]
+				if nic.StackType != "IPV4_IPV6" || nic.Ipv6AccessType != "EXTERNAL" {
+					t.Errorf("Expected stack type IPV4_IPV6 with IPv6 access type EXTERNAL, got: %q with %q", nic.StackType, nic.Ipv6AccessType)
 				}
-				if instance.ConfidentialInstanceConfig.ConfidentialInstanceType != "SEV_SNP" {
-					t.Errorf("Expected ConfidentialInstanceType to be SEV_SNP, Got: %s", instance.ConfidentialInstanceConfig.ConfidentialInstanceType)
+				if len(nic.Ipv6AccessConfigs) != 1 || nic.Ipv6AccessConfigs[0].Type != "DIRECT_IPV6" {
+					t.Errorf("Expected one DIRECT_IPV6 access config, got: %v", nic.Ipv6AccessConfigs)
 				}
 			},
 		},
 		{
-			name: "confidential compute IntelTrustedDomainExtensions",
+			name: "Fail when the subnetwork cannot be used",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region:              "test-region",
-				Zone:                "test-zone",
-				MachineType:         "c3-standard-4",
-				ConfidentialCompute: machinev1.ConfidentialComputePolicyTDX,
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
 				Disks: []*machinev1.GCPDisk{
 					{
 						Boot:  true,
 						Image: "projects/fooproject/global/images/uefi-image",
 					},
 				},
-				ResourceManagerTags: []machinev1.ResourceManagerTag{
+				NetworkInterfaces: []*machinev1.GCPNetworkInterface{
 					{
-						ParentID: "openshift",
-						Key:      "key1",
-						Value:    "value1",
+						ProjectID:  "host-project",
+						Network:    "network",
+						Subnetwork: "subnetwork",
 					},
 				},
 			},
-			validateInstance: func(t *testing.T, instance *compute.Instance) {
-				if instance.ConfidentialInstanceConfig.EnableConfidentialCompute != true {
-					t.Errorf("Expected EnableConfidentialCompute to be true, Got: %t", instance.ConfidentialInstanceConfig.EnableConfidentialCompute)
-				}
-				if instance.ConfidentialInstanceConfig.ConfidentialInstanceType != "TDX" {
-					t.Errorf("Expected ConfidentialInstanceType to be TDX, Got: %s", instance.ConfidentialInstanceConfig.ConfidentialInstanceType)
-				}
+			mockSubnetworksTestIamPermissions: func(project string, region string, subnetwork string, permissions []string) ([]string, error) {
+				return []string{}, nil
 			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("permission compute.subnetworks.use is not granted on subnetwork subnetwork in project host-project"),
 		},
 		{
-			name: "failed to fetch resource manager tags",
+			name: "Fail when the KMS key does not exist",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region: "test-region",
-				Zone:   "test-zone",
-				ResourceManagerTags: []machinev1.ResourceManagerTag{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
+				Disks: []*machinev1.GCPDisk{
 					{
-						ParentID: "openshift",
-						Key:      "key2",
-						Value:    "value2",
+						Boot:  true,
+						Image: "projects/fooproject/global/images/uefi-image",
+						EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+							KMSKey: &machinev1.GCPKMSKeyReference{
+								Name:     "kms-key",
+								KeyRing:  "kms-ring",
+								Location: "global",
+							},
+						},
 					},
 				},
 			},
-			expectedError: errors.New("failed to fetch user-defined tags for : failed to fetch openshift/key2/value2 tag details: googleapi: Error 500: Internal error while fetching 'openshift/key2/value2'"),
+			mockCryptoKeysGet: func(ctx context.Context, name string) (*cloudkms.CryptoKey, error) {
+				return nil, &googleapi.Error{Code: http.StatusNotFound}
+			},
+			expectedCondition: &metav1.Condition{
+				Type:    util.KMSKeyAccessibleCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  util.KMSKeyNotFoundReason,
+				Message: "KMS key projects/project/locations/global/keyRings/kms-ring/cryptoKeys/kms-key not found",
+			},
+			expectedError: errors.New("KMS key cannot be used: KMS key projects/project/locations/global/keyRings/kms-ring/cryptoKeys/kms-key not found"),
 		},
 		{
-			name: "a3-ultragpu (H200 GPUs) instance create succeeds when quota is not available",
+			name: "Fail when the KMS key primary version is disabled",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region:      "test-region",
-				Zone:        "test-zone",
-				MachineType: "a3-ultragpu-8g",
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
 				Disks: []*machinev1.GCPDisk{
 					{
 						Boot:  true,
 						Image: "projects/fooproject/global/images/uefi-image",
+						EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+							KMSKey: &machinev1.GCPKMSKeyReference{
+								Name:     "kms-key",
+								KeyRing:  "kms-ring",
+								Location: "global",
+							},
+						},
 					},
 				},
 			},
-			mockGPUCompatibleMachineTypesList: func(project string, zone string, ctx context.Context) (map[string]computeservice.GpuInfo, []string) {
-				var compatibleMachineType = []string{}
-				var gpuInfo = map[string]computeservice.GpuInfo{
-					"a3-ultragpu-8g": {
-						Type:  "nvidia-h200-141gb",
-						Count: 1,
-					},
-				}
-				return gpuInfo, compatibleMachineType
+			mockCryptoKeysGet: func(ctx context.Context, name string) (*cloudkms.CryptoKey, error) {
+				return &cloudkms.CryptoKey{Name: name, Primary: &cloudkms.CryptoKeyVersion{State: "DISABLED"}}, nil
 			},
-			mockRegionGet: func(project string, region string) (*compute.Region, error) {
-				var computeQuota = &compute.Quota{}
-				var computeRegion = &compute.Region{Quotas: []*compute.Quota{computeQuota}}
-				return computeRegion, nil
+			expectedCondition: &metav1.Condition{
+				Type:    util.KMSKeyAccessibleCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  util.KMSKeyDisabledReason,
+				Message: "primary version of KMS key projects/project/locations/global/keyRings/kms-ring/cryptoKeys/kms-key is disabled",
 			},
+			expectedError: errors.New("KMS key cannot be used: primary version of KMS key projects/project/locations/global/keyRings/kms-ring/cryptoKeys/kms-key is disabled"),
 		},
 		{
-			name: "Create spot instance successfully",
+			name: "Warn when the KMS key service account is not granted the encrypter role",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region:            "test-region",
-				Zone:              "test-zone",
-				MachineType:       "n1-standard-4",
-				ProvisioningModel: ptr.To(machinev1.GCPSpotInstance),
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
 				Disks: []*machinev1.GCPDisk{
 					{
 						Boot:  true,
 						Image: "projects/fooproject/global/images/uefi-image",
+						EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+							KMSKey: &machinev1.GCPKMSKeyReference{
+								Name:     "kms-key",
+								KeyRing:  "kms-ring",
+								Location: "global",
+							},
+							KMSKeyServiceAccount: "other-service-account",
+						},
 					},
 				},
 			},
+			expectedCondition: &metav1.Condition{
+				Type:    util.KMSKeyAccessibleCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  util.KMSKeyAccessUnverifiedReason,
+				Message: "no role binding grants roles/cloudkms.cryptoKeyEncrypterDecrypter on KMS key projects/project/locations/global/keyRings/kms-ring/cryptoKeys/kms-key to service account other-service-account",
+			},
+		},
+		{
+			name: "Create instance from a source instance template",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID:              "project",
+				Region:                 "test-region",
+				Zone:                   "test-zone",
+				SourceInstanceTemplate: "workers",
+				Tags:                   []string{"worker"},
+			},
 			validateInstance: func(t *testing.T, instance *compute.Instance) {
-				expectedProvisioningModel, err := toComputeProvisioningModel(ptr.To(machinev1.GCPSpotInstance))
-				if err != nil {
-					t.Fatalf("failed to get expected provisioning model: %v", err)
+				if instance.MachineType != "" {
+					t.Errorf("Expected the machine type of the template, got: %s", instance.MachineType)
 				}
-				if instance.Scheduling.ProvisioningModel != expectedProvisioningModel {
-					t.Errorf("expected ProvisioningModel to be %q, Got: %s", expectedProvisioningModel, instance.Scheduling.ProvisioningModel)
+				if instance.Disks != nil || instance.NetworkInterfaces != nil || instance.ServiceAccounts != nil {
+					t.Errorf("Expected the disks, network interfaces and service accounts of the template, got: %v, %v, %v", instance.Disks, instance.NetworkInterfaces, instance.ServiceAccounts)
+				}
+				if instance.ShieldedInstanceConfig != nil {
+					t.Errorf("Expected the shielded instance config of the template, got: %v", instance.ShieldedInstanceConfig)
 				}
+				assertStrings(t, "tags", []string{"worker"}, instance.Tags.Items)
 			},
 		},
 		{
-			name: "Fail on invalid provisioning model",
+			name: "Fail when the regional source instance template is in another region",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region:            "test-region",
-				Zone:              "test-zone",
-				ProvisioningModel: ptr.To(machinev1.GCPProvisioningModelType("InvalidModel")),
-				Disks: []*machinev1.GCPDisk{
-					{
-						Boot:  true,
-						Image: "projects/fooproject/global/images/uefi-image",
-					},
+				ProjectID:              "project",
+				Region:                 "test-region",
+				Zone:                   "test-zone",
+				SourceInstanceTemplate: "projects/project/regions/other-region/instanceTemplates/workers",
+			},
+			expectedError: errors.New("invalid source instance template: instance template \"workers\" is in region \"other-region\", not in the region \"test-region\" of the machine"),
+		},
+		{
+			name: "Create instance in an existing managed instance group",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID:              "project",
+				Region:                 "test-region",
+				Zone:                   "test-zone",
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup: &machinev1.GCPManagedInstanceGroup{
+					Name: "workers",
 				},
 			},
-			expectedError: errors.New("failed validating machine provider spec: Unsupported provisioning model \"InvalidModel\", Valid value is \"Spot\". Omit provisioningModel for standard."),
+			mockInstanceGroupManagersGet: func(project string, zone string, instanceGroupManager string) (*compute.InstanceGroupManager, error) {
+				return &compute.InstanceGroupManager{
+					Name:             instanceGroupManager,
+					InstanceTemplate: "https://www.googleapis.com/compute/v1/projects/project/global/instanceTemplates/workers",
+				}, nil
+			},
+			// The instance is created asynchronously by the group
+			expectedError: errors.New("requeue in: 20s"),
 		},
 		{
-			name: "Fail when both preemptible and spot are set",
+			name: "Fail when the existing managed instance group uses another instance template",
 			providerSpec: &machinev1.GCPMachineProviderSpec{
-				Region:            "test-region",
-				Zone:              "test-zone",
-				Preemptible:       true,
-				ProvisioningModel: ptr.To(machinev1.GCPSpotInstance),
+				ProjectID:              "project",
+				Region:                 "test-region",
+				Zone:                   "test-zone",
+				SourceInstanceTemplate: "workers",
+				ManagedInstanceGroup: &machinev1.GCPManagedInstanceGroup{
+					Name: "workers",
+				},
+			},
+			mockInstanceGroupManagersGet: func(project string, zone string, instanceGroupManager string) (*compute.InstanceGroupManager, error) {
+				return &compute.InstanceGroupManager{
+					Name:             instanceGroupManager,
+					InstanceTemplate: "https://www.googleapis.com/compute/v1/projects/project/global/instanceTemplates/workers-v1",
+				}, nil
+			},
+			expectedError: errors.New("managed instance group workers creates its instances from instance template projects/project/global/instanceTemplates/workers-v1, not from the source instance template projects/project/global/instanceTemplates/workers of the machine"),
+		},
+		{
+			name: "Fail when the managed instance group has no source instance template",
+			providerSpec: &machinev1.GCPMachineProviderSpec{
+				ProjectID: "project",
+				Region:    "test-region",
+				Zone:      "test-zone",
 				Disks: []*machinev1.GCPDisk{
 					{
 						Boot:  true,
 						Image: "projects/fooproject/global/images/uefi-image",
 					},
 				},
+				ManagedInstanceGroup: &machinev1.GCPManagedInstanceGroup{
+					Name: "workers",
+				},
 			},
-			expectedError: errors.New("failed validating machine provider spec: preemptible cannot be used together with 'Spot' provisioning model"),
+			expectedError: errors.New("failed validating machine provider spec: managed instance group requires a source instance template"),
 		},
 	}
 
@@ -902,6 +2057,7 @@ func TestCreate(t *testing.T) {
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			receivedInstance, mockComputeService := computeservice.NewComputeServiceMock()
+			mockKMSService := kmsservice.NewMockKMSService()
 			providerSpec := &machinev1.GCPMachineProviderSpec{
 				Disks: []*machinev1.GCPDisk{
 					{
@@ -967,6 +2123,7 @@ func TestCreate(t *testing.T) {
 				projectID:      providerSpec.ProjectID,
 				featureGates:   gate,
 				tagService:     mockTagService,
+				kmsService:     mockKMSService,
 			}
 
 			reconciler := newReconciler(&machineScope)
@@ -983,6 +2140,37 @@ func TestCreate(t *testing.T) {
 				mockComputeService.MockRegionGet = tc.mockRegionGet
 			}
 
+			if tc.mockResourcePoliciesGet != nil {
+				mockComputeService.MockResourcePoliciesGet = tc.mockResourcePoliciesGet
+			}
+			if tc.mockInstanceGroupManagersGet != nil {
+				mockComputeService.MockInstanceGroupManagersGet = tc.mockInstanceGroupManagersGet
+			}
+
+			if tc.mockSubnetworksGet != nil {
+				mockComputeService.MockSubnetworksGet = tc.mockSubnetworksGet
+			}
+
+			if tc.mockNetworksGet != nil {
+				mockComputeService.MockNetworksGet = tc.mockNetworksGet
+			}
+
+			if tc.mockSubnetworksTestIamPermissions != nil {
+				mockComputeService.MockSubnetworksTestIamPermissions = tc.mockSubnetworksTestIamPermissions
+			}
+
+			if tc.mockNodeGroupsList != nil {
+				mockComputeService.MockNodeGroupsList = tc.mockNodeGroupsList
+			}
+
+			if tc.mockCryptoKeysGet != nil {
+				mockKMSService.MockCryptoKeysGet = tc.mockCryptoKeysGet
+			}
+
+			if tc.mockKeyRingsGetIamPolicy != nil {
+				mockKMSService.MockKeyRingsGetIamPolicy = tc.mockKeyRingsGetIamPolicy
+			}
+
 			err = reconciler.create()
 
 			if tc.expectedCondition != nil {
@@ -1342,7 +2530,7 @@ func TestRegisterInstanceToControlPlaneInstanceGroup(t *testing.T) {
 	}
 	for _, tc := range tCases {
 		rec := newReconciler(tc.scope)
-		err := rec.registerInstanceToControlPlaneInstanceGroup()
+		err := rec.registerInstanceToInstanceGroup(rec.controlPlaneInstanceGroupAttachment())
 		if tc.expectedErr {
 			if err == nil {
 				t.Errorf("expected error from registerInstanceToInstanceGroup but got nil")
@@ -1419,16 +2607,16 @@ func TestUnregisterInstanceToControlPlaneInstanceGroup(t *testing.T) {
 	}
 	for _, tc := range tCases {
 		rec := newReconciler(tc.scope)
-		err := rec.unregisterInstanceFromControlPlaneInstanceGroup()
+		err := rec.unregisterInstanceFromInstanceGroup(rec.controlPlaneGroupName())
 		if tc.expectedErr {
 			if err == nil {
-				t.Errorf("expected error \"%v\" from unregisterInstanceFromControlPlaneInstanceGroup but got nil", tc.errString)
+				t.Errorf("expected error \"%v\" from unregisterInstanceFromInstanceGroup but got nil", tc.errString)
 			} else if !strings.Contains(err.Error(), tc.errString) {
-				t.Errorf("expected error from unregisterInstanceFromControlPlaneInstanceGroup to contain \"%v\" but got \"%v\"", tc.errString, err.Error())
+				t.Errorf("expected error from unregisterInstanceFromInstanceGroup to contain \"%v\" but got \"%v\"", tc.errString, err.Error())
 			}
 		} else {
 			if err != nil {
-				t.Errorf("unexpected error from unregisterInstanceFromControlPlaneInstanceGroup: %v", err)
+				t.Errorf("unexpected error from unregisterInstanceFromInstanceGroup: %v", err)
 			}
 		}
 	}
@@ -1695,102 +2883,106 @@ func TestRestartPolicyToBool(t *testing.T) {
 	}
 }
 
-func TestEnsureCorrectNetworkAndSubnetName(t *testing.T) {
-	testType := "testType"
-	testRegion := "testRegion"
-	testZone := "testZone"
-
-	r := Reconciler{
-		machineScope: &machineScope{
-
-			machine: &machinev1.Machine{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "",
-					Namespace: "",
-					Labels: map[string]string{
-						machinev1.MachineClusterIDLabel: "test-machine-1",
-						openshiftMachineRoleLabel:       "test-machine-role",
-					},
-				},
-			},
-			providerSpec: &machinev1.GCPMachineProviderSpec{
-				MachineType: testType,
-				Region:      testRegion,
-				Zone:        testZone,
-			},
-		},
-	}
-
+func TestRegisterNewInstanceGroup(t *testing.T) {
 	cases := []struct {
-		name                   string
-		expectedNetworkName    string
-		expectedSubnetworkName string
-		networkInterfaces      []*machinev1.GCPNetworkInterface
+		name                     string
+		networkInterfaces        []*machinev1.GCPNetworkInterface
+		insertStatus             string
+		expectedNetwork          string
+		expectedSubnetwork       string
+		expectedRequeue          bool
+		expectedPendingOperation bool
 	}{
 		{
-			name:                   "Custom network and subnetwork name",
-			expectedNetworkName:    "custom-test-network",
-			expectedSubnetworkName: "custom-test-subnetwork",
+			name: "Network of the first network interface",
 			networkInterfaces: []*machinev1.GCPNetworkInterface{
 				{
-					Network:    "custom-test-network",
-					Subnetwork: "custom-test-subnetwork",
+					Network:    "custom-test-1-network",
+					Subnetwork: "custom-test-1-subnetwork",
 				},
-			},
-		},
-		{
-			name:                   "Expected network and subnetwork name present",
-			expectedNetworkName:    "test-machine-1-network",
-			expectedSubnetworkName: "test-machine-1-test-machine-role-subnet",
-			networkInterfaces: []*machinev1.GCPNetworkInterface{
 				{
 					Network:    "test-machine-1-network",
 					Subnetwork: "test-machine-1-test-machine-role-subnet",
 				},
 			},
+			expectedNetwork:    "projects/test/global/networks/custom-test-1-network",
+			expectedSubnetwork: "projects/test/regions/testRegion/subnetworks/custom-test-1-subnetwork",
 		},
 		{
-			name:                   "Two interfaces, expected in index 0",
-			expectedNetworkName:    "test-machine-1-network",
-			expectedSubnetworkName: "test-machine-1-test-machine-role-subnet",
+			name: "Shared VPC network of the host project",
 			networkInterfaces: []*machinev1.GCPNetworkInterface{
 				{
-					Network:    "test-machine-1-network",
-					Subnetwork: "test-machine-1-test-machine-role-subnet",
-				},
-				{
-					Network:    "custom-test-2-network",
-					Subnetwork: "custom-test-2-subnetwork",
+					ProjectID:  "host",
+					Network:    "shared-network",
+					Subnetwork: "shared-subnetwork",
 				},
 			},
+			expectedNetwork:    "projects/host/global/networks/shared-network",
+			expectedSubnetwork: "projects/host/regions/testRegion/subnetworks/shared-subnetwork",
 		},
 		{
-			name:                   "Three interfaces, expected in index 2",
-			expectedNetworkName:    "test-machine-1-network",
-			expectedSubnetworkName: "test-machine-1-test-machine-role-subnet",
+			name: "Requeue until the instance group is created",
 			networkInterfaces: []*machinev1.GCPNetworkInterface{
 				{
-					Network:    "custom-test-1-network",
-					Subnetwork: "custom-test-1-subnetwork",
-				},
-				{
-					Network:    "custom-test-2-network",
-					Subnetwork: "custom-test-2-subnetwork",
-				},
-				{
-					Network:    "test-machine-1-network",
-					Subnetwork: "test-machine-1-test-machine-role-subnet",
+					Network:    "custom-test-network",
+					Subnetwork: "custom-test-subnetwork",
 				},
 			},
+			insertStatus:             "RUNNING",
+			expectedNetwork:          "projects/test/global/networks/custom-test-network",
+			expectedSubnetwork:       "projects/test/regions/testRegion/subnetworks/custom-test-subnetwork",
+			expectedRequeue:          true,
+			expectedPendingOperation: true,
 		},
 	}
 
 	for _, tc := range cases {
-		r.providerSpec.NetworkInterfaces = tc.networkInterfaces
-		actualNetworkName, actualSubnetworkName := r.ensureCorrectNetworkAndSubnetName()
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var inserted *compute.InstanceGroup
+			mockComputeService.MockInstanceGroupInsert = func(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error) {
+				if project != "test" {
+					t.Errorf("Expected the instance group in the project of the machine, got: %s", project)
+				}
+				inserted = instanceGroup
+				status := tc.insertStatus
+				if status == "" {
+					status = "DONE"
+				}
+				return &compute.Operation{Name: "insert", Status: status}, nil
+			}
 
-		if actualNetworkName != tc.expectedNetworkName || actualSubnetworkName != tc.expectedSubnetworkName {
-			t.Errorf("Expected NetworkName: %s, got: %s\nExpected SubnetworkName: %s, got: %s", tc.expectedNetworkName, actualNetworkName, tc.expectedSubnetworkName, actualSubnetworkName)
-		}
+			providerStatus := &machinev1.GCPMachineProviderStatus{}
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "machine-0",
+					},
+				},
+				coreClient: controllerfake.NewFakeClient(),
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					Region:            "testRegion",
+					Zone:              "testZone",
+					NetworkInterfaces: tc.networkInterfaces,
+				},
+				projectID:      "test",
+				providerStatus: providerStatus,
+				computeService: mockComputeService,
+			})
+
+			err := r.registerNewInstanceGroup("ingress-testZone")
+			if isRequeueAfterError(err) != tc.expectedRequeue || (err != nil && !tc.expectedRequeue) {
+				t.Errorf("Expected requeue: %v, got: %v", tc.expectedRequeue, err)
+			}
+			if inserted == nil {
+				t.Fatal("Expected the instance group to be inserted")
+			}
+			if inserted.Network != tc.expectedNetwork || inserted.Subnetwork != tc.expectedSubnetwork {
+				t.Errorf("Expected network %s and subnetwork %s, got: %s %s", tc.expectedNetwork, tc.expectedSubnetwork, inserted.Network, inserted.Subnetwork)
+			}
+			if (len(providerStatus.PendingOperations) > 0) != tc.expectedPendingOperation {
+				t.Errorf("Expected pending operation: %v, got: %v", tc.expectedPendingOperation, providerStatus.PendingOperations)
+			}
+		})
 	}
 }
@@ -0,0 +1,114 @@
+package machine
+
+import (
+	"fmt"
+	"path"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// reservationNameAffinityKey is the key of the affinity of an instance consuming a specific reservation.
+	reservationNameAffinityKey = "compute.googleapis.com/reservation-name"
+
+	reservationStatusReady = "READY"
+)
+
+// toComputeReservationAffinity converts the reservation affinity of the provider spec to the reservation
+// affinity of the instance. Shared reservations of other projects are referenced by their resource path.
+func toComputeReservationAffinity(affinity *machinev1.GCPReservationAffinity, projectID string) *compute.ReservationAffinity {
+	if affinity == nil {
+		return nil
+	}
+
+	switch affinity.ConsumeReservationType {
+	case machinev1.ReservationAffinitySpecific:
+		value := affinity.Name
+		if affinity.ProjectID != "" && affinity.ProjectID != projectID {
+			value = fmt.Sprintf("projects/%s/reservations/%s", affinity.ProjectID, affinity.Name)
+		}
+		return &compute.ReservationAffinity{
+			ConsumeReservationType: "SPECIFIC_RESERVATION",
+			Key:                    reservationNameAffinityKey,
+			Values:                 []string{value},
+		}
+	case machinev1.ReservationAffinityNone:
+		return &compute.ReservationAffinity{ConsumeReservationType: "NO_RESERVATION"}
+	default:
+		return &compute.ReservationAffinity{ConsumeReservationType: "ANY_RESERVATION"}
+	}
+}
+
+func validateReservationAffinity(affinity *machinev1.GCPReservationAffinity) error {
+	if affinity == nil {
+		return nil
+	}
+
+	switch affinity.ConsumeReservationType {
+	case machinev1.ReservationAffinitySpecific:
+		if affinity.Name == "" {
+			return fmt.Errorf("reservation name is required with the %q reservation affinity", machinev1.ReservationAffinitySpecific)
+		}
+	case machinev1.ReservationAffinityAny, machinev1.ReservationAffinityNone:
+		if affinity.Name != "" || affinity.ProjectID != "" {
+			return fmt.Errorf("reservation name and project can only be set with the %q reservation affinity", machinev1.ReservationAffinitySpecific)
+		}
+	default:
+		return fmt.Errorf("unknown reservation affinity %q, valid values are %q, %q and %q", affinity.ConsumeReservationType, machinev1.ReservationAffinityAny, machinev1.ReservationAffinitySpecific, machinev1.ReservationAffinityNone)
+	}
+	return nil
+}
+
+// validateReservation checks the specific reservation the instance consumes exists in the zone of the
+// machine, is for the machine type of the machine and has remaining capacity before inserting the instance.
+func (r *Reconciler) validateReservation() error {
+	affinity := r.providerSpec.ReservationAffinity
+	if affinity == nil || affinity.ConsumeReservationType != machinev1.ReservationAffinitySpecific {
+		return nil
+	}
+
+	projectID := affinity.ProjectID
+	if projectID == "" {
+		projectID = r.projectID
+	}
+	reservation, err := r.computeService.ReservationsGet(projectID, r.providerSpec.Zone, affinity.Name)
+	if err != nil {
+		if isNotFoundError(err) {
+			return machinecontroller.InvalidMachineConfiguration("reservation %s not found in zone %s of project %s", affinity.Name, r.providerSpec.Zone, projectID)
+		}
+		if isForbiddenError(err) {
+			klog.Warningf("%s: not allowed to get reservation %s in project %s, skipping its validation: %v", r.machine.Name, affinity.Name, projectID, err)
+			return nil
+		}
+		return fmt.Errorf("failed to get reservation %s: %w", affinity.Name, err)
+	}
+
+	if reservation.Status != reservationStatusReady {
+		return fmt.Errorf("reservation %s is %s, expected %s", affinity.Name, reservation.Status, reservationStatusReady)
+	}
+
+	specific := reservation.SpecificReservation
+	if specific == nil {
+		return machinecontroller.InvalidMachineConfiguration("reservation %s does not reserve instances", affinity.Name)
+	}
+	if specific.InstanceProperties != nil && specific.InstanceProperties.MachineType != "" && r.providerSpec.MachineType != "" && path.Base(specific.InstanceProperties.MachineType) != r.providerSpec.MachineType {
+		return machinecontroller.InvalidMachineConfiguration("reservation %s is for machine type %s, not %s", affinity.Name, path.Base(specific.InstanceProperties.MachineType), r.providerSpec.MachineType)
+	}
+	if specific.InUseCount >= specific.Count {
+		// The capacity is released when an instance consuming the reservation is deleted
+		return fmt.Errorf("reservation %s has no remaining capacity, %d of %d instances are in use", affinity.Name, specific.InUseCount, specific.Count)
+	}
+	return nil
+}
+
+// consumedReservation returns the full resource name of the reservation the instance consumes,
+// empty when the instance does not consume a reservation.
+func consumedReservation(instance *compute.Instance) string {
+	if instance.ResourceStatus == nil || instance.ResourceStatus.ReservationConsumptionInfo == nil {
+		return ""
+	}
+	return instance.ResourceStatus.ReservationConsumptionInfo.ConsumedReservation
+}
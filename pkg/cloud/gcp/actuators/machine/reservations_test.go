@@ -0,0 +1,203 @@
+package machine
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestToComputeReservationAffinity(t *testing.T) {
+	cases := []struct {
+		name     string
+		affinity *machinev1.GCPReservationAffinity
+		expected *compute.ReservationAffinity
+	}{
+		{
+			name: "Default reservation affinity",
+		},
+		{
+			name:     "Any reservation",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinityAny},
+			expected: &compute.ReservationAffinity{ConsumeReservationType: "ANY_RESERVATION"},
+		},
+		{
+			name:     "No reservation",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinityNone},
+			expected: &compute.ReservationAffinity{ConsumeReservationType: "NO_RESERVATION"},
+		},
+		{
+			name:     "Specific reservation",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus", ProjectID: "test"},
+			expected: &compute.ReservationAffinity{
+				ConsumeReservationType: "SPECIFIC_RESERVATION",
+				Key:                    "compute.googleapis.com/reservation-name",
+				Values:                 []string{"gpus"},
+			},
+		},
+		{
+			name:     "Specific shared reservation",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus", ProjectID: "owner"},
+			expected: &compute.ReservationAffinity{
+				ConsumeReservationType: "SPECIFIC_RESERVATION",
+				Key:                    "compute.googleapis.com/reservation-name",
+				Values:                 []string{"projects/owner/reservations/gpus"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := toComputeReservationAffinity(tc.affinity, "test"); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected reservation affinity: %v, got: %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateReservation(t *testing.T) {
+	cases := []struct {
+		name              string
+		affinity          *machinev1.GCPReservationAffinity
+		reservation       *compute.Reservation
+		getErr            error
+		expectedProject   string
+		expectedError     string
+		expectInvalidSpec bool
+	}{
+		{
+			name:     "Any reservation is not checked",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinityAny},
+		},
+		{
+			name:     "Reservation with remaining capacity",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus"},
+			reservation: &compute.Reservation{
+				Status: "READY",
+				SpecificReservation: &compute.AllocationSpecificSKUReservation{
+					Count:              4,
+					InUseCount:         3,
+					InstanceProperties: &compute.AllocationSpecificSKUAllocationReservedInstanceProperties{MachineType: "a2-highgpu-1g"},
+				},
+			},
+			expectedProject: "test",
+		},
+		{
+			name:            "Shared reservation of another project",
+			affinity:        &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus", ProjectID: "owner"},
+			reservation:     &compute.Reservation{Status: "READY", SpecificReservation: &compute.AllocationSpecificSKUReservation{Count: 1}},
+			expectedProject: "owner",
+		},
+		{
+			name:     "Reservation without remaining capacity",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus"},
+			reservation: &compute.Reservation{
+				Status:              "READY",
+				SpecificReservation: &compute.AllocationSpecificSKUReservation{Count: 4, InUseCount: 4},
+			},
+			expectedProject: "test",
+			expectedError:   "reservation gpus has no remaining capacity, 4 of 4 instances are in use",
+		},
+		{
+			name:     "Reservation for another machine type",
+			affinity: &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus"},
+			reservation: &compute.Reservation{
+				Status: "READY",
+				SpecificReservation: &compute.AllocationSpecificSKUReservation{
+					Count:              4,
+					InstanceProperties: &compute.AllocationSpecificSKUAllocationReservedInstanceProperties{MachineType: "a2-highgpu-2g"},
+				},
+			},
+			expectedProject:   "test",
+			expectedError:     "reservation gpus is for machine type a2-highgpu-2g, not a2-highgpu-1g",
+			expectInvalidSpec: true,
+		},
+		{
+			name:            "Reservation being created",
+			affinity:        &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus"},
+			reservation:     &compute.Reservation{Status: "CREATING", SpecificReservation: &compute.AllocationSpecificSKUReservation{Count: 1}},
+			expectedProject: "test",
+			expectedError:   "reservation gpus is CREATING, expected READY",
+		},
+		{
+			name:              "Missing reservation",
+			affinity:          &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus"},
+			getErr:            &googleapi.Error{Code: http.StatusNotFound},
+			expectedProject:   "test",
+			expectedError:     "reservation gpus not found in zone zone1 of project test",
+			expectInvalidSpec: true,
+		},
+		{
+			name:            "Skip the validation when the reservation cannot be read",
+			affinity:        &machinev1.GCPReservationAffinity{ConsumeReservationType: machinev1.ReservationAffinitySpecific, Name: "gpus", ProjectID: "owner"},
+			getErr:          &googleapi.Error{Code: http.StatusForbidden},
+			expectedProject: "owner",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var gotProject string
+			mockComputeService.MockReservationsGet = func(project string, zone string, reservation string) (*compute.Reservation, error) {
+				gotProject = project
+				if zone != "zone1" || reservation != "gpus" {
+					t.Errorf("Unexpected reservation %s in zone %s", reservation, zone)
+				}
+				return tc.reservation, tc.getErr
+			}
+
+			r := newReconciler(&machineScope{
+				machine: &machinev1.Machine{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "machine-0",
+					},
+				},
+				providerSpec: &machinev1.GCPMachineProviderSpec{
+					MachineType:         "a2-highgpu-1g",
+					Region:              "region1",
+					Zone:                "zone1",
+					ReservationAffinity: tc.affinity,
+				},
+				projectID:      "test",
+				computeService: mockComputeService,
+			})
+
+			err := r.validateReservation()
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("Expected error: %q, got: %v", tc.expectedError, err)
+				}
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			if isInvalidMachineConfigurationError(err) != tc.expectInvalidSpec {
+				t.Errorf("Expected invalid machine configuration: %v, got: %v", tc.expectInvalidSpec, err)
+			}
+			if gotProject != tc.expectedProject {
+				t.Errorf("Expected the reservation to be read in project %q, got: %q", tc.expectedProject, gotProject)
+			}
+		})
+	}
+}
+
+func TestConsumedReservation(t *testing.T) {
+	instance := &compute.Instance{
+		ResourceStatus: &compute.ResourceStatus{
+			ReservationConsumptionInfo: &compute.ResourceStatusReservationConsumptionInfo{
+				ConsumedReservation: "projects/owner/zones/zone1/reservations/gpus",
+			},
+		},
+	}
+	if got := consumedReservation(instance); got != "projects/owner/zones/zone1/reservations/gpus" {
+		t.Errorf("Expected consumed reservation %q, got: %q", "projects/owner/zones/zone1/reservations/gpus", got)
+	}
+	if got := consumedReservation(&compute.Instance{}); got != "" {
+		t.Errorf("Expected no consumed reservation, got: %q", got)
+	}
+}
@@ -0,0 +1,158 @@
+package machine
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	addressTypeInternal = "INTERNAL"
+	addressTypeExternal = "EXTERNAL"
+
+	addressStatusInUse = "IN_USE"
+)
+
+// validateStaticAddresses makes sure the static addresses of a network interface are consistent.
+func validateStaticAddresses(nic *machinev1.GCPNetworkInterface) error {
+	for _, address := range []*machinev1.GCPStaticAddress{nic.InternalAddress, nic.ExternalAddress} {
+		if address == nil {
+			continue
+		}
+		switch address.DeletionPolicy {
+		case "", machinev1.AddressDeletionPolicyDelete, machinev1.AddressDeletionPolicyRetain:
+		default:
+			return fmt.Errorf("unsupported address deletion policy %q, valid values are %q and %q", address.DeletionPolicy,
+				machinev1.AddressDeletionPolicyDelete, machinev1.AddressDeletionPolicyRetain)
+		}
+	}
+
+	if nic.ExternalAddress != nil && nic.ExternalAddress.Address != "" {
+		return fmt.Errorf("external address cannot specify an IP address, it is chosen by GCP")
+	}
+	if nic.InternalAddress != nil && nic.InternalAddress.Address != "" {
+		if _, err := netip.ParseAddr(nic.InternalAddress.Address); err != nil {
+			return fmt.Errorf("invalid internal address %q: %v", nic.InternalAddress.Address, err)
+		}
+	}
+	return nil
+}
+
+// staticAddressName returns the name of the address resource of a network interface.
+func staticAddressName(machineName string, nicIndex int, address *machinev1.GCPStaticAddress, addressType string) string {
+	if address.Name != "" {
+		return address.Name
+	}
+	return fmt.Sprintf("%s-nic%d-%s", machineName, nicIndex, strings.ToLower(addressType))
+}
+
+// ensureStaticAddress returns the IP address of the static address of a network interface,
+// reserving the address when it does not exist yet. Reserved addresses are labelled as owned
+// by the cluster so that they can be released when the machine is deleted.
+func (r *Reconciler) ensureStaticAddress(nicIndex int, address *machinev1.GCPStaticAddress, addressType string, subnetwork string, networkTier string) (string, error) {
+	name := staticAddressName(r.machine.Name, nicIndex, address, addressType)
+
+	gceAddress, err := r.computeService.AddressesGet(r.projectID, r.providerSpec.Region, name)
+	if err == nil {
+		if gceAddress.AddressType != "" && gceAddress.AddressType != addressType {
+			return "", machinecontroller.InvalidMachineConfiguration("address %s is an %s address, expected an %s address", name, gceAddress.AddressType, addressType)
+		}
+		if gceAddress.Status == addressStatusInUse && !isAddressUser(gceAddress, r.machine.Name) {
+			return "", machinecontroller.InvalidMachineConfiguration("address %s is already in use by %s", name, strings.Join(gceAddress.Users, ", "))
+		}
+		return gceAddress.Address, nil
+	}
+	if !isNotFoundError(err) {
+		return "", fmt.Errorf("failed to get address %s: %w", name, err)
+	}
+
+	labels, err := util.GetLabelsList(r.coreClient, r.machine.Labels[machinev1.MachineClusterIDLabel], nil)
+	if err != nil {
+		return "", err
+	}
+	reservation := &compute.Address{
+		Name:        name,
+		AddressType: addressType,
+		Labels:      labels,
+	}
+	if addressType == addressTypeInternal {
+		if subnetwork == "" {
+			return "", machinecontroller.InvalidMachineConfiguration("network interface with an internal address must specify a subnetwork")
+		}
+		reservation.Address = address.Address
+		reservation.Subnetwork = subnetwork
+	} else {
+		reservation.NetworkTier = networkTier
+	}
+
+	klog.Infof("%s: reserving %s address %s", r.machine.Name, strings.ToLower(addressType), name)
+	op, err := r.computeService.AddressesInsert(r.projectID, r.providerSpec.Region, reservation)
+	if err != nil {
+		return "", fmt.Errorf("failed to reserve address %s: %w", name, err)
+	}
+	if err := r.awaitRegionOperation(op); err != nil {
+		return "", err
+	}
+
+	gceAddress, err = r.computeService.AddressesGet(r.projectID, r.providerSpec.Region, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get address %s: %w", name, err)
+	}
+	return gceAddress.Address, nil
+}
+
+// releaseStaticAddresses releases the static addresses reserved for the cluster once the
+// instance is deleted, unless their deletion policy retains them.
+func (r *Reconciler) releaseStaticAddresses() error {
+	for i, nic := range r.providerSpec.NetworkInterfaces {
+		for addressType, address := range map[string]*machinev1.GCPStaticAddress{
+			addressTypeInternal: nic.InternalAddress,
+			addressTypeExternal: nic.ExternalAddress,
+		} {
+			if address == nil || address.DeletionPolicy == machinev1.AddressDeletionPolicyRetain {
+				continue
+			}
+			if err := r.releaseStaticAddress(staticAddressName(r.machine.Name, i, address, addressType)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) releaseStaticAddress(name string) error {
+	gceAddress, err := r.computeService.AddressesGet(r.projectID, r.providerSpec.Region, name)
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get address %s: %w", name, err)
+	}
+	if !util.IsOwnedByCluster(gceAddress.Labels, r.machine.Labels[machinev1.MachineClusterIDLabel]) {
+		klog.Infof("%s: address %s was not reserved for the cluster, keeping it", r.machine.Name, name)
+		return nil
+	}
+
+	klog.Infof("%s: releasing address %s", r.machine.Name, name)
+	op, err := r.computeService.AddressesDelete(r.projectID, r.providerSpec.Region, name)
+	if err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("failed to release address %s: %w", name, err)
+	}
+	return r.awaitRegionOperation(op)
+}
+
+// isAddressUser tells whether the instance is one of the users of the address.
+func isAddressUser(address *compute.Address, instanceName string) bool {
+	for _, user := range address.Users {
+		if strings.HasSuffix(user, "/instances/"+instanceName) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package machine
+
+import (
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinecontroller "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	compute "google.golang.org/api/compute/v1"
+	googleapi "google.golang.org/api/googleapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	controllerfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newStaticAddressReconciler(mockComputeService *computeservice.GCPComputeServiceMock, nic *machinev1.GCPNetworkInterface) *Reconciler {
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster",
+		},
+		Status: configv1.InfrastructureStatus{
+			PlatformStatus: &configv1.PlatformStatus{
+				Type: configv1.GCPPlatformType,
+				GCP:  &configv1.GCPPlatformStatus{},
+			},
+		},
+	}
+
+	return newReconciler(&machineScope{
+		machine: &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "machine-0",
+				Labels: map[string]string{
+					machinev1.MachineClusterIDLabel: "CLUSTERID",
+				},
+			},
+		},
+		coreClient: controllerfake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(infra).Build(),
+		providerSpec: &machinev1.GCPMachineProviderSpec{
+			Region:            "region1",
+			Zone:              "zone1",
+			NetworkInterfaces: []*machinev1.GCPNetworkInterface{nic},
+		},
+		projectID:      "test",
+		providerStatus: &machinev1.GCPMachineProviderStatus{},
+		computeService: mockComputeService,
+	})
+}
+
+func TestEnsureStaticAddress(t *testing.T) {
+	cases := []struct {
+		name             string
+		address          *machinev1.GCPStaticAddress
+		addressType      string
+		existingAddress  *compute.Address
+		expectedReserved *compute.Address
+		expectedIP       string
+		expectedError    error
+	}{
+		{
+			name:        "Reserve a missing internal address",
+			address:     &machinev1.GCPStaticAddress{Address: "10.0.0.20"},
+			addressType: addressTypeInternal,
+			expectedReserved: &compute.Address{
+				Name:        "machine-0-nic0-internal",
+				AddressType: addressTypeInternal,
+				Address:     "10.0.0.20",
+				Subnetwork:  "subnetwork",
+			},
+			expectedIP: "10.0.0.20",
+		},
+		{
+			name:        "Reserve a missing external address",
+			address:     &machinev1.GCPStaticAddress{Name: "edge-ip"},
+			addressType: addressTypeExternal,
+			expectedReserved: &compute.Address{
+				Name:        "edge-ip",
+				AddressType: addressTypeExternal,
+			},
+			expectedIP: "35.1.2.3",
+		},
+		{
+			name:        "Use an existing address",
+			address:     &machinev1.GCPStaticAddress{Name: "edge-ip"},
+			addressType: addressTypeExternal,
+			existingAddress: &compute.Address{
+				Name:        "edge-ip",
+				AddressType: addressTypeExternal,
+				Address:     "35.1.2.4",
+				Status:      "RESERVED",
+			},
+			expectedIP: "35.1.2.4",
+		},
+		{
+			name:        "Fail when the address is used by another instance",
+			address:     &machinev1.GCPStaticAddress{Name: "edge-ip"},
+			addressType: addressTypeExternal,
+			existingAddress: &compute.Address{
+				Name:    "edge-ip",
+				Address: "35.1.2.4",
+				Status:  addressStatusInUse,
+				Users:   []string{"https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instances/machine-1"},
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("address edge-ip is already in use by https://www.googleapis.com/compute/v1/projects/test/zones/zone1/instances/machine-1"),
+		},
+		{
+			name:        "Fail when the address has another type",
+			address:     &machinev1.GCPStaticAddress{Name: "edge-ip"},
+			addressType: addressTypeInternal,
+			existingAddress: &compute.Address{
+				Name:        "edge-ip",
+				AddressType: addressTypeExternal,
+			},
+			expectedError: machinecontroller.InvalidMachineConfiguration("address edge-ip is an EXTERNAL address, expected an INTERNAL address"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			var reserved *compute.Address
+			mockComputeService.MockAddressesGet = func(project string, region string, address string) (*compute.Address, error) {
+				if tc.existingAddress != nil {
+					return tc.existingAddress, nil
+				}
+				if reserved != nil {
+					ip := reserved.Address
+					if ip == "" {
+						ip = "35.1.2.3"
+					}
+					return &compute.Address{Name: address, Address: ip}, nil
+				}
+				return nil, &googleapi.Error{Code: 404}
+			}
+			mockComputeService.MockAddressesInsert = func(project string, region string, address *compute.Address) (*compute.Operation, error) {
+				reserved = address
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newStaticAddressReconciler(mockComputeService, &machinev1.GCPNetworkInterface{Network: "network"})
+			ip, err := r.ensureStaticAddress(0, tc.address, tc.addressType, "subnetwork", "")
+			if tc.expectedError != nil {
+				if err == nil || err.Error() != tc.expectedError.Error() {
+					t.Errorf("Expected: %v, got: %v", tc.expectedError, err)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+
+			if ip != tc.expectedIP {
+				t.Errorf("Expected IP address %q, got: %q", tc.expectedIP, ip)
+			}
+			if tc.expectedReserved == nil {
+				if reserved != nil {
+					t.Errorf("Expected no address to be reserved, got: %v", reserved)
+				}
+				return
+			}
+			if reserved == nil {
+				t.Fatalf("Expected address %s to be reserved", tc.expectedReserved.Name)
+			}
+			if reserved.Name != tc.expectedReserved.Name || reserved.AddressType != tc.expectedReserved.AddressType ||
+				reserved.Address != tc.expectedReserved.Address || reserved.Subnetwork != tc.expectedReserved.Subnetwork {
+				t.Errorf("Expected reserved address: %+v, got: %+v", tc.expectedReserved, reserved)
+			}
+			if reserved.Labels["kubernetes-io-cluster-CLUSTERID"] != "owned" {
+				t.Errorf("Expected reserved address to be labelled with the cluster ID, got: %v", reserved.Labels)
+			}
+		})
+	}
+}
+
+func TestReleaseStaticAddresses(t *testing.T) {
+	ownedLabels := map[string]string{"kubernetes-io-cluster-CLUSTERID": "owned"}
+
+	cases := []struct {
+		name             string
+		nic              *machinev1.GCPNetworkInterface
+		addressLabels    map[string]string
+		addressNotFound  bool
+		expectedReleased []string
+	}{
+		{
+			name: "Release addresses reserved for the cluster",
+			nic: &machinev1.GCPNetworkInterface{
+				InternalAddress: &machinev1.GCPStaticAddress{},
+			},
+			addressLabels:    ownedLabels,
+			expectedReleased: []string{"machine-0-nic0-internal"},
+		},
+		{
+			name: "Retain addresses with the retain policy",
+			nic: &machinev1.GCPNetworkInterface{
+				ExternalAddress: &machinev1.GCPStaticAddress{Name: "edge-ip", DeletionPolicy: machinev1.AddressDeletionPolicyRetain},
+			},
+			addressLabels: ownedLabels,
+		},
+		{
+			name: "Keep addresses not reserved for the cluster",
+			nic: &machinev1.GCPNetworkInterface{
+				ExternalAddress: &machinev1.GCPStaticAddress{Name: "edge-ip"},
+			},
+		},
+		{
+			name: "Skip addresses already released",
+			nic: &machinev1.GCPNetworkInterface{
+				ExternalAddress: &machinev1.GCPStaticAddress{Name: "edge-ip"},
+			},
+			addressNotFound: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, mockComputeService := computeservice.NewComputeServiceMock()
+			mockComputeService.MockAddressesGet = func(project string, region string, address string) (*compute.Address, error) {
+				if tc.addressNotFound {
+					return nil, &googleapi.Error{Code: 404}
+				}
+				return &compute.Address{Name: address, Labels: tc.addressLabels}, nil
+			}
+			var released []string
+			mockComputeService.MockAddressesDelete = func(project string, region string, address string) (*compute.Operation, error) {
+				released = append(released, address)
+				return &compute.Operation{Status: "DONE"}, nil
+			}
+
+			r := newStaticAddressReconciler(mockComputeService, tc.nic)
+			if err := r.releaseStaticAddresses(); err != nil {
+				t.Errorf("reconciler was not expected to return error: %v", err)
+			}
+			assertStrings(t, "released addresses", tc.expectedReleased, released)
+		})
+	}
+}
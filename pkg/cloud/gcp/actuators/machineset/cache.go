@@ -14,15 +14,25 @@ limitations under the License.
 package machineset
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
+	machinev1 "github.com/openshift/api/machine/v1beta1"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
 	gce "google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
+	"k8s.io/apimachinery/pkg/util/cache"
 	"k8s.io/klog/v2"
 )
 
+// kmsKeysValidationTTL is how long the result of a KMS key validation is reused. The validation reads
+// the keys and the IAM policies of their key rings and projects, which rarely change.
+const kmsKeysValidationTTL = 10 * time.Minute
+
 // machineTypeKey is used to identify MachineType.
 type machineTypeKey struct {
 	zone        string
@@ -67,6 +77,60 @@ func (mc *machineTypesCache) getMachineTypeFromCache(gcpService computeservice.G
 	return mt, nil
 }
 
+// kmsKeysCache is used for caching the results of KMS key validations.
+type kmsKeysCache struct {
+	cacheMutex   sync.Mutex
+	kmsKeysCache *cache.Expiring
+}
+
+// newKMSKeysCache creates empty kmsKeysCache.
+func newKMSKeysCache() *kmsKeysCache {
+	return &kmsKeysCache{
+		kmsKeysCache: cache.NewExpiring(),
+	}
+}
+
+// kmsKeysValidation is the cached result of a KMS key validation.
+type kmsKeysValidation struct {
+	err error
+}
+
+// getKMSKeysValidationFromCache returns the result of the validation of the KMS keys of the providerConfig,
+// validating them again once the previous result expired. Errors other than a KMSKeyError are not cached.
+func (kc *kmsKeysCache) getKMSKeysValidationFromCache(namespace string, providerConfig *machinev1.GCPMachineProviderSpec, validate func() error) error {
+	kc.cacheMutex.Lock()
+	defer kc.cacheMutex.Unlock()
+
+	key := kmsKeysCacheKey(namespace, providerConfig)
+	if validation, ok := kc.kmsKeysCache.Get(key); ok {
+		return validation.(kmsKeysValidation).err
+	}
+
+	err := validate()
+	var keyErr *util.KMSKeyError
+	if err == nil || errors.As(err, &keyErr) {
+		kc.kmsKeysCache.Set(key, kmsKeysValidation{err: err}, kmsKeysValidationTTL)
+	}
+	return err
+}
+
+// kmsKeysCacheKey identifies the KMS keys of the providerConfig, together with the credentials
+// and the project they are validated with.
+func kmsKeysCacheKey(namespace string, providerConfig *machinev1.GCPMachineProviderSpec) string {
+	parts := []string{namespace, providerConfig.ProjectID}
+	if providerConfig.CredentialsSecret != nil {
+		parts = append(parts, providerConfig.CredentialsSecret.Name)
+	}
+	for _, disk := range providerConfig.Disks {
+		if disk.EncryptionKey == nil || disk.EncryptionKey.KMSKey == nil {
+			continue
+		}
+		keyRef := disk.EncryptionKey.KMSKey
+		parts = append(parts, keyRef.ProjectID, keyRef.Location, keyRef.KeyRing, keyRef.Name, disk.EncryptionKey.KMSKeyServiceAccount)
+	}
+	return strings.Join(parts, "/")
+}
+
 func isNotFoundError(err error) bool {
 	switch t := err.(type) {
 	case *googleapi.Error:
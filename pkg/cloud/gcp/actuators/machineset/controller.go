@@ -15,7 +15,9 @@ package machineset
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path"
 	"strconv"
 
 	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
@@ -24,8 +26,12 @@ import (
 	machinev1 "github.com/openshift/api/machine/v1beta1"
 	mapierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
 	mapiutil "github.com/openshift/machine-api-operator/pkg/util"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
+	"google.golang.org/api/compute/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
@@ -52,9 +58,12 @@ type Reconciler struct {
 	recorder record.EventRecorder
 	scheme   *runtime.Scheme
 	cache    *machineTypesCache
+	kmsKeys  *kmsKeysCache
 
 	// Allow a mock GCPComputeService to be injected during testing
 	getGCPService func(namespace string, providerConfig machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, error)
+	// Allow a mock KMSService to be injected during testing, together with the project the machines are created in
+	getKMSService func(ctx context.Context, namespace string, providerConfig machinev1.GCPMachineProviderSpec) (kmsservice.KMSService, string, error)
 }
 
 // SetupWithManager creates a new controller for a manager.
@@ -69,12 +78,16 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager, options controller.Optio
 	}
 
 	r.cache = newMachineTypesCache()
+	r.kmsKeys = newKMSKeysCache()
 	r.recorder = mgr.GetEventRecorderFor("machineset-controller")
 	r.scheme = mgr.GetScheme()
 
 	if r.getGCPService == nil {
 		r.getGCPService = r.getRealGCPService
 	}
+	if r.getKMSService == nil {
+		r.getKMSService = r.getRealKMSService
+	}
 	return nil
 }
 
@@ -99,15 +112,23 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	if !machineSet.DeletionTimestamp.IsZero() {
 		return ctrl.Result{}, nil
 	}
-	originalMachineSetToPatch := client.MergeFrom(machineSet.DeepCopy())
+	originalMachineSet := machineSet.DeepCopy()
+	originalMachineSetToPatch := client.MergeFrom(originalMachineSet)
 
-	result, err := r.reconcile(machineSet)
+	result, err := r.reconcile(ctx, machineSet)
 	if err != nil {
 		logger.Error(err, "Failed to reconcile MachineSet")
 		r.recorder.Eventf(machineSet, corev1.EventTypeWarning, "ReconcileError", "%v", err)
 		// we don't return here so we want to attempt to patch the machine regardless of an error.
 	}
 
+	// Patch the status first, the spec patch overwrites the local status with the stored one.
+	if !equality.Semantic.DeepEqual(originalMachineSet.Status, machineSet.Status) {
+		if err := r.Client.Status().Patch(ctx, machineSet.DeepCopy(), originalMachineSetToPatch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to patch machineSet status: %v", err)
+		}
+	}
+
 	if err := r.Client.Patch(ctx, machineSet, originalMachineSetToPatch); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to patch machineSet: %v", err)
 	}
@@ -131,7 +152,7 @@ func isInvalidConfigurationError(err error) bool {
 	return false
 }
 
-func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, error) {
+func (r *Reconciler) reconcile(ctx context.Context, machineSet *machinev1.MachineSet) (ctrl.Result, error) {
 	providerConfig, err := getproviderConfig(machineSet)
 	if err != nil {
 		return ctrl.Result{}, mapierrors.InvalidMachineConfiguration("failed to get providerConfig: %v", err)
@@ -142,9 +163,23 @@ func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, e
 		return ctrl.Result{}, err
 	}
 
-	machineType, err := r.cache.getMachineTypeFromCache(gceService, providerConfig.ProjectID, providerConfig.Zone, providerConfig.MachineType)
+	// The machine type and accelerators the providerSpec does not set are the ones of the source instance template
+	machineTypeName := providerConfig.MachineType
+	var templateAccelerators []*compute.AcceleratorConfig
+	if providerConfig.SourceInstanceTemplate != "" {
+		template, _, err := util.GetInstanceTemplate(gceService, providerConfig.ProjectID, providerConfig)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("error fetching source instance template %q: %w", providerConfig.SourceInstanceTemplate, err)
+		}
+		if machineTypeName == "" {
+			machineTypeName = path.Base(template.Properties.MachineType)
+		}
+		templateAccelerators = template.Properties.GuestAccelerators
+	}
+
+	machineType, err := r.cache.getMachineTypeFromCache(gceService, providerConfig.ProjectID, providerConfig.Zone, machineTypeName)
 	if err != nil {
-		return ctrl.Result{}, mapierrors.InvalidMachineConfiguration("error fetching machine type %q: %v", providerConfig.MachineType, err)
+		return ctrl.Result{}, mapierrors.InvalidMachineConfiguration("error fetching machine type %q: %v", machineTypeName, err)
 	} else if machineType == nil {
 		// Returning no error to prevent further reconciliation, as user intervention is now required but emit an informational event
 		r.recorder.Eventf(machineSet, corev1.EventTypeWarning, "FailedUpdate", "Failed to set autoscaling from zero annotations, machine type unknown")
@@ -163,6 +198,8 @@ func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, e
 	case len(providerConfig.GPUs) > 0:
 		// Guest accelerators will always be max size of 1
 		machineSet.Annotations[gpuKey] = strconv.FormatInt(int64(providerConfig.GPUs[0].Count), 10)
+	case len(templateAccelerators) > 0:
+		machineSet.Annotations[gpuKey] = strconv.FormatInt(templateAccelerators[0].AcceleratorCount, 10)
 	case len(machineType.Accelerators) > 0:
 		// Accelerators will always be max size of 1
 		machineSet.Annotations[gpuKey] = strconv.FormatInt(machineType.Accelerators[0].GuestAcceleratorCount, 10)
@@ -173,7 +210,7 @@ func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, e
 	// We guarantee that any existing labels provided via the capacity annotations are preserved.
 	// See https://github.com/kubernetes/autoscaler/pull/5382 and https://github.com/kubernetes/autoscaler/pull/5697
 	machineSet.Annotations[labelsKey] = mapiutil.MergeCommaSeparatedKeyValuePairs(
-		fmt.Sprintf("kubernetes.io/arch=%s", util.CPUArchitecture(providerConfig.MachineType)),
+		fmt.Sprintf("kubernetes.io/arch=%s", util.CPUArchitecture(machineTypeName)),
 		machineSet.Annotations[labelsKey])
 
 	// OCP 4.12 and under did not have the ShieldedInstanceConfig field. From OCP
@@ -182,6 +219,10 @@ func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, e
 	// new default. This may present as customers being unable to scale existing
 	// machinesets. We should disable the shielded instance config in the
 	// MachineSet's template, so that new Machines created from it will boot.
+	// The boot disk of a source instance template is left to the template.
+	if providerConfig.SourceInstanceTemplate != "" && len(providerConfig.Disks) == 0 {
+		return ctrl.Result{}, r.reconcileKMSKeys(ctx, machineSet, providerConfig)
+	}
 	uefiCompatible, err := util.IsUEFICompatible(gceService, providerConfig)
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("error fetching disk information: %s", err)
@@ -201,7 +242,53 @@ func (r *Reconciler) reconcile(machineSet *machinev1.MachineSet) (ctrl.Result, e
 
 		machineSet.Spec.Template.Spec.ProviderSpec.Value = ext
 	}
-	return ctrl.Result{}, nil
+
+	return ctrl.Result{}, r.reconcileKMSKeys(ctx, machineSet, providerConfig)
+}
+
+// reconcileKMSKeys verifies the KMS keys encrypting the disks can be used by new Machines,
+// and reports it in the KMSKeyAccessible condition of the MachineSet. The result of the
+// verification is reused for kmsKeysValidationTTL by the MachineSets using the same keys.
+func (r *Reconciler) reconcileKMSKeys(ctx context.Context, machineSet *machinev1.MachineSet, providerConfig *machinev1.GCPMachineProviderSpec) error {
+	usesKMSKeys := false
+	for _, disk := range providerConfig.Disks {
+		if disk.EncryptionKey != nil && disk.EncryptionKey.KMSKey != nil {
+			usesKMSKeys = true
+			break
+		}
+	}
+	if !usesKMSKeys {
+		return nil
+	}
+
+	var serviceErr error
+	err := r.kmsKeys.getKMSKeysValidationFromCache(machineSet.GetNamespace(), providerConfig, func() error {
+		kmsService, projectID, err := r.getKMSService(ctx, machineSet.GetNamespace(), *providerConfig)
+		if err != nil {
+			serviceErr = err
+			return err
+		}
+		return util.ValidateKMSKeys(ctx, kmsService, projectID, providerConfig)
+	})
+	if serviceErr != nil {
+		return serviceErr
+	}
+	var keyErr *util.KMSKeyError
+	if errors.As(err, &keyErr) {
+		severity := machinev1.ConditionSeverityError
+		if keyErr.Warning {
+			severity = machinev1.ConditionSeverityWarning
+		}
+		conditions.MarkFalse(machineSet, util.KMSKeyAccessibleCondition, keyErr.Reason, severity, "%s", keyErr.Message)
+		r.recorder.Eventf(machineSet, corev1.EventTypeWarning, keyErr.Reason, "%s", keyErr.Message)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to validate KMS keys: %w", err)
+	}
+
+	conditions.MarkTrue(machineSet, util.KMSKeyAccessibleCondition)
+	return nil
 }
 
 func getproviderConfig(machineSet *machinev1.MachineSet) (*machinev1.GCPMachineProviderSpec, error) {
@@ -221,3 +308,26 @@ func (r *Reconciler) getRealGCPService(namespace string, providerConfig machinev
 	}
 	return computeService, nil
 }
+
+// getRealKMSService returns a real KMSService for talking to GCP, shared by the reconciliations
+// with the same credentials, together with the project the machines are created in
+func (r *Reconciler) getRealKMSService(ctx context.Context, namespace string, providerConfig machinev1.GCPMachineProviderSpec) (kmsservice.KMSService, string, error) {
+	serviceAccountJSON, err := util.GetCredentialsSecret(r.Client, namespace, providerConfig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	projectID := providerConfig.ProjectID
+	if len(projectID) == 0 {
+		projectID, err = util.GetProjectIDFromJSONKey([]byte(serviceAccountJSON))
+		if err != nil {
+			return nil, "", mapierrors.InvalidMachineConfiguration("error getting project from JSON key: %v", err)
+		}
+	}
+
+	kmsService, err := kmsservice.NewCachedKMSService(ctx, serviceAccountJSON)
+	if err != nil {
+		return nil, "", mapierrors.InvalidMachineConfiguration("error creating kms service: %v", err)
+	}
+	return kmsService, projectID, nil
+}
@@ -26,7 +26,12 @@ import (
 	. "github.com/onsi/gomega"
 	gtypes "github.com/onsi/gomega/types"
 	machinev1 "github.com/openshift/api/machine/v1beta1"
+	"github.com/openshift/machine-api-operator/pkg/util/conditions"
 	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
 	"google.golang.org/api/compute/v1"
 	"google.golang.org/api/googleapi"
 	corev1 "k8s.io/api/core/v1"
@@ -425,6 +430,7 @@ func TestReconcile(t *testing.T) {
 			r := &Reconciler{
 				recorder: record.NewFakeRecorder(1),
 				cache:    newMachineTypesCache(),
+				kmsKeys:  newKMSKeysCache(),
 				getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, error) {
 					return service, nil
 				},
@@ -440,7 +446,7 @@ func TestReconcile(t *testing.T) {
 			machineSet, err := newTestMachineSet("default", tc.machineType, tc.guestAccelerators, tc.existingAnnotations, disks)
 			g.Expect(err).ToNot(HaveOccurred())
 
-			_, err = r.reconcile(machineSet)
+			_, err = r.reconcile(context.Background(), machineSet)
 			g.Expect(err != nil).To(Equal(tc.expectErr))
 			g.Expect(machineSet.Annotations).To(Equal(tc.expectedAnnotations))
 		})
@@ -479,12 +485,13 @@ func TestReconcileDisks(t *testing.T) {
 			r := &Reconciler{
 				recorder: record.NewFakeRecorder(1),
 				cache:    newMachineTypesCache(),
+				kmsKeys:  newKMSKeysCache(),
 				getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, error) {
 					return service, nil
 				},
 			}
 
-			_, err = r.reconcile(machineSet)
+			_, err = r.reconcile(context.Background(), machineSet)
 			g.Expect(err).NotTo(HaveOccurred())
 
 			providerConfig, err := getproviderConfig(machineSet)
@@ -504,6 +511,195 @@ func TestReconcileDisks(t *testing.T) {
 	}
 }
 
+func TestReconcileInstanceTemplate(t *testing.T) {
+	testCases := []struct {
+		name                string
+		machineType         string
+		template            *compute.InstanceProperties
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:     "machine type of the template",
+			template: &compute.InstanceProperties{MachineType: "a2-highgpu-2g"},
+			expectedAnnotations: map[string]string{
+				cpuKey:    "24",
+				memoryKey: "174080",
+				gpuKey:    "2",
+				labelsKey: "kubernetes.io/arch=amd64",
+			},
+		},
+		{
+			name: "accelerators of the template",
+			template: &compute.InstanceProperties{
+				MachineType:       "n1-standard-2",
+				GuestAccelerators: []*compute.AcceleratorConfig{{AcceleratorType: "nvidia-tesla-t4", AcceleratorCount: 4}},
+			},
+			expectedAnnotations: map[string]string{
+				cpuKey:    "2",
+				memoryKey: "7680",
+				gpuKey:    "4",
+				labelsKey: "kubernetes.io/arch=amd64",
+			},
+		},
+		{
+			name:        "machine type overriding the template",
+			machineType: "t2a-standard-2",
+			template:    &compute.InstanceProperties{MachineType: "n2-highcpu-16"},
+			expectedAnnotations: map[string]string{
+				cpuKey:    "2",
+				memoryKey: "7680",
+				gpuKey:    "0",
+				labelsKey: "kubernetes.io/arch=arm64",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			g := NewWithT(tt)
+
+			_, service := computeservice.NewComputeServiceMock()
+			service.MockMachineTypesGet = mockMachineTypesFunc
+			service.MockInstanceTemplatesGet = func(_ string, instanceTemplate string) (*compute.InstanceTemplate, error) {
+				return &compute.InstanceTemplate{Name: instanceTemplate, Properties: tc.template}, nil
+			}
+			r := &Reconciler{
+				recorder: record.NewFakeRecorder(1),
+				cache:    newMachineTypesCache(),
+				kmsKeys:  newKMSKeysCache(),
+				getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, error) {
+					return service, nil
+				},
+			}
+
+			providerSpec, err := providerSpecFromMachine(&machinev1.GCPMachineProviderSpec{
+				MachineType:            tc.machineType,
+				SourceInstanceTemplate: "workers",
+			})
+			g.Expect(err).ToNot(HaveOccurred())
+			machineSet := &machinev1.MachineSet{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "test-machineset-",
+					Namespace:    "default",
+				},
+				Spec: machinev1.MachineSetSpec{
+					Template: machinev1.MachineTemplateSpec{
+						Spec: machinev1.MachineSpec{
+							ProviderSpec: providerSpec,
+						},
+					},
+				},
+			}
+
+			_, err = r.reconcile(context.Background(), machineSet)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(machineSet.Annotations).To(Equal(tc.expectedAnnotations))
+		})
+	}
+}
+
+func TestReconcileKMSKeys(t *testing.T) {
+	keyName := "projects/project/locations/global/keyRings/kms-ring/cryptoKeys/kms-key"
+
+	testCases := []struct {
+		name                     string
+		mockCryptoKeysGet        func(ctx context.Context, name string) (*cloudkms.CryptoKey, error)
+		mockProjectsGetIamPolicy func(ctx context.Context, projectID string) (*resourcemanager.Policy, error)
+		expectedStatus           corev1.ConditionStatus
+		expectedSeverity         machinev1.ConditionSeverity
+		expectedReason           string
+		expectedMessage          string
+	}{
+		{
+			name:           "usable key",
+			expectedStatus: corev1.ConditionTrue,
+		},
+		{
+			name: "key not found",
+			mockCryptoKeysGet: func(ctx context.Context, name string) (*cloudkms.CryptoKey, error) {
+				return nil, &googleapi.Error{Code: 404}
+			},
+			expectedStatus:   corev1.ConditionFalse,
+			expectedSeverity: machinev1.ConditionSeverityError,
+			expectedReason:   util.KMSKeyNotFoundReason,
+			expectedMessage:  fmt.Sprintf("KMS key %s not found", keyName),
+		},
+		{
+			name: "key primary version destroyed",
+			mockCryptoKeysGet: func(ctx context.Context, name string) (*cloudkms.CryptoKey, error) {
+				return &cloudkms.CryptoKey{Name: name, Primary: &cloudkms.CryptoKeyVersion{State: "DESTROYED"}}, nil
+			},
+			expectedStatus:   corev1.ConditionFalse,
+			expectedSeverity: machinev1.ConditionSeverityError,
+			expectedReason:   util.KMSKeyDisabledReason,
+			expectedMessage:  fmt.Sprintf("primary version of KMS key %s is destroyed", keyName),
+		},
+		{
+			name: "role binding not found",
+			mockProjectsGetIamPolicy: func(ctx context.Context, projectID string) (*resourcemanager.Policy, error) {
+				return &resourcemanager.Policy{}, nil
+			},
+			expectedStatus:   corev1.ConditionFalse,
+			expectedSeverity: machinev1.ConditionSeverityWarning,
+			expectedReason:   util.KMSKeyAccessUnverifiedReason,
+			expectedMessage:  fmt.Sprintf("no role binding grants roles/cloudkms.cryptoKeyEncrypterDecrypter on KMS key %s to service account service-%s@compute-system.iam.gserviceaccount.com", keyName, kmsservice.MockProjectNumber),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			g := NewWithT(tt)
+			disks := []*machinev1.GCPDisk{
+				{
+					Boot:  true,
+					Image: "uefi-image",
+					EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+						KMSKey: &machinev1.GCPKMSKeyReference{
+							Name:     "kms-key",
+							KeyRing:  "kms-ring",
+							Location: "global",
+						},
+					},
+				},
+			}
+			machineSet, err := newTestMachineSet("default", "n1-standard-2", nil, make(map[string]string), disks)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			_, service := computeservice.NewComputeServiceMock()
+			service.MockMachineTypesGet = mockMachineTypesFunc
+			kmsService := kmsservice.NewMockKMSService()
+			kmsService.MockCryptoKeysGet = tc.mockCryptoKeysGet
+			kmsService.MockProjectsGetIamPolicy = tc.mockProjectsGetIamPolicy
+			kmsServiceBuilds := 0
+			r := &Reconciler{
+				recorder: record.NewFakeRecorder(2),
+				cache:    newMachineTypesCache(),
+				kmsKeys:  newKMSKeysCache(),
+				getGCPService: func(_ string, _ machinev1.GCPMachineProviderSpec) (computeservice.GCPComputeService, error) {
+					return service, nil
+				},
+				getKMSService: func(_ context.Context, _ string, _ machinev1.GCPMachineProviderSpec) (kmsservice.KMSService, string, error) {
+					kmsServiceBuilds++
+					return kmsService, "project", nil
+				},
+			}
+
+			_, err = r.reconcile(context.Background(), machineSet)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// The result of the validation is reused by the next reconciliations
+			_, err = r.reconcile(context.Background(), machineSet)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(kmsServiceBuilds).To(Equal(1))
+
+			condition := conditions.Get(machineSet, util.KMSKeyAccessibleCondition)
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Status).To(Equal(tc.expectedStatus))
+			g.Expect(condition.Severity).To(Equal(tc.expectedSeverity))
+			g.Expect(condition.Reason).To(Equal(tc.expectedReason))
+			g.Expect(condition.Message).To(Equal(tc.expectedMessage))
+		})
+	}
+}
+
 func newTestMachineSet(namespace string, machineType string, guestAccelerators []machinev1.GCPGPUConfig, existingAnnotations map[string]string, disks []*machinev1.GCPDisk) (*machinev1.MachineSet, error) {
 	// Copy anntotations map so we don't modify the input
 	annotations := make(map[string]string)
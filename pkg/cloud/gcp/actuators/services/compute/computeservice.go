@@ -35,8 +35,59 @@ type GCPComputeService interface {
 	InstanceGroupsRemoveInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error)
 	InstanceGroupInsert(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error)
 	InstanceGroupGet(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error)
-	AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error)
+	RegionBackendServicesPatch(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error)
 	BackendServiceGet(project string, region string, backendServiceName string) (*compute.BackendService, error)
+	InstancesDetachDisk(project string, zone string, instance string, deviceName string) (*compute.Operation, error)
+	InstancesSetDiskAutoDelete(project string, zone string, instance string, autoDelete bool, deviceName string) (*compute.Operation, error)
+	DisksCreateSnapshot(project string, zone string, disk string, snapshot *compute.Snapshot) (*compute.Operation, error)
+	SnapshotsGet(project string, snapshot string) (*compute.Snapshot, error)
+	DisksGet(project string, zone string, disk string) (*compute.Disk, error)
+	DisksAddResourcePolicies(project string, zone string, disk string, resourcePolicies []string) (*compute.Operation, error)
+	ResourcePoliciesGet(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error)
+	SubnetworksGet(project string, region string, subnetwork string) (*compute.Subnetwork, error)
+	InstancesUpdateNetworkInterface(project string, zone string, instance string, networkInterface string, nic *compute.NetworkInterface) (*compute.Operation, error)
+	AddressesGet(project string, region string, address string) (*compute.Address, error)
+	AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error)
+	AddressesDelete(project string, region string, address string) (*compute.Operation, error)
+	RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error)
+	NetworksGet(project string, network string) (*compute.Network, error)
+	SubnetworksTestIamPermissions(project string, region string, subnetwork string, permissions []string) ([]string, error)
+	InstancesList(ctx context.Context, project string, zone string, filter string) ([]*compute.Instance, error)
+	InstancesAggregatedNetworkInterfaces(ctx context.Context, project string) ([]*compute.NetworkInterface, error)
+	GlobalBackendServiceGet(project string, backendServiceName string) (*compute.BackendService, error)
+	BackendServicesPatch(project string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error)
+	NetworkEndpointGroupsListNetworkEndpoints(ctx context.Context, project string, zone string, networkEndpointGroup string) ([]*compute.NetworkEndpoint, error)
+	NetworkEndpointGroupsAttachNetworkEndpoints(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	BackendServiceGetHealth(project string, region string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error)
+	GlobalOperationsGet(project string, operation string) (*compute.Operation, error)
+	InstanceGroupsDelete(project string, zone string, instanceGroup string) (*compute.Operation, error)
+	GlobalBackendServiceGetHealth(project string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error)
+	NodeGroupsList(ctx context.Context, project string, zone string) ([]*compute.NodeGroup, error)
+	NodeGroupsListNodes(ctx context.Context, project string, zone string, nodeGroup string) ([]*compute.NodeGroupNode, error)
+	ReservationsGet(project string, zone string, reservation string) (*compute.Reservation, error)
+	ResourcePoliciesInsert(project string, region string, resourcePolicy *compute.ResourcePolicy) (*compute.Operation, error)
+	ResourcePoliciesDelete(project string, region string, resourcePolicy string) (*compute.Operation, error)
+	InstancesBulkInsert(project string, zone string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error)
+	RegionInstancesBulkInsert(project string, region string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error)
+	InstanceTemplatesGet(project string, instanceTemplate string) (*compute.InstanceTemplate, error)
+	RegionInstanceTemplatesGet(project string, region string, instanceTemplate string) (*compute.InstanceTemplate, error)
+	InstancesInsertFromTemplate(project string, zone string, instance *compute.Instance, sourceInstanceTemplate string) (*compute.Operation, error)
+	InstanceGroupManagersGet(project string, zone string, instanceGroupManager string) (*compute.InstanceGroupManager, error)
+	InstanceGroupManagersInsert(project string, zone string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error)
+	InstanceGroupManagersDelete(project string, zone string, instanceGroupManager string) (*compute.Operation, error)
+	InstanceGroupManagersCreateInstances(project string, zone string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error)
+	InstanceGroupManagersDeleteInstances(project string, zone string, instanceGroupManager string, instances []string) (*compute.Operation, error)
+	InstanceGroupManagersListManagedInstances(ctx context.Context, project string, zone string, instanceGroupManager string) ([]*compute.ManagedInstance, error)
+	InstanceGroupManagersSetInstanceTemplate(project string, zone string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error)
+	RegionInstanceGroupManagersGet(project string, region string, instanceGroupManager string) (*compute.InstanceGroupManager, error)
+	RegionInstanceGroupManagersInsert(project string, region string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error)
+	RegionInstanceGroupManagersDelete(project string, region string, instanceGroupManager string) (*compute.Operation, error)
+	RegionInstanceGroupManagersCreateInstances(project string, region string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error)
+	RegionInstanceGroupManagersDeleteInstances(project string, region string, instanceGroupManager string, instances []string) (*compute.Operation, error)
+	RegionInstanceGroupManagersListManagedInstances(ctx context.Context, project string, region string, instanceGroupManager string) ([]*compute.ManagedInstance, error)
+	RegionInstanceGroupManagersSetInstanceTemplate(project string, region string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error)
+	InstancesSetLabels(project string, zone string, instance string, labels map[string]string, labelFingerprint string) (*compute.Operation, error)
 }
 
 type computeService struct {
@@ -194,8 +245,10 @@ func (c *computeService) InstanceGroupGet(project string, zone string, instanceG
 	return c.service.InstanceGroups.Get(project, zone, instanceGroupName).Do()
 }
 
-func (c *computeService) AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
-	return c.service.RegionBackendServices.Update(project, region, backendServiceName, backendService).Do()
+// RegionBackendServicesPatch patches the regional backend service. The backend service is
+// expected to only have the fields to patch set, along with its fingerprint.
+func (c *computeService) RegionBackendServicesPatch(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+	return c.service.RegionBackendServices.Patch(project, region, backendServiceName, backendService).Do()
 }
 
 func (c *computeService) BackendServiceGet(project string, region string, backendServiceName string) (*compute.BackendService, error) {
@@ -209,3 +262,315 @@ func (c *computeService) ImageGet(project string, image string) (*compute.Image,
 func (c *computeService) ImageFamilyGet(project string, zone string, family string) (*compute.ImageFamilyView, error) {
 	return c.service.ImageFamilyViews.Get(project, zone, family).Do()
 }
+
+func (c *computeService) InstancesDetachDisk(project string, zone string, instance string, deviceName string) (*compute.Operation, error) {
+	return c.service.Instances.DetachDisk(project, zone, instance, deviceName).Do()
+}
+
+func (c *computeService) InstancesSetDiskAutoDelete(project string, zone string, instance string, autoDelete bool, deviceName string) (*compute.Operation, error) {
+	return c.service.Instances.SetDiskAutoDelete(project, zone, instance, autoDelete, deviceName).Do()
+}
+
+func (c *computeService) DisksCreateSnapshot(project string, zone string, disk string, snapshot *compute.Snapshot) (*compute.Operation, error) {
+	return c.service.Disks.CreateSnapshot(project, zone, disk, snapshot).Do()
+}
+
+func (c *computeService) SnapshotsGet(project string, snapshot string) (*compute.Snapshot, error) {
+	return c.service.Snapshots.Get(project, snapshot).Do()
+}
+
+func (c *computeService) DisksGet(project string, zone string, disk string) (*compute.Disk, error) {
+	return c.service.Disks.Get(project, zone, disk).Do()
+}
+
+func (c *computeService) DisksAddResourcePolicies(project string, zone string, disk string, resourcePolicies []string) (*compute.Operation, error) {
+	request := &compute.DisksAddResourcePoliciesRequest{
+		ResourcePolicies: resourcePolicies,
+	}
+	return c.service.Disks.AddResourcePolicies(project, zone, disk, request).Do()
+}
+
+func (c *computeService) ResourcePoliciesGet(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error) {
+	return c.service.ResourcePolicies.Get(project, region, resourcePolicy).Do()
+}
+
+func (c *computeService) SubnetworksGet(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+	return c.service.Subnetworks.Get(project, region, subnetwork).Do()
+}
+
+func (c *computeService) InstancesUpdateNetworkInterface(project string, zone string, instance string, networkInterface string, nic *compute.NetworkInterface) (*compute.Operation, error) {
+	return c.service.Instances.UpdateNetworkInterface(project, zone, instance, networkInterface, nic).Do()
+}
+
+func (c *computeService) AddressesGet(project string, region string, address string) (*compute.Address, error) {
+	return c.service.Addresses.Get(project, region, address).Do()
+}
+
+func (c *computeService) AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error) {
+	return c.service.Addresses.Insert(project, region, address).Do()
+}
+
+func (c *computeService) AddressesDelete(project string, region string, address string) (*compute.Operation, error) {
+	return c.service.Addresses.Delete(project, region, address).Do()
+}
+
+func (c *computeService) RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error) {
+	return c.service.RegionOperations.Get(project, region, operation).Do()
+}
+
+func (c *computeService) NetworksGet(project string, network string) (*compute.Network, error) {
+	return c.service.Networks.Get(project, network).Do()
+}
+
+func (c *computeService) SubnetworksTestIamPermissions(project string, region string, subnetwork string, permissions []string) ([]string, error) {
+	request := &compute.TestPermissionsRequest{
+		Permissions: permissions,
+	}
+	response, err := c.service.Subnetworks.TestIamPermissions(project, region, subnetwork, request).Do()
+	if err != nil {
+		return nil, err
+	}
+	return response.Permissions, nil
+}
+
+// InstancesList lists the instances of the zone matching the filter.
+func (c *computeService) InstancesList(ctx context.Context, project string, zone string, filter string) ([]*compute.Instance, error) {
+	instances := []*compute.Instance{}
+	err := c.service.Instances.List(project, zone).Filter(filter).Pages(ctx, func(page *compute.InstanceList) error {
+		instances = append(instances, page.Items...)
+		return nil
+	})
+	return instances, err
+}
+
+// InstancesAggregatedNetworkInterfaces lists the network interfaces of the instances of every zone of the project.
+// Only the subnetwork and the alias IP ranges of the network interfaces are read.
+func (c *computeService) InstancesAggregatedNetworkInterfaces(ctx context.Context, project string) ([]*compute.NetworkInterface, error) {
+	networkInterfaces := []*compute.NetworkInterface{}
+	err := c.service.Instances.AggregatedList(project).
+		Fields("nextPageToken", "items/*/instances(networkInterfaces(subnetwork,aliasIpRanges))").
+		Pages(ctx, func(page *compute.InstanceAggregatedList) error {
+			for _, scopedList := range page.Items {
+				for _, instance := range scopedList.Instances {
+					networkInterfaces = append(networkInterfaces, instance.NetworkInterfaces...)
+				}
+			}
+			return nil
+		})
+	return networkInterfaces, err
+}
+
+func (c *computeService) GlobalBackendServiceGet(project string, backendServiceName string) (*compute.BackendService, error) {
+	return c.service.BackendServices.Get(project, backendServiceName).Do()
+}
+
+// BackendServicesPatch patches the global backend service. The backend service is
+// expected to only have the fields to patch set, along with its fingerprint.
+func (c *computeService) BackendServicesPatch(project string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+	return c.service.BackendServices.Patch(project, backendServiceName, backendService).Do()
+}
+
+func (c *computeService) NetworkEndpointGroupsListNetworkEndpoints(ctx context.Context, project string, zone string, networkEndpointGroup string) ([]*compute.NetworkEndpoint, error) {
+	endpoints := []*compute.NetworkEndpoint{}
+	request := &compute.NetworkEndpointGroupsListEndpointsRequest{
+		HealthStatus: "SKIP",
+	}
+	err := c.service.NetworkEndpointGroups.ListNetworkEndpoints(project, zone, networkEndpointGroup, request).Pages(ctx, func(page *compute.NetworkEndpointGroupsListNetworkEndpoints) error {
+		for _, item := range page.Items {
+			endpoints = append(endpoints, item.NetworkEndpoint)
+		}
+		return nil
+	})
+	return endpoints, err
+}
+
+func (c *computeService) NetworkEndpointGroupsAttachNetworkEndpoints(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	request := &compute.NetworkEndpointGroupsAttachEndpointsRequest{
+		NetworkEndpoints: endpoints,
+	}
+	return c.service.NetworkEndpointGroups.AttachNetworkEndpoints(project, zone, networkEndpointGroup, request).Do()
+}
+
+func (c *computeService) NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	request := &compute.NetworkEndpointGroupsDetachEndpointsRequest{
+		NetworkEndpoints: endpoints,
+	}
+	return c.service.NetworkEndpointGroups.DetachNetworkEndpoints(project, zone, networkEndpointGroup, request).Do()
+}
+
+func (c *computeService) BackendServiceGetHealth(project string, region string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error) {
+	request := &compute.ResourceGroupReference{
+		Group: instanceGroup,
+	}
+	return c.service.RegionBackendServices.GetHealth(project, region, backendServiceName, request).Do()
+}
+
+func (c *computeService) GlobalOperationsGet(project string, operation string) (*compute.Operation, error) {
+	return c.service.GlobalOperations.Get(project, operation).Do()
+}
+
+func (c *computeService) InstanceGroupsDelete(project string, zone string, instanceGroup string) (*compute.Operation, error) {
+	return c.service.InstanceGroups.Delete(project, zone, instanceGroup).Do()
+}
+
+func (c *computeService) GlobalBackendServiceGetHealth(project string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error) {
+	request := &compute.ResourceGroupReference{
+		Group: instanceGroup,
+	}
+	return c.service.BackendServices.GetHealth(project, backendServiceName, request).Do()
+}
+
+func (c *computeService) NodeGroupsList(ctx context.Context, project string, zone string) ([]*compute.NodeGroup, error) {
+	nodeGroups := []*compute.NodeGroup{}
+	err := c.service.NodeGroups.List(project, zone).Pages(ctx, func(page *compute.NodeGroupList) error {
+		nodeGroups = append(nodeGroups, page.Items...)
+		return nil
+	})
+	return nodeGroups, err
+}
+
+func (c *computeService) NodeGroupsListNodes(ctx context.Context, project string, zone string, nodeGroup string) ([]*compute.NodeGroupNode, error) {
+	nodes := []*compute.NodeGroupNode{}
+	err := c.service.NodeGroups.ListNodes(project, zone, nodeGroup).Pages(ctx, func(page *compute.NodeGroupsListNodes) error {
+		nodes = append(nodes, page.Items...)
+		return nil
+	})
+	return nodes, err
+}
+
+func (c *computeService) ReservationsGet(project string, zone string, reservation string) (*compute.Reservation, error) {
+	return c.service.Reservations.Get(project, zone, reservation).Do()
+}
+
+func (c *computeService) ResourcePoliciesInsert(project string, region string, resourcePolicy *compute.ResourcePolicy) (*compute.Operation, error) {
+	return c.service.ResourcePolicies.Insert(project, region, resourcePolicy).Do()
+}
+
+func (c *computeService) ResourcePoliciesDelete(project string, region string, resourcePolicy string) (*compute.Operation, error) {
+	return c.service.ResourcePolicies.Delete(project, region, resourcePolicy).Do()
+}
+
+func (c *computeService) InstancesBulkInsert(project string, zone string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error) {
+	return c.service.Instances.BulkInsert(project, zone, resource).Do()
+}
+
+func (c *computeService) RegionInstancesBulkInsert(project string, region string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error) {
+	return c.service.RegionInstances.BulkInsert(project, region, resource).Do()
+}
+
+func (c *computeService) InstanceTemplatesGet(project string, instanceTemplate string) (*compute.InstanceTemplate, error) {
+	return c.service.InstanceTemplates.Get(project, instanceTemplate).Do()
+}
+
+func (c *computeService) RegionInstanceTemplatesGet(project string, region string, instanceTemplate string) (*compute.InstanceTemplate, error) {
+	return c.service.RegionInstanceTemplates.Get(project, region, instanceTemplate).Do()
+}
+
+// InstancesInsertFromTemplate inserts the instance from the source instance template, the properties
+// of the instance overriding the ones of the template.
+func (c *computeService) InstancesInsertFromTemplate(project string, zone string, instance *compute.Instance, sourceInstanceTemplate string) (*compute.Operation, error) {
+	return c.service.Instances.Insert(project, zone, instance).SourceInstanceTemplate(sourceInstanceTemplate).Do()
+}
+
+func (c *computeService) InstanceGroupManagersGet(project string, zone string, instanceGroupManager string) (*compute.InstanceGroupManager, error) {
+	return c.service.InstanceGroupManagers.Get(project, zone, instanceGroupManager).Do()
+}
+
+func (c *computeService) InstanceGroupManagersInsert(project string, zone string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error) {
+	return c.service.InstanceGroupManagers.Insert(project, zone, instanceGroupManager).Do()
+}
+
+func (c *computeService) InstanceGroupManagersDelete(project string, zone string, instanceGroupManager string) (*compute.Operation, error) {
+	return c.service.InstanceGroupManagers.Delete(project, zone, instanceGroupManager).Do()
+}
+
+func (c *computeService) InstanceGroupManagersCreateInstances(project string, zone string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error) {
+	request := &compute.InstanceGroupManagersCreateInstancesRequest{
+		Instances: instances,
+	}
+	return c.service.InstanceGroupManagers.CreateInstances(project, zone, instanceGroupManager, request).Do()
+}
+
+// InstanceGroupManagersDeleteInstances deletes the instances, referenced by URL, of the managed instance group
+// and reduces its target size accordingly. Instances which are not in the group are skipped.
+func (c *computeService) InstanceGroupManagersDeleteInstances(project string, zone string, instanceGroupManager string, instances []string) (*compute.Operation, error) {
+	request := &compute.InstanceGroupManagersDeleteInstancesRequest{
+		Instances:                      instances,
+		SkipInstancesOnValidationError: true,
+	}
+	return c.service.InstanceGroupManagers.DeleteInstances(project, zone, instanceGroupManager, request).Do()
+}
+
+func (c *computeService) InstanceGroupManagersListManagedInstances(ctx context.Context, project string, zone string, instanceGroupManager string) ([]*compute.ManagedInstance, error) {
+	instances := []*compute.ManagedInstance{}
+	err := c.service.InstanceGroupManagers.ListManagedInstances(project, zone, instanceGroupManager).Pages(ctx, func(page *compute.InstanceGroupManagersListManagedInstancesResponse) error {
+		instances = append(instances, page.ManagedInstances...)
+		return nil
+	})
+	return instances, err
+}
+
+func (c *computeService) RegionInstanceGroupManagersGet(project string, region string, instanceGroupManager string) (*compute.InstanceGroupManager, error) {
+	return c.service.RegionInstanceGroupManagers.Get(project, region, instanceGroupManager).Do()
+}
+
+func (c *computeService) RegionInstanceGroupManagersInsert(project string, region string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error) {
+	return c.service.RegionInstanceGroupManagers.Insert(project, region, instanceGroupManager).Do()
+}
+
+func (c *computeService) RegionInstanceGroupManagersDelete(project string, region string, instanceGroupManager string) (*compute.Operation, error) {
+	return c.service.RegionInstanceGroupManagers.Delete(project, region, instanceGroupManager).Do()
+}
+
+func (c *computeService) RegionInstanceGroupManagersCreateInstances(project string, region string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error) {
+	request := &compute.RegionInstanceGroupManagersCreateInstancesRequest{
+		Instances: instances,
+	}
+	return c.service.RegionInstanceGroupManagers.CreateInstances(project, region, instanceGroupManager, request).Do()
+}
+
+// RegionInstanceGroupManagersDeleteInstances deletes the instances, referenced by URL, of the regional managed
+// instance group and reduces its target size accordingly. Instances which are not in the group are skipped.
+func (c *computeService) RegionInstanceGroupManagersDeleteInstances(project string, region string, instanceGroupManager string, instances []string) (*compute.Operation, error) {
+	request := &compute.RegionInstanceGroupManagersDeleteInstancesRequest{
+		Instances:                      instances,
+		SkipInstancesOnValidationError: true,
+	}
+	return c.service.RegionInstanceGroupManagers.DeleteInstances(project, region, instanceGroupManager, request).Do()
+}
+
+func (c *computeService) RegionInstanceGroupManagersListManagedInstances(ctx context.Context, project string, region string, instanceGroupManager string) ([]*compute.ManagedInstance, error) {
+	instances := []*compute.ManagedInstance{}
+	err := c.service.RegionInstanceGroupManagers.ListManagedInstances(project, region, instanceGroupManager).Pages(ctx, func(page *compute.RegionInstanceGroupManagersListInstancesResponse) error {
+		instances = append(instances, page.ManagedInstances...)
+		return nil
+	})
+	return instances, err
+}
+
+// InstanceGroupManagersSetInstanceTemplate sets the instance template the managed instance group creates
+// its new instances from.
+func (c *computeService) InstanceGroupManagersSetInstanceTemplate(project string, zone string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error) {
+	request := &compute.InstanceGroupManagersSetInstanceTemplateRequest{
+		InstanceTemplate: instanceTemplate,
+	}
+	return c.service.InstanceGroupManagers.SetInstanceTemplate(project, zone, instanceGroupManager, request).Do()
+}
+
+// RegionInstanceGroupManagersSetInstanceTemplate sets the instance template the regional managed instance group
+// creates its new instances from.
+func (c *computeService) RegionInstanceGroupManagersSetInstanceTemplate(project string, region string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error) {
+	request := &compute.RegionInstanceGroupManagersSetTemplateRequest{
+		InstanceTemplate: instanceTemplate,
+	}
+	return c.service.RegionInstanceGroupManagers.SetInstanceTemplate(project, region, instanceGroupManager, request).Do()
+}
+
+// InstancesSetLabels replaces the labels of the instance, which were read with the label fingerprint.
+func (c *computeService) InstancesSetLabels(project string, zone string, instance string, labels map[string]string, labelFingerprint string) (*compute.Operation, error) {
+	request := &compute.InstancesSetLabelsRequest{
+		Labels:           labels,
+		LabelFingerprint: labelFingerprint,
+	}
+	return c.service.Instances.SetLabels(project, zone, instance, request).Do()
+}
@@ -25,15 +25,79 @@ const (
 	PatchBackendService            = "patchBackendService"
 	AddGroupSuccessfully           = "addGroupSuccessfully"
 	UEFICompatible                 = "UEFI_COMPATIBLE"
+	LargeImage                     = "large-image"
+	ARM64Image                     = "arm64-image"
+	DeprecatedImage                = "deprecated-image"
+	ObsoleteImage                  = "obsolete-image"
+	GVNICImage                     = "gvnic-image"
 )
 
 type GCPComputeServiceMock struct {
-	MockGPUCompatibleMachineTypesList func(project string, zone string, ctx context.Context) (map[string]GpuInfo, []string)
-	MockInstancesInsert               func(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
-	MockMachineTypesGet               func(project string, zone string, machineType string) (*compute.MachineType, error)
-	MockRegionGet                     func(project string, region string) (*compute.Region, error)
-	mockZoneOperationsGet             func(project string, zone string, operation string) (*compute.Operation, error)
-	mockInstancesGet                  func(project string, zone string, instance string) (*compute.Instance, error)
+	MockGPUCompatibleMachineTypesList                   func(project string, zone string, ctx context.Context) (map[string]GpuInfo, []string)
+	MockInstancesInsert                                 func(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
+	MockMachineTypesGet                                 func(project string, zone string, machineType string) (*compute.MachineType, error)
+	MockRegionGet                                       func(project string, region string) (*compute.Region, error)
+	MockZoneOperationsGet                               func(project string, zone string, operation string) (*compute.Operation, error)
+	MockInstancesGet                                    func(project string, zone string, instance string) (*compute.Instance, error)
+	MockInstancesDelete                                 func(requestId string, project string, zone string, instance string) (*compute.Operation, error)
+	MockInstancesDetachDisk                             func(project string, zone string, instance string, deviceName string) (*compute.Operation, error)
+	MockInstancesSetDiskAutoDelete                      func(project string, zone string, instance string, autoDelete bool, deviceName string) (*compute.Operation, error)
+	MockDisksCreateSnapshot                             func(project string, zone string, disk string, snapshot *compute.Snapshot) (*compute.Operation, error)
+	MockSnapshotsGet                                    func(project string, snapshot string) (*compute.Snapshot, error)
+	MockDisksGet                                        func(project string, zone string, disk string) (*compute.Disk, error)
+	MockDisksAddResourcePolicies                        func(project string, zone string, disk string, resourcePolicies []string) (*compute.Operation, error)
+	MockResourcePoliciesGet                             func(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error)
+	MockSubnetworksGet                                  func(project string, region string, subnetwork string) (*compute.Subnetwork, error)
+	MockInstancesUpdateNetworkInterface                 func(project string, zone string, instance string, networkInterface string, nic *compute.NetworkInterface) (*compute.Operation, error)
+	MockAddressesGet                                    func(project string, region string, address string) (*compute.Address, error)
+	MockAddressesInsert                                 func(project string, region string, address *compute.Address) (*compute.Operation, error)
+	MockAddressesDelete                                 func(project string, region string, address string) (*compute.Operation, error)
+	MockRegionOperationsGet                             func(project string, region string, operation string) (*compute.Operation, error)
+	MockNetworksGet                                     func(project string, network string) (*compute.Network, error)
+	MockSubnetworksTestIamPermissions                   func(project string, region string, subnetwork string, permissions []string) ([]string, error)
+	MockInstancesList                                   func(ctx context.Context, project string, zone string, filter string) ([]*compute.Instance, error)
+	MockInstancesAggregatedNetworkInterfaces            func(ctx context.Context, project string) ([]*compute.NetworkInterface, error)
+	MockInstanceGroupGet                                func(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error)
+	MockInstanceGroupInsert                             func(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error)
+	MockInstanceGroupsListInstances                     func(project string, zone string, instanceGroup string, request *compute.InstanceGroupsListInstancesRequest) (*compute.InstanceGroupsListInstances, error)
+	MockInstanceGroupsAddInstances                      func(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error)
+	MockInstanceGroupsRemoveInstances                   func(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error)
+	MockBackendServiceGet                               func(project string, region string, backendServiceName string) (*compute.BackendService, error)
+	MockRegionBackendServicesPatch                      func(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error)
+	MockGlobalBackendServiceGet                         func(project string, backendServiceName string) (*compute.BackendService, error)
+	MockBackendServicesPatch                            func(project string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error)
+	MockNetworkEndpointGroupsListNetworkEndpoints       func(ctx context.Context, project string, zone string, networkEndpointGroup string) ([]*compute.NetworkEndpoint, error)
+	MockNetworkEndpointGroupsAttachNetworkEndpoints     func(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	MockNetworkEndpointGroupsDetachNetworkEndpoints     func(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error)
+	MockBackendServiceGetHealth                         func(project string, region string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error)
+	MockGlobalOperationsGet                             func(project string, operation string) (*compute.Operation, error)
+	MockInstanceGroupsDelete                            func(project string, zone string, instanceGroup string) (*compute.Operation, error)
+	MockGlobalBackendServiceGetHealth                   func(project string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error)
+	MockNodeGroupsList                                  func(ctx context.Context, project string, zone string) ([]*compute.NodeGroup, error)
+	MockNodeGroupsListNodes                             func(ctx context.Context, project string, zone string, nodeGroup string) ([]*compute.NodeGroupNode, error)
+	MockReservationsGet                                 func(project string, zone string, reservation string) (*compute.Reservation, error)
+	MockResourcePoliciesInsert                          func(project string, region string, resourcePolicy *compute.ResourcePolicy) (*compute.Operation, error)
+	MockResourcePoliciesDelete                          func(project string, region string, resourcePolicy string) (*compute.Operation, error)
+	MockInstancesBulkInsert                             func(project string, zone string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error)
+	MockRegionInstancesBulkInsert                       func(project string, region string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error)
+	MockInstanceTemplatesGet                            func(project string, instanceTemplate string) (*compute.InstanceTemplate, error)
+	MockRegionInstanceTemplatesGet                      func(project string, region string, instanceTemplate string) (*compute.InstanceTemplate, error)
+	MockInstancesInsertFromTemplate                     func(project string, zone string, instance *compute.Instance, sourceInstanceTemplate string) (*compute.Operation, error)
+	MockInstanceGroupManagersGet                        func(project string, zone string, instanceGroupManager string) (*compute.InstanceGroupManager, error)
+	MockInstanceGroupManagersInsert                     func(project string, zone string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error)
+	MockInstanceGroupManagersDelete                     func(project string, zone string, instanceGroupManager string) (*compute.Operation, error)
+	MockInstanceGroupManagersCreateInstances            func(project string, zone string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error)
+	MockInstanceGroupManagersDeleteInstances            func(project string, zone string, instanceGroupManager string, instances []string) (*compute.Operation, error)
+	MockInstanceGroupManagersListManagedInstances       func(ctx context.Context, project string, zone string, instanceGroupManager string) ([]*compute.ManagedInstance, error)
+	MockRegionInstanceGroupManagersGet                  func(project string, region string, instanceGroupManager string) (*compute.InstanceGroupManager, error)
+	MockRegionInstanceGroupManagersInsert               func(project string, region string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error)
+	MockRegionInstanceGroupManagersDelete               func(project string, region string, instanceGroupManager string) (*compute.Operation, error)
+	MockRegionInstanceGroupManagersCreateInstances      func(project string, region string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error)
+	MockRegionInstanceGroupManagersDeleteInstances      func(project string, region string, instanceGroupManager string, instances []string) (*compute.Operation, error)
+	MockRegionInstanceGroupManagersListManagedInstances func(ctx context.Context, project string, region string, instanceGroupManager string) ([]*compute.ManagedInstance, error)
+	MockInstanceGroupManagersSetInstanceTemplate        func(project string, zone string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error)
+	MockRegionInstanceGroupManagersSetInstanceTemplate  func(project string, region string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error)
+	MockInstancesSetLabels                              func(project string, zone string, instance string, labels map[string]string, labelFingerprint string) (*compute.Operation, error)
 }
 
 func (c *GCPComputeServiceMock) InstancesInsert(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
@@ -44,20 +108,23 @@ func (c *GCPComputeServiceMock) InstancesInsert(project string, zone string, ins
 }
 
 func (c *GCPComputeServiceMock) InstancesDelete(requestId string, project string, zone string, instance string) (*compute.Operation, error) {
+	if c.MockInstancesDelete != nil {
+		return c.MockInstancesDelete(requestId, project, zone, instance)
+	}
 	return &compute.Operation{
 		Status: "DONE",
 	}, nil
 }
 
 func (c *GCPComputeServiceMock) ZoneOperationsGet(project string, zone string, operation string) (*compute.Operation, error) {
-	if c.mockZoneOperationsGet == nil {
+	if c.MockZoneOperationsGet == nil {
 		return nil, nil
 	}
-	return c.mockZoneOperationsGet(project, zone, operation)
+	return c.MockZoneOperationsGet(project, zone, operation)
 }
 
 func (c *GCPComputeServiceMock) InstancesGet(project string, zone string, instance string) (*compute.Instance, error) {
-	if c.mockInstancesGet == nil {
+	if c.MockInstancesGet == nil {
 		return &compute.Instance{
 			Name:         instance,
 			Zone:         zone,
@@ -76,7 +143,7 @@ func (c *GCPComputeServiceMock) InstancesGet(project string, zone string, instan
 			Status: "RUNNING",
 		}, nil
 	}
-	return c.mockInstancesGet(project, zone, instance)
+	return c.MockInstancesGet(project, zone, instance)
 }
 
 func (c *GCPComputeServiceMock) ZonesGet(project string, zone string) (*compute.Zone, error) {
@@ -125,7 +192,7 @@ func NewComputeServiceMock() (*compute.Instance, *GCPComputeServiceMock) {
 				Status: "DONE",
 			}, nil
 		},
-		mockZoneOperationsGet: func(project string, zone string, operation string) (*compute.Operation, error) {
+		MockZoneOperationsGet: func(project string, zone string, operation string) (*compute.Operation, error) {
 			return &compute.Operation{
 				Status: "DONE",
 			}, nil
@@ -141,7 +208,7 @@ func MockBuilderFuncType(serviceAccountJSON string) (GCPComputeService, error) {
 
 func MockBuilderFuncTypeNotFound(serviceAccountJSON string) (GCPComputeService, error) {
 	_, computeSvc := NewComputeServiceMock()
-	computeSvc.mockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
+	computeSvc.MockInstancesGet = func(project string, zone string, instance string) (*compute.Instance, error) {
 		return nil, &googleapi.Error{
 			Code: 404,
 		}
@@ -171,6 +238,9 @@ func (c *GCPComputeServiceMock) AcceleratorTypeGet(project string, zone string,
 }
 
 func (c *GCPComputeServiceMock) InstanceGroupsListInstances(projectID string, zone string, instanceGroup string, request *compute.InstanceGroupsListInstancesRequest) (*compute.InstanceGroupsListInstances, error) {
+	if c.MockInstanceGroupsListInstances != nil {
+		return c.MockInstanceGroupsListInstances(projectID, zone, instanceGroup, request)
+	}
 	if projectID == GroupDoesNotExist {
 		return nil, &googleapi.Error{
 			Code: 404,
@@ -199,6 +269,9 @@ func (c *GCPComputeServiceMock) InstanceGroupsListInstances(projectID string, zo
 }
 
 func (c *GCPComputeServiceMock) InstanceGroupsAddInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+	if c.MockInstanceGroupsAddInstances != nil {
+		return c.MockInstanceGroupsAddInstances(project, zone, instance, instanceGroup)
+	}
 	if project == ErrRegisteringInstance {
 		return nil, errors.New("a GCP error")
 	}
@@ -208,6 +281,9 @@ func (c *GCPComputeServiceMock) InstanceGroupsAddInstances(project string, zone
 }
 
 func (c *GCPComputeServiceMock) InstanceGroupsRemoveInstances(project string, zone string, instance string, instanceGroup string) (*compute.Operation, error) {
+	if c.MockInstanceGroupsRemoveInstances != nil {
+		return c.MockInstanceGroupsRemoveInstances(project, zone, instance, instanceGroup)
+	}
 	if project == ErrUnregisteringInstance {
 		return nil, errors.New("a GCP error")
 	}
@@ -217,6 +293,9 @@ func (c *GCPComputeServiceMock) InstanceGroupsRemoveInstances(project string, zo
 }
 
 func (c *GCPComputeServiceMock) InstanceGroupInsert(project string, zone string, instanceGroup *compute.InstanceGroup) (*compute.Operation, error) {
+	if c.MockInstanceGroupInsert != nil {
+		return c.MockInstanceGroupInsert(project, zone, instanceGroup)
+	}
 	if project == AddGroupSuccessfully {
 		return &compute.Operation{
 			Status: "DONE",
@@ -230,6 +309,9 @@ func (c *GCPComputeServiceMock) InstanceGroupInsert(project string, zone string,
 }
 
 func (c *GCPComputeServiceMock) InstanceGroupGet(project string, zone string, instanceGroupName string) (*compute.InstanceGroup, error) {
+	if c.MockInstanceGroupGet != nil {
+		return c.MockInstanceGroupGet(project, zone, instanceGroupName)
+	}
 	if project == ErrFailGroupGet {
 		return nil, errors.New("instanceGroupGet request failed")
 	}
@@ -239,7 +321,10 @@ func (c *GCPComputeServiceMock) InstanceGroupGet(project string, zone string, in
 	return nil, nil
 }
 
-func (c *GCPComputeServiceMock) AddInstanceGroupToBackendService(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+func (c *GCPComputeServiceMock) RegionBackendServicesPatch(project string, region string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+	if c.MockRegionBackendServicesPatch != nil {
+		return c.MockRegionBackendServicesPatch(project, region, backendServiceName, backendService)
+	}
 	if project == ErrPatchingBackendService {
 		return nil, errors.New("failed to add new instanceGroup to backend service")
 	}
@@ -249,6 +334,9 @@ func (c *GCPComputeServiceMock) AddInstanceGroupToBackendService(project string,
 }
 
 func (c *GCPComputeServiceMock) BackendServiceGet(project string, region string, backendServiceName string) (*compute.BackendService, error) {
+	if c.MockBackendServiceGet != nil {
+		return c.MockBackendServiceGet(project, region, backendServiceName)
+	}
 	if project == ErrGettingBackendService || project == ErrPatchingBackendService {
 		return nil, errors.New("failed to get the regional backend service")
 	}
@@ -273,6 +361,7 @@ func (c *GCPComputeServiceMock) ImageGet(project string, image string) (*compute
 	if image == "uefi-image" {
 		img.GuestOsFeatures = append(img.GuestOsFeatures, &compute.GuestOsFeature{Type: UEFICompatible})
 	}
+	setMockImageProperties(img, image)
 
 	return img, nil
 }
@@ -291,6 +380,475 @@ func (c *GCPComputeServiceMock) ImageFamilyGet(project, zone, family string) (*c
 	if family == "uefi-image-family" {
 		imgView.Image.GuestOsFeatures = append(imgView.Image.GuestOsFeatures, &compute.GuestOsFeature{Type: UEFICompatible})
 	}
+	setMockImageProperties(imgView.Image, family)
 
 	return imgView, nil
 }
+
+// setMockImageProperties sets the size, architecture and deprecation status of the well-known test images.
+func setMockImageProperties(img *compute.Image, name string) {
+	img.Name = name
+	switch name {
+	case LargeImage:
+		img.DiskSizeGb = 200
+	case ARM64Image:
+		img.Architecture = "ARM64"
+	case DeprecatedImage:
+		img.Deprecated = &compute.DeprecationStatus{State: "DEPRECATED", Replacement: "uefi-image"}
+	case ObsoleteImage:
+		img.Deprecated = &compute.DeprecationStatus{State: "OBSOLETE"}
+	case GVNICImage:
+		img.GuestOsFeatures = append(img.GuestOsFeatures, &compute.GuestOsFeature{Type: "GVNIC"})
+	}
+}
+
+func (c *GCPComputeServiceMock) InstancesDetachDisk(project string, zone string, instance string, deviceName string) (*compute.Operation, error) {
+	if c.MockInstancesDetachDisk == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstancesDetachDisk(project, zone, instance, deviceName)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetDiskAutoDelete(project string, zone string, instance string, autoDelete bool, deviceName string) (*compute.Operation, error) {
+	if c.MockInstancesSetDiskAutoDelete == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstancesSetDiskAutoDelete(project, zone, instance, autoDelete, deviceName)
+}
+
+func (c *GCPComputeServiceMock) DisksCreateSnapshot(project string, zone string, disk string, snapshot *compute.Snapshot) (*compute.Operation, error) {
+	if c.MockDisksCreateSnapshot == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockDisksCreateSnapshot(project, zone, disk, snapshot)
+}
+
+func (c *GCPComputeServiceMock) SnapshotsGet(project string, snapshot string) (*compute.Snapshot, error) {
+	if c.MockSnapshotsGet == nil {
+		return &compute.Snapshot{
+			Name:   snapshot,
+			Status: "READY",
+		}, nil
+	}
+	return c.MockSnapshotsGet(project, snapshot)
+}
+
+func (c *GCPComputeServiceMock) DisksGet(project string, zone string, disk string) (*compute.Disk, error) {
+	if c.MockDisksGet == nil {
+		return &compute.Disk{
+			Name: disk,
+			Zone: zone,
+		}, nil
+	}
+	return c.MockDisksGet(project, zone, disk)
+}
+
+func (c *GCPComputeServiceMock) DisksAddResourcePolicies(project string, zone string, disk string, resourcePolicies []string) (*compute.Operation, error) {
+	if c.MockDisksAddResourcePolicies == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockDisksAddResourcePolicies(project, zone, disk, resourcePolicies)
+}
+
+func (c *GCPComputeServiceMock) ResourcePoliciesGet(project string, region string, resourcePolicy string) (*compute.ResourcePolicy, error) {
+	if c.MockResourcePoliciesGet == nil {
+		return &compute.ResourcePolicy{
+			Name:   resourcePolicy,
+			Region: region,
+		}, nil
+	}
+	return c.MockResourcePoliciesGet(project, region, resourcePolicy)
+}
+
+func (c *GCPComputeServiceMock) SubnetworksGet(project string, region string, subnetwork string) (*compute.Subnetwork, error) {
+	if c.MockSubnetworksGet == nil {
+		return &compute.Subnetwork{
+			Name:      subnetwork,
+			Region:    region,
+			StackType: "IPV4_ONLY",
+		}, nil
+	}
+	return c.MockSubnetworksGet(project, region, subnetwork)
+}
+
+func (c *GCPComputeServiceMock) InstancesUpdateNetworkInterface(project string, zone string, instance string, networkInterface string, nic *compute.NetworkInterface) (*compute.Operation, error) {
+	if c.MockInstancesUpdateNetworkInterface == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstancesUpdateNetworkInterface(project, zone, instance, networkInterface, nic)
+}
+
+func (c *GCPComputeServiceMock) AddressesGet(project string, region string, address string) (*compute.Address, error) {
+	if c.MockAddressesGet == nil {
+		return &compute.Address{
+			Name:    address,
+			Region:  region,
+			Address: "10.0.0.100",
+			Status:  "RESERVED",
+		}, nil
+	}
+	return c.MockAddressesGet(project, region, address)
+}
+
+func (c *GCPComputeServiceMock) AddressesInsert(project string, region string, address *compute.Address) (*compute.Operation, error) {
+	if c.MockAddressesInsert == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockAddressesInsert(project, region, address)
+}
+
+func (c *GCPComputeServiceMock) AddressesDelete(project string, region string, address string) (*compute.Operation, error) {
+	if c.MockAddressesDelete == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockAddressesDelete(project, region, address)
+}
+
+func (c *GCPComputeServiceMock) RegionOperationsGet(project string, region string, operation string) (*compute.Operation, error) {
+	if c.MockRegionOperationsGet == nil {
+		return nil, nil
+	}
+	return c.MockRegionOperationsGet(project, region, operation)
+}
+
+func (c *GCPComputeServiceMock) NetworksGet(project string, network string) (*compute.Network, error) {
+	if c.MockNetworksGet == nil {
+		return &compute.Network{
+			Name:     network,
+			SelfLink: fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/global/networks/%s", project, network),
+		}, nil
+	}
+	return c.MockNetworksGet(project, network)
+}
+
+func (c *GCPComputeServiceMock) SubnetworksTestIamPermissions(project string, region string, subnetwork string, permissions []string) ([]string, error) {
+	if c.MockSubnetworksTestIamPermissions == nil {
+		return permissions, nil
+	}
+	return c.MockSubnetworksTestIamPermissions(project, region, subnetwork, permissions)
+}
+
+func (c *GCPComputeServiceMock) InstancesList(ctx context.Context, project string, zone string, filter string) ([]*compute.Instance, error) {
+	if c.MockInstancesList == nil {
+		return []*compute.Instance{}, nil
+	}
+	return c.MockInstancesList(ctx, project, zone, filter)
+}
+
+func (c *GCPComputeServiceMock) InstancesAggregatedNetworkInterfaces(ctx context.Context, project string) ([]*compute.NetworkInterface, error) {
+	if c.MockInstancesAggregatedNetworkInterfaces == nil {
+		return []*compute.NetworkInterface{}, nil
+	}
+	return c.MockInstancesAggregatedNetworkInterfaces(ctx, project)
+}
+
+func (c *GCPComputeServiceMock) GlobalBackendServiceGet(project string, backendServiceName string) (*compute.BackendService, error) {
+	if c.MockGlobalBackendServiceGet == nil {
+		return &compute.BackendService{
+			Name: backendServiceName,
+		}, nil
+	}
+	return c.MockGlobalBackendServiceGet(project, backendServiceName)
+}
+
+func (c *GCPComputeServiceMock) BackendServicesPatch(project string, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+	if c.MockBackendServicesPatch == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockBackendServicesPatch(project, backendServiceName, backendService)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupsListNetworkEndpoints(ctx context.Context, project string, zone string, networkEndpointGroup string) ([]*compute.NetworkEndpoint, error) {
+	if c.MockNetworkEndpointGroupsListNetworkEndpoints == nil {
+		return []*compute.NetworkEndpoint{}, nil
+	}
+	return c.MockNetworkEndpointGroupsListNetworkEndpoints(ctx, project, zone, networkEndpointGroup)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupsAttachNetworkEndpoints(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	if c.MockNetworkEndpointGroupsAttachNetworkEndpoints == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockNetworkEndpointGroupsAttachNetworkEndpoints(project, zone, networkEndpointGroup, endpoints)
+}
+
+func (c *GCPComputeServiceMock) NetworkEndpointGroupsDetachNetworkEndpoints(project string, zone string, networkEndpointGroup string, endpoints []*compute.NetworkEndpoint) (*compute.Operation, error) {
+	if c.MockNetworkEndpointGroupsDetachNetworkEndpoints == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockNetworkEndpointGroupsDetachNetworkEndpoints(project, zone, networkEndpointGroup, endpoints)
+}
+
+func (c *GCPComputeServiceMock) BackendServiceGetHealth(project string, region string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error) {
+	if c.MockBackendServiceGetHealth == nil {
+		return &compute.BackendServiceGroupHealth{}, nil
+	}
+	return c.MockBackendServiceGetHealth(project, region, backendServiceName, instanceGroup)
+}
+
+func (c *GCPComputeServiceMock) GlobalOperationsGet(project string, operation string) (*compute.Operation, error) {
+	if c.MockGlobalOperationsGet == nil {
+		return nil, nil
+	}
+	return c.MockGlobalOperationsGet(project, operation)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupsDelete(project string, zone string, instanceGroup string) (*compute.Operation, error) {
+	if c.MockInstanceGroupsDelete == nil {
+		return &compute.Operation{Status: "DONE"}, nil
+	}
+	return c.MockInstanceGroupsDelete(project, zone, instanceGroup)
+}
+
+func (c *GCPComputeServiceMock) GlobalBackendServiceGetHealth(project string, backendServiceName string, instanceGroup string) (*compute.BackendServiceGroupHealth, error) {
+	if c.MockGlobalBackendServiceGetHealth == nil {
+		return &compute.BackendServiceGroupHealth{}, nil
+	}
+	return c.MockGlobalBackendServiceGetHealth(project, backendServiceName, instanceGroup)
+}
+
+func (c *GCPComputeServiceMock) NodeGroupsList(ctx context.Context, project string, zone string) ([]*compute.NodeGroup, error) {
+	if c.MockNodeGroupsList == nil {
+		return nil, nil
+	}
+	return c.MockNodeGroupsList(ctx, project, zone)
+}
+
+func (c *GCPComputeServiceMock) NodeGroupsListNodes(ctx context.Context, project string, zone string, nodeGroup string) ([]*compute.NodeGroupNode, error) {
+	if c.MockNodeGroupsListNodes == nil {
+		return nil, nil
+	}
+	return c.MockNodeGroupsListNodes(ctx, project, zone, nodeGroup)
+}
+
+func (c *GCPComputeServiceMock) ReservationsGet(project string, zone string, reservation string) (*compute.Reservation, error) {
+	if c.MockReservationsGet == nil {
+		return &compute.Reservation{
+			Name:                reservation,
+			Zone:                zone,
+			Status:              "READY",
+			SpecificReservation: &compute.AllocationSpecificSKUReservation{Count: 1},
+		}, nil
+	}
+	return c.MockReservationsGet(project, zone, reservation)
+}
+
+func (c *GCPComputeServiceMock) ResourcePoliciesInsert(project string, region string, resourcePolicy *compute.ResourcePolicy) (*compute.Operation, error) {
+	if c.MockResourcePoliciesInsert == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockResourcePoliciesInsert(project, region, resourcePolicy)
+}
+
+func (c *GCPComputeServiceMock) ResourcePoliciesDelete(project string, region string, resourcePolicy string) (*compute.Operation, error) {
+	if c.MockResourcePoliciesDelete == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockResourcePoliciesDelete(project, region, resourcePolicy)
+}
+
+func (c *GCPComputeServiceMock) InstancesBulkInsert(project string, zone string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error) {
+	if c.MockInstancesBulkInsert == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstancesBulkInsert(project, zone, resource)
+}
+
+func (c *GCPComputeServiceMock) RegionInstancesBulkInsert(project string, region string, resource *compute.BulkInsertInstanceResource) (*compute.Operation, error) {
+	if c.MockRegionInstancesBulkInsert == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockRegionInstancesBulkInsert(project, region, resource)
+}
+
+func (c *GCPComputeServiceMock) InstanceTemplatesGet(project string, instanceTemplate string) (*compute.InstanceTemplate, error) {
+	if c.MockInstanceTemplatesGet == nil {
+		return &compute.InstanceTemplate{
+			Name: instanceTemplate,
+			Properties: &compute.InstanceProperties{
+				MachineType: "n1-standard-2",
+			},
+		}, nil
+	}
+	return c.MockInstanceTemplatesGet(project, instanceTemplate)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceTemplatesGet(project string, region string, instanceTemplate string) (*compute.InstanceTemplate, error) {
+	if c.MockRegionInstanceTemplatesGet == nil {
+		return &compute.InstanceTemplate{
+			Name:   instanceTemplate,
+			Region: region,
+			Properties: &compute.InstanceProperties{
+				MachineType: "n1-standard-2",
+			},
+		}, nil
+	}
+	return c.MockRegionInstanceTemplatesGet(project, region, instanceTemplate)
+}
+
+func (c *GCPComputeServiceMock) InstancesInsertFromTemplate(project string, zone string, instance *compute.Instance, sourceInstanceTemplate string) (*compute.Operation, error) {
+	if c.MockInstancesInsertFromTemplate == nil {
+		// Record the instance like any other insert
+		return c.InstancesInsert(project, zone, instance)
+	}
+	return c.MockInstancesInsertFromTemplate(project, zone, instance, sourceInstanceTemplate)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupManagersGet(project string, zone string, instanceGroupManager string) (*compute.InstanceGroupManager, error) {
+	if c.MockInstanceGroupManagersGet == nil {
+		return &compute.InstanceGroupManager{
+			Name: instanceGroupManager,
+		}, nil
+	}
+	return c.MockInstanceGroupManagersGet(project, zone, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupManagersInsert(project string, zone string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error) {
+	if c.MockInstanceGroupManagersInsert == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstanceGroupManagersInsert(project, zone, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupManagersDelete(project string, zone string, instanceGroupManager string) (*compute.Operation, error) {
+	if c.MockInstanceGroupManagersDelete == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstanceGroupManagersDelete(project, zone, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupManagersCreateInstances(project string, zone string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error) {
+	if c.MockInstanceGroupManagersCreateInstances == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstanceGroupManagersCreateInstances(project, zone, instanceGroupManager, instances)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupManagersDeleteInstances(project string, zone string, instanceGroupManager string, instances []string) (*compute.Operation, error) {
+	if c.MockInstanceGroupManagersDeleteInstances == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstanceGroupManagersDeleteInstances(project, zone, instanceGroupManager, instances)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupManagersListManagedInstances(ctx context.Context, project string, zone string, instanceGroupManager string) ([]*compute.ManagedInstance, error) {
+	if c.MockInstanceGroupManagersListManagedInstances == nil {
+		return []*compute.ManagedInstance{}, nil
+	}
+	return c.MockInstanceGroupManagersListManagedInstances(ctx, project, zone, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceGroupManagersGet(project string, region string, instanceGroupManager string) (*compute.InstanceGroupManager, error) {
+	if c.MockRegionInstanceGroupManagersGet == nil {
+		return &compute.InstanceGroupManager{
+			Name: instanceGroupManager,
+		}, nil
+	}
+	return c.MockRegionInstanceGroupManagersGet(project, region, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceGroupManagersInsert(project string, region string, instanceGroupManager *compute.InstanceGroupManager) (*compute.Operation, error) {
+	if c.MockRegionInstanceGroupManagersInsert == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockRegionInstanceGroupManagersInsert(project, region, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceGroupManagersDelete(project string, region string, instanceGroupManager string) (*compute.Operation, error) {
+	if c.MockRegionInstanceGroupManagersDelete == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockRegionInstanceGroupManagersDelete(project, region, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceGroupManagersCreateInstances(project string, region string, instanceGroupManager string, instances []*compute.PerInstanceConfig) (*compute.Operation, error) {
+	if c.MockRegionInstanceGroupManagersCreateInstances == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockRegionInstanceGroupManagersCreateInstances(project, region, instanceGroupManager, instances)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceGroupManagersDeleteInstances(project string, region string, instanceGroupManager string, instances []string) (*compute.Operation, error) {
+	if c.MockRegionInstanceGroupManagersDeleteInstances == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockRegionInstanceGroupManagersDeleteInstances(project, region, instanceGroupManager, instances)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceGroupManagersListManagedInstances(ctx context.Context, project string, region string, instanceGroupManager string) ([]*compute.ManagedInstance, error) {
+	if c.MockRegionInstanceGroupManagersListManagedInstances == nil {
+		return []*compute.ManagedInstance{}, nil
+	}
+	return c.MockRegionInstanceGroupManagersListManagedInstances(ctx, project, region, instanceGroupManager)
+}
+
+func (c *GCPComputeServiceMock) InstanceGroupManagersSetInstanceTemplate(project string, zone string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error) {
+	if c.MockInstanceGroupManagersSetInstanceTemplate == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstanceGroupManagersSetInstanceTemplate(project, zone, instanceGroupManager, instanceTemplate)
+}
+
+func (c *GCPComputeServiceMock) RegionInstanceGroupManagersSetInstanceTemplate(project string, region string, instanceGroupManager string, instanceTemplate string) (*compute.Operation, error) {
+	if c.MockRegionInstanceGroupManagersSetInstanceTemplate == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockRegionInstanceGroupManagersSetInstanceTemplate(project, region, instanceGroupManager, instanceTemplate)
+}
+
+func (c *GCPComputeServiceMock) InstancesSetLabels(project string, zone string, instance string, labels map[string]string, labelFingerprint string) (*compute.Operation, error) {
+	if c.MockInstancesSetLabels == nil {
+		return &compute.Operation{
+			Status: "DONE",
+		}, nil
+	}
+	return c.MockInstancesSetLabels(project, zone, instance, labels, labelFingerprint)
+}
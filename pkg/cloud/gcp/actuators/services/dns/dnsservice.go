@@ -0,0 +1,98 @@
+package dnsservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	dns "google.golang.org/api/dns/v1"
+	"google.golang.org/api/option"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+// DNSService is a pass through wrapper for google.golang.org/api/dns/v1
+// to enable tests to mock this struct and control behavior.
+type DNSService interface {
+	ManagedZonesGet(ctx context.Context, project string, managedZone string) (*dns.ManagedZone, error)
+	ResourceRecordSetsGet(ctx context.Context, project string, managedZone string, name string, recordType string) (*dns.ResourceRecordSet, error)
+	ResourceRecordSetsCreate(ctx context.Context, project string, managedZone string, recordSet *dns.ResourceRecordSet) (*dns.ResourceRecordSet, error)
+	ResourceRecordSetsPatch(ctx context.Context, project string, managedZone string, recordSet *dns.ResourceRecordSet) (*dns.ResourceRecordSet, error)
+	ResourceRecordSetsDelete(ctx context.Context, project string, managedZone string, name string, recordType string) error
+}
+
+// dnsService implements DNSService interface.
+type dnsService struct {
+	service *dns.Service
+}
+
+// BuilderFuncType is function type for building GCP Cloud DNS client.
+type BuilderFuncType func(ctx context.Context, serviceAccountJSON string) (DNSService, error)
+
+// NewDNSService return a new dnsService.
+func NewDNSService(ctx context.Context, serviceAccountJSON string) (DNSService, error) {
+	service, err := dns.NewService(ctx, option.WithCredentialsJSON([]byte(serviceAccountJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("could not create new dns service: %w", err)
+	}
+
+	return &dnsService{
+		service: service,
+	}, nil
+}
+
+// cachedServiceTTL is how long a service built by NewCachedDNSService is reused, so that the
+// services of rotated credentials go away.
+const cachedServiceTTL = time.Hour
+
+// cachedServices holds the services built by NewCachedDNSService, by hash of their credentials.
+var cachedServices = struct {
+	sync.Mutex
+	services *cache.Expiring
+}{services: cache.NewExpiring()}
+
+// NewCachedDNSService returns the dnsService built for the credentials by a previous call, or builds it.
+// The services outlive the reconciliation requesting them, they are built with a background context.
+func NewCachedDNSService(_ context.Context, serviceAccountJSON string) (DNSService, error) {
+	key := sha256.Sum256([]byte(serviceAccountJSON))
+
+	cachedServices.Lock()
+	defer cachedServices.Unlock()
+	if service, ok := cachedServices.services.Get(key); ok {
+		return service.(DNSService), nil
+	}
+
+	service, err := NewDNSService(context.Background(), serviceAccountJSON)
+	if err != nil {
+		return nil, err
+	}
+	cachedServices.services.Set(key, service, cachedServiceTTL)
+	return service, nil
+}
+
+// ManagedZonesGet is a pass through wrapper for dns.Service.ManagedZones.Get(...)
+func (d *dnsService) ManagedZonesGet(ctx context.Context, project string, managedZone string) (*dns.ManagedZone, error) {
+	return d.service.ManagedZones.Get(project, managedZone).Context(ctx).Do()
+}
+
+// ResourceRecordSetsGet is a pass through wrapper for dns.Service.ResourceRecordSets.Get(...)
+func (d *dnsService) ResourceRecordSetsGet(ctx context.Context, project string, managedZone string, name string, recordType string) (*dns.ResourceRecordSet, error) {
+	return d.service.ResourceRecordSets.Get(project, managedZone, name, recordType).Context(ctx).Do()
+}
+
+// ResourceRecordSetsCreate is a pass through wrapper for dns.Service.ResourceRecordSets.Create(...)
+func (d *dnsService) ResourceRecordSetsCreate(ctx context.Context, project string, managedZone string, recordSet *dns.ResourceRecordSet) (*dns.ResourceRecordSet, error) {
+	return d.service.ResourceRecordSets.Create(project, managedZone, recordSet).Context(ctx).Do()
+}
+
+// ResourceRecordSetsPatch is a pass through wrapper for dns.Service.ResourceRecordSets.Patch(...)
+func (d *dnsService) ResourceRecordSetsPatch(ctx context.Context, project string, managedZone string, recordSet *dns.ResourceRecordSet) (*dns.ResourceRecordSet, error) {
+	return d.service.ResourceRecordSets.Patch(project, managedZone, recordSet.Name, recordSet.Type, recordSet).Context(ctx).Do()
+}
+
+// ResourceRecordSetsDelete is a pass through wrapper for dns.Service.ResourceRecordSets.Delete(...)
+func (d *dnsService) ResourceRecordSetsDelete(ctx context.Context, project string, managedZone string, name string, recordType string) error {
+	_, err := d.service.ResourceRecordSets.Delete(project, managedZone, name, recordType).Context(ctx).Do()
+	return err
+}
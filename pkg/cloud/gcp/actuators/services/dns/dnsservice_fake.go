@@ -0,0 +1,142 @@
+package dnsservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	dns "google.golang.org/api/dns/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// FakeDNSService is an in-memory implementation of the DNSService interface for tests.
+// It keeps the record sets of its managed zones, so that tests can check the records
+// left behind by a sequence of operations. Unknown zones and records are reported with
+// the same 404 and 409 errors as the Cloud DNS API.
+type FakeDNSService struct {
+	lock    sync.Mutex
+	zones   map[string]*dns.ManagedZone
+	records map[string]map[string]*dns.ResourceRecordSet
+}
+
+// NewFakeDNSService returns a fake Cloud DNS API serving the given managed zones.
+func NewFakeDNSService(zones ...*dns.ManagedZone) *FakeDNSService {
+	f := &FakeDNSService{
+		zones:   map[string]*dns.ManagedZone{},
+		records: map[string]map[string]*dns.ResourceRecordSet{},
+	}
+	for _, zone := range zones {
+		f.zones[zone.Name] = zone
+		f.records[zone.Name] = map[string]*dns.ResourceRecordSet{}
+	}
+	return f
+}
+
+// NewFakeDNSServiceBuilder returns a builder always returning the given fake.
+func NewFakeDNSServiceBuilder(f *FakeDNSService) BuilderFuncType {
+	return func(ctx context.Context, serviceAccountJSON string) (DNSService, error) {
+		return f, nil
+	}
+}
+
+// Records returns a copy of the record sets of the managed zone, keyed by name and type.
+func (f *FakeDNSService) Records(managedZone string) map[string]*dns.ResourceRecordSet {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	records := map[string]*dns.ResourceRecordSet{}
+	for key, recordSet := range f.records[managedZone] {
+		records[key] = copyRecordSet(recordSet)
+	}
+	return records
+}
+
+func (f *FakeDNSService) ManagedZonesGet(ctx context.Context, project string, managedZone string) (*dns.ManagedZone, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	zone, ok := f.zones[managedZone]
+	if !ok {
+		return nil, notFound("managed zone %s", managedZone)
+	}
+	return zone, nil
+}
+
+func (f *FakeDNSService) ResourceRecordSetsGet(ctx context.Context, project string, managedZone string, name string, recordType string) (*dns.ResourceRecordSet, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	records, ok := f.records[managedZone]
+	if !ok {
+		return nil, notFound("managed zone %s", managedZone)
+	}
+	recordSet, ok := records[RecordKey(name, recordType)]
+	if !ok {
+		return nil, notFound("record set %s %s", name, recordType)
+	}
+	return copyRecordSet(recordSet), nil
+}
+
+func (f *FakeDNSService) ResourceRecordSetsCreate(ctx context.Context, project string, managedZone string, recordSet *dns.ResourceRecordSet) (*dns.ResourceRecordSet, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	records, ok := f.records[managedZone]
+	if !ok {
+		return nil, notFound("managed zone %s", managedZone)
+	}
+	key := RecordKey(recordSet.Name, recordSet.Type)
+	if _, ok := records[key]; ok {
+		return nil, &googleapi.Error{Code: http.StatusConflict, Message: fmt.Sprintf("record set %s %s already exists", recordSet.Name, recordSet.Type)}
+	}
+	records[key] = copyRecordSet(recordSet)
+	return copyRecordSet(recordSet), nil
+}
+
+func (f *FakeDNSService) ResourceRecordSetsPatch(ctx context.Context, project string, managedZone string, recordSet *dns.ResourceRecordSet) (*dns.ResourceRecordSet, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	records, ok := f.records[managedZone]
+	if !ok {
+		return nil, notFound("managed zone %s", managedZone)
+	}
+	key := RecordKey(recordSet.Name, recordSet.Type)
+	if _, ok := records[key]; !ok {
+		return nil, notFound("record set %s %s", recordSet.Name, recordSet.Type)
+	}
+	records[key] = copyRecordSet(recordSet)
+	return copyRecordSet(recordSet), nil
+}
+
+func (f *FakeDNSService) ResourceRecordSetsDelete(ctx context.Context, project string, managedZone string, name string, recordType string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	records, ok := f.records[managedZone]
+	if !ok {
+		return notFound("managed zone %s", managedZone)
+	}
+	key := RecordKey(name, recordType)
+	if _, ok := records[key]; !ok {
+		return notFound("record set %s %s", name, recordType)
+	}
+	delete(records, key)
+	return nil
+}
+
+// RecordKey is the key of a record set in the map returned by Records.
+func RecordKey(name string, recordType string) string {
+	return name + "/" + recordType
+}
+
+func copyRecordSet(recordSet *dns.ResourceRecordSet) *dns.ResourceRecordSet {
+	c := *recordSet
+	c.Rrdatas = append([]string{}, recordSet.Rrdatas...)
+	return &c
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf(format+" not found", args...)}
+}
@@ -0,0 +1,106 @@
+package kmsservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	kms "google.golang.org/api/cloudkms/v1"
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/option"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+// KMSService is a pass through wrapper for google.golang.org/api/cloudkms/v1
+// to enable tests to mock this struct and control behavior.
+// It also reads the projects a key belongs to, as IAM policies are inherited from them.
+type KMSService interface {
+	CryptoKeysGet(ctx context.Context, name string) (*kms.CryptoKey, error)
+	CryptoKeysGetIamPolicy(ctx context.Context, resource string) (*kms.Policy, error)
+	KeyRingsGetIamPolicy(ctx context.Context, resource string) (*kms.Policy, error)
+	ProjectsGet(ctx context.Context, projectID string) (*resourcemanager.Project, error)
+	ProjectsGetIamPolicy(ctx context.Context, projectID string) (*resourcemanager.Policy, error)
+}
+
+// kmsService implements KMSService interface.
+type kmsService struct {
+	service                *kms.Service
+	resourceManagerService *resourcemanager.Service
+}
+
+// BuilderFuncType is function type for building GCP KMS client.
+type BuilderFuncType func(ctx context.Context, serviceAccountJSON string) (KMSService, error)
+
+// NewKMSService return a new kmsService.
+func NewKMSService(ctx context.Context, serviceAccountJSON string) (KMSService, error) {
+	service, err := kms.NewService(ctx, option.WithCredentialsJSON([]byte(serviceAccountJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("could not create new kms service: %w", err)
+	}
+
+	resourceManagerService, err := resourcemanager.NewService(ctx, option.WithCredentialsJSON([]byte(serviceAccountJSON)))
+	if err != nil {
+		return nil, fmt.Errorf("could not create new resource manager service: %w", err)
+	}
+
+	return &kmsService{
+		service:                service,
+		resourceManagerService: resourceManagerService,
+	}, nil
+}
+
+// cachedServiceTTL is how long a service built by NewCachedKMSService is reused, so that the
+// services of rotated credentials go away.
+const cachedServiceTTL = time.Hour
+
+// cachedServices holds the services built by NewCachedKMSService, by hash of their credentials.
+var cachedServices = struct {
+	sync.Mutex
+	services *cache.Expiring
+}{services: cache.NewExpiring()}
+
+// NewCachedKMSService returns the kmsService built for the credentials by a previous call, or builds it.
+// The services outlive the reconciliation requesting them, they are built with a background context.
+func NewCachedKMSService(_ context.Context, serviceAccountJSON string) (KMSService, error) {
+	key := sha256.Sum256([]byte(serviceAccountJSON))
+
+	cachedServices.Lock()
+	defer cachedServices.Unlock()
+	if service, ok := cachedServices.services.Get(key); ok {
+		return service.(KMSService), nil
+	}
+
+	service, err := NewKMSService(context.Background(), serviceAccountJSON)
+	if err != nil {
+		return nil, err
+	}
+	cachedServices.services.Set(key, service, cachedServiceTTL)
+	return service, nil
+}
+
+// CryptoKeysGet is a pass through wrapper for kms.Service.Projects.Locations.KeyRings.CryptoKeys.Get(...)
+func (k *kmsService) CryptoKeysGet(ctx context.Context, name string) (*kms.CryptoKey, error) {
+	return k.service.Projects.Locations.KeyRings.CryptoKeys.Get(name).Context(ctx).Do()
+}
+
+// CryptoKeysGetIamPolicy is a pass through wrapper for kms.Service.Projects.Locations.KeyRings.CryptoKeys.GetIamPolicy(...)
+func (k *kmsService) CryptoKeysGetIamPolicy(ctx context.Context, resource string) (*kms.Policy, error) {
+	return k.service.Projects.Locations.KeyRings.CryptoKeys.GetIamPolicy(resource).Context(ctx).Do()
+}
+
+// KeyRingsGetIamPolicy is a pass through wrapper for kms.Service.Projects.Locations.KeyRings.GetIamPolicy(...)
+func (k *kmsService) KeyRingsGetIamPolicy(ctx context.Context, resource string) (*kms.Policy, error) {
+	return k.service.Projects.Locations.KeyRings.GetIamPolicy(resource).Context(ctx).Do()
+}
+
+// ProjectsGet is a pass through wrapper for resourcemanager.Service.Projects.Get(...)
+func (k *kmsService) ProjectsGet(ctx context.Context, projectID string) (*resourcemanager.Project, error) {
+	return k.resourceManagerService.Projects.Get("projects/" + projectID).Context(ctx).Do()
+}
+
+// ProjectsGetIamPolicy is a pass through wrapper for resourcemanager.Service.Projects.GetIamPolicy(...)
+func (k *kmsService) ProjectsGetIamPolicy(ctx context.Context, projectID string) (*resourcemanager.Policy, error) {
+	return k.resourceManagerService.Projects.GetIamPolicy("projects/"+projectID, &resourcemanager.GetIamPolicyRequest{}).Context(ctx).Do()
+}
@@ -0,0 +1,88 @@
+package kmsservice
+
+import (
+	"context"
+
+	kms "google.golang.org/api/cloudkms/v1"
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
+)
+
+const (
+	// MockProjectNumber is the project number returned by the mock for any project.
+	MockProjectNumber = "123456789"
+	// MockComputeServiceAgent is the compute service agent of the mock project.
+	MockComputeServiceAgent = "service-" + MockProjectNumber + "@compute-system.iam.gserviceaccount.com"
+)
+
+// MockKMSService mocks KMSService interface for tests.
+// By default every key exists, has an enabled primary version and the compute
+// service agent of the mock project is granted the encrypter/decrypter role on the key's project.
+type MockKMSService struct {
+	MockCryptoKeysGet          func(ctx context.Context, name string) (*kms.CryptoKey, error)
+	MockCryptoKeysGetIamPolicy func(ctx context.Context, resource string) (*kms.Policy, error)
+	MockKeyRingsGetIamPolicy   func(ctx context.Context, resource string) (*kms.Policy, error)
+	MockProjectsGet            func(ctx context.Context, projectID string) (*resourcemanager.Project, error)
+	MockProjectsGetIamPolicy   func(ctx context.Context, projectID string) (*resourcemanager.Policy, error)
+}
+
+// NewMockKMSService returns new mock of kmsService.
+func NewMockKMSService() *MockKMSService {
+	return &MockKMSService{}
+}
+
+// NewMockKMSServiceBuilder returns new mock for creating GCP KMS client.
+func NewMockKMSServiceBuilder(ctx context.Context, serviceAccountJSON string) (KMSService, error) {
+	return NewMockKMSService(), nil
+}
+
+func (m *MockKMSService) CryptoKeysGet(ctx context.Context, name string) (*kms.CryptoKey, error) {
+	if m.MockCryptoKeysGet == nil {
+		return &kms.CryptoKey{
+			Name:    name,
+			Purpose: "ENCRYPT_DECRYPT",
+			Primary: &kms.CryptoKeyVersion{
+				Name:  name + "/cryptoKeyVersions/1",
+				State: "ENABLED",
+			},
+		}, nil
+	}
+	return m.MockCryptoKeysGet(ctx, name)
+}
+
+func (m *MockKMSService) CryptoKeysGetIamPolicy(ctx context.Context, resource string) (*kms.Policy, error) {
+	if m.MockCryptoKeysGetIamPolicy == nil {
+		return &kms.Policy{}, nil
+	}
+	return m.MockCryptoKeysGetIamPolicy(ctx, resource)
+}
+
+func (m *MockKMSService) KeyRingsGetIamPolicy(ctx context.Context, resource string) (*kms.Policy, error) {
+	if m.MockKeyRingsGetIamPolicy == nil {
+		return &kms.Policy{}, nil
+	}
+	return m.MockKeyRingsGetIamPolicy(ctx, resource)
+}
+
+func (m *MockKMSService) ProjectsGet(ctx context.Context, projectID string) (*resourcemanager.Project, error) {
+	if m.MockProjectsGet == nil {
+		return &resourcemanager.Project{
+			Name:      "projects/" + MockProjectNumber,
+			ProjectId: projectID,
+		}, nil
+	}
+	return m.MockProjectsGet(ctx, projectID)
+}
+
+func (m *MockKMSService) ProjectsGetIamPolicy(ctx context.Context, projectID string) (*resourcemanager.Policy, error) {
+	if m.MockProjectsGetIamPolicy == nil {
+		return &resourcemanager.Policy{
+			Bindings: []*resourcemanager.Binding{
+				{
+					Role:    "roles/cloudkms.cryptoKeyEncrypterDecrypter",
+					Members: []string{"serviceAccount:" + MockComputeServiceAgent},
+				},
+			},
+		}, nil
+	}
+	return m.MockProjectsGetIamPolicy(ctx, projectID)
+}
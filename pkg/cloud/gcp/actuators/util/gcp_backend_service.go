@@ -0,0 +1,109 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// MaxBackendServiceUpdateAttempts is the number of times a backend service patch is attempted
+// when the backend service is modified concurrently.
+const MaxBackendServiceUpdateAttempts = 5
+
+// BackendServiceUpdateBackoff is the backoff between the attempts of a backend service patch, which
+// gives the concurrent changes of the backend service, from outside the manager, time to complete.
+var BackendServiceUpdateBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    MaxBackendServiceUpdateAttempts,
+}
+
+// backendServiceLocks serializes the changes of each backend service by the machine actuator
+// and the control plane controller running in the manager.
+var backendServiceLocks = &keyedMutex{}
+
+// keyedMutex is a set of mutexes, one per key.
+type keyedMutex struct {
+	mutex   sync.Mutex
+	mutexes map[string]*sync.Mutex
+}
+
+// lock locks the mutex of the key and returns the function unlocking it.
+func (k *keyedMutex) lock(key string) func() {
+	k.mutex.Lock()
+	if k.mutexes == nil {
+		k.mutexes = map[string]*sync.Mutex{}
+	}
+	m, ok := k.mutexes[key]
+	if !ok {
+		m = &sync.Mutex{}
+		k.mutexes[key] = m
+	}
+	k.mutex.Unlock()
+
+	m.Lock()
+	return m.Unlock
+}
+
+// PatchBackendService updates the regional backend service, or the global one when the region is empty.
+// The update is given the backend service and returns the patch to apply, nil when it is up to date.
+// The backend service is patched with the fingerprint it was read with, so that concurrent changes are
+// not overwritten, and the update is retried with a backoff on a fresh read when it was modified in the meantime.
+// It returns the patch operation, nil when nothing was patched.
+func PatchBackendService(computeService computeservice.GCPComputeService, projectID, region, name string, update func(*compute.BackendService) *compute.BackendService) (*compute.Operation, error) {
+	key := fmt.Sprintf("projects/%s/global/backendServices/%s", projectID, name)
+	if region != "" {
+		key = fmt.Sprintf("projects/%s/regions/%s/backendServices/%s", projectID, region, name)
+	}
+	unlock := backendServiceLocks.lock(key)
+	defer unlock()
+
+	var op *compute.Operation
+	var patchErr error
+	err := wait.ExponentialBackoff(BackendServiceUpdateBackoff, func() (bool, error) {
+		var backendService *compute.BackendService
+		var err error
+		if region == "" {
+			backendService, err = computeService.GlobalBackendServiceGet(projectID, name)
+		} else {
+			backendService, err = computeService.BackendServiceGet(projectID, region, name)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get backend service %s: %w", name, err)
+		}
+
+		patch := update(backendService)
+		if patch == nil {
+			return true, nil
+		}
+		patch.Fingerprint = backendService.Fingerprint
+
+		if region == "" {
+			op, patchErr = computeService.BackendServicesPatch(projectID, name, patch)
+		} else {
+			op, patchErr = computeService.RegionBackendServicesPatch(projectID, region, name, patch)
+		}
+		if isGoogleAPIErrorCode(patchErr, http.StatusPreconditionFailed) {
+			klog.Infof("Backend service %s was modified concurrently, retrying", name)
+			return false, nil
+		}
+		if patchErr != nil {
+			return false, fmt.Errorf("failed to patch backend service %s: %w", name, patchErr)
+		}
+		return true, nil
+	})
+	if wait.Interrupted(err) {
+		return nil, fmt.Errorf("failed to patch backend service %s: %w", name, patchErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
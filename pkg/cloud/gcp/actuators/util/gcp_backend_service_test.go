@@ -0,0 +1,109 @@
+package util_test
+
+import (
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+var _ = Describe("PatchBackendService", func() {
+
+	BeforeEach(func() {
+		backoff := util.BackendServiceUpdateBackoff
+		util.BackendServiceUpdateBackoff.Duration = time.Millisecond
+		DeferCleanup(func() {
+			util.BackendServiceUpdateBackoff = backoff
+		})
+	})
+
+	type patchBackendServiceInput struct {
+		region               string
+		conflicting          int
+		upToDate             bool
+		expectedPatches      int
+		expectedErrSubstring string
+	}
+
+	var tableFunc func(in patchBackendServiceInput) = func(in patchBackendServiceInput) {
+		_, computeService := computeservice.NewComputeServiceMock()
+		fingerprint := 0
+		get := func() (*compute.BackendService, error) {
+			return &compute.BackendService{Fingerprint: string(rune('a' + fingerprint))}, nil
+		}
+		patches := 0
+		patch := func(backendService *compute.BackendService) (*compute.Operation, error) {
+			patches++
+			Expect(backendService.Fingerprint).To(Equal(string(rune('a' + fingerprint))))
+			if patches <= in.conflicting {
+				// Another client changed the backend service since it was read
+				fingerprint++
+				return nil, &googleapi.Error{Code: http.StatusPreconditionFailed}
+			}
+			return &compute.Operation{Status: "DONE"}, nil
+		}
+		computeService.MockBackendServiceGet = func(project, region, backendServiceName string) (*compute.BackendService, error) {
+			Expect(region).To(Equal(in.region))
+			return get()
+		}
+		computeService.MockGlobalBackendServiceGet = func(project, backendServiceName string) (*compute.BackendService, error) {
+			Expect(in.region).To(BeEmpty())
+			return get()
+		}
+		computeService.MockRegionBackendServicesPatch = func(project, region, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+			Expect(region).To(Equal(in.region))
+			return patch(backendService)
+		}
+		computeService.MockBackendServicesPatch = func(project, backendServiceName string, backendService *compute.BackendService) (*compute.Operation, error) {
+			Expect(in.region).To(BeEmpty())
+			return patch(backendService)
+		}
+
+		op, err := util.PatchBackendService(computeService, "fooproject", in.region, "api", func(*compute.BackendService) *compute.BackendService {
+			if in.upToDate {
+				return nil
+			}
+			return &compute.BackendService{}
+		})
+		Expect(patches).To(Equal(in.expectedPatches))
+		if in.expectedErrSubstring != "" {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(in.expectedErrSubstring))
+			return
+		}
+		Expect(err).ToNot(HaveOccurred())
+		Expect(op == nil).To(Equal(in.upToDate))
+	}
+
+	DescribeTable("Backend service patch",
+		tableFunc,
+		Entry("Patches a regional backend service with its fingerprint", patchBackendServiceInput{
+			region:          "us-central1",
+			expectedPatches: 1,
+		}),
+		Entry("Patches a global backend service with its fingerprint", patchBackendServiceInput{
+			expectedPatches: 1,
+		}),
+		Entry("Does not patch a backend service which is up to date", patchBackendServiceInput{
+			region:   "us-central1",
+			upToDate: true,
+		}),
+		Entry("Retries when the backend service is modified concurrently", patchBackendServiceInput{
+			region:          "us-central1",
+			conflicting:     2,
+			expectedPatches: 3,
+		}),
+		Entry("Gives up when the backend service keeps being modified", patchBackendServiceInput{
+			region:               "us-central1",
+			conflicting:          util.MaxBackendServiceUpdateAttempts,
+			expectedPatches:      util.MaxBackendServiceUpdateAttempts,
+			expectedErrSubstring: "failed to patch backend service api: googleapi: got HTTP response code 412",
+		}),
+	)
+})
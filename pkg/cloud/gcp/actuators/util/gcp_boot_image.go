@@ -0,0 +1,166 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	// DefaultBootDiskSizeGB is the boot disk size used when the providerSpec does not set one.
+	// The minimum size of the RHCOS images is not enough to hold the container images of a node.
+	DefaultBootDiskSizeGB int64 = 128
+
+	imageDeprecated = "DEPRECATED"
+	imageObsolete   = "OBSOLETE"
+	imageDeleted    = "DELETED"
+)
+
+// imageArchitectureMap maps the GCE image architectures to the normalized architecture names.
+var imageArchitectureMap = map[string]NormalizedArch{
+	"X86_64": ArchitectureAmd64,
+	"ARM64":  ArchitectureArm64,
+}
+
+// GetBootImage retrieves the image the boot disk of the machine is created from.
+// Images referenced by family are resolved to the latest image of the family.
+func GetBootImage(gceService computeservice.GCPComputeService, providerConfig *machinev1.GCPMachineProviderSpec) (*compute.Image, error) {
+	for _, disk := range providerConfig.Disks {
+		if !disk.Boot {
+			continue
+		}
+
+		// Parse the image reference from the disk.
+		imageRef, err := parseImageReference(disk.Image, providerConfig.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse disk image reference: %w", err)
+		}
+
+		if imageRef.IsFamily {
+			family, err := gceService.ImageFamilyGet(imageRef.Project, providerConfig.Zone, imageRef.Image)
+			if err != nil {
+				return nil, fmt.Errorf("unable to retrieve image family %q in project %q: %w", imageRef.Image, imageRef.Project, err)
+			}
+			return family.Image, nil
+		}
+
+		img, err := gceService.ImageGet(imageRef.Project, imageRef.Image)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve image %q in project %q: %w", imageRef.Image, imageRef.Project, err)
+		}
+		return img, nil
+	}
+	return nil, fmt.Errorf("no boot disk found")
+}
+
+// BootDiskSizeGB returns the size the boot disk is created with. A boot disk without a size is defaulted
+// to the larger of DefaultBootDiskSizeGB and the size of its image, if the image is known.
+// The providerSpec is left untouched so that it keeps matching the one of its machine set.
+func BootDiskSizeGB(image *compute.Image, disk *machinev1.GCPDisk) int64 {
+	if disk.SizeGB != 0 || image == nil {
+		return disk.SizeGB
+	}
+	if image.DiskSizeGb > DefaultBootDiskSizeGB {
+		return image.DiskSizeGb
+	}
+	return DefaultBootDiskSizeGB
+}
+
+// InspectBootImage validates the boot disk of the machine against the image it is created from.
+// A boot disk smaller than the image and an image built for a different architecture than the machine
+// type are rejected. Deprecated images are reported as warnings, obsolete and deleted images as errors.
+func InspectBootImage(image *compute.Image, providerConfig *machinev1.GCPMachineProviderSpec) ([]string, error) {
+	var warnings []string
+
+	for _, disk := range providerConfig.Disks {
+		if disk.Boot && disk.SizeGB != 0 && disk.SizeGB < image.DiskSizeGb {
+			return nil, fmt.Errorf("boot disk size %dGB is smaller than the %dGB required by image %q", disk.SizeGB, image.DiskSizeGb, image.Name)
+		}
+	}
+
+	if imageArch, ok := imageArchitectureMap[image.Architecture]; ok {
+		if machineArch := CPUArchitecture(providerConfig.MachineType); imageArch != machineArch {
+			return nil, fmt.Errorf("image %q architecture %s does not match machine type %q architecture %s", image.Name, imageArch, providerConfig.MachineType, machineArch)
+		}
+	}
+
+	if image.Deprecated != nil {
+		switch image.Deprecated.State {
+		case imageDeprecated:
+			warning := fmt.Sprintf("image %q is deprecated", image.Name)
+			if image.Deprecated.Replacement != "" {
+				warning = fmt.Sprintf("%s, use %q instead", warning, image.Deprecated.Replacement)
+			}
+			warnings = append(warnings, warning)
+		case imageObsolete, imageDeleted:
+			return nil, fmt.Errorf("image %q is %s", image.Name, strings.ToLower(image.Deprecated.State))
+		}
+	}
+
+	return warnings, nil
+}
+
+// imageReference holds parsed details from a disk.Image string.
+type imageReference struct {
+	Project  string
+	Image    string
+	IsFamily bool // true if the image is specified as a family reference.
+}
+
+// parseImageReference extracts project and image information from the given image string.
+// It supports various formats:
+//   - "projects/{project}/global/images/{image}"
+//   - "projects/{project}/global/images/family/{imageFamily}"
+//   - "https://www.googleapis.com/compute/v1/projects/{project}/global/images/{image}"
+//   - A simple image name without slashes, in which case providerProject is used.
+func parseImageReference(imageStr, providerProject string) (*imageReference, error) {
+	// If the image string does not contain a slash, assume it's a simple image name.
+	if !strings.Contains(imageStr, "/") {
+		return &imageReference{
+			Project:  providerProject,
+			Image:    imageStr,
+			IsFamily: false,
+		}, nil
+	}
+
+	// Check if the image string contains "projects/".
+	if !strings.Contains(imageStr, "projects/") {
+		return nil, fmt.Errorf("image string %q does not contain expected 'projects/' segment", imageStr)
+	}
+
+	// Split based on "projects/".
+	parts := strings.SplitN(imageStr, "projects/", 2)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("unexpected format for image string: %q", imageStr)
+	}
+
+	// Split the remainder by "/".
+	subParts := strings.Split(parts[1], "/")
+	// Expected formats:
+	// For non-family images: {project}/global/images/{image} => at least 4 parts.
+	// For family images: {project}/global/images/family/{imageFamily} => at least 5 parts.
+	if len(subParts) < 4 {
+		return nil, fmt.Errorf("unexpected image path format in %q", imageStr)
+	}
+
+	// Determine if the image is specified as a family.
+	isFamily := false
+	imageName := ""
+	if len(subParts) >= 5 && subParts[2] == "images" && subParts[3] == "family" {
+		isFamily = true
+		imageName = subParts[4]
+	} else if subParts[1] == "global" && subParts[2] == "images" {
+		imageName = subParts[3]
+	} else {
+		return nil, fmt.Errorf("unrecognized image path format in %q", imageStr)
+	}
+
+	return &imageReference{
+		Project:  subParts[0],
+		Image:    imageName,
+		IsFamily: isFamily,
+	}, nil
+}
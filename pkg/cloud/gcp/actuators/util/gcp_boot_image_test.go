@@ -0,0 +1,99 @@
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinev1builder "github.com/openshift/cluster-api-actuator-pkg/testutils/resourcebuilder/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+)
+
+var _ = Describe("InspectBootImage", func() {
+
+	type inspectBootImageInput struct {
+		image                string
+		machineType          string
+		sizeGB               int64
+		expectedSizeGB       int64
+		expectedWarnings     []string
+		expectedErrSubstring string
+	}
+
+	var tableFunc func(in inspectBootImageInput) = func(in inspectBootImageInput) {
+		_, computeService := computeservice.NewComputeServiceMock()
+		providerSpec := machinev1builder.GCPProviderSpec().
+			WithMachineType(in.machineType).
+			WithDisks([]*machinev1.GCPDisk{
+				{
+					Boot:   true,
+					Image:  in.image,
+					SizeGB: in.sizeGB,
+				},
+			}).Build()
+
+		image, err := util.GetBootImage(computeService, providerSpec)
+		Expect(err).ToNot(HaveOccurred())
+
+		warnings, err := util.InspectBootImage(image, providerSpec)
+		if in.expectedErrSubstring != "" {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(in.expectedErrSubstring))
+			return
+		}
+		Expect(err).ToNot(HaveOccurred())
+		Expect(warnings).To(Equal(in.expectedWarnings))
+		Expect(util.BootDiskSizeGB(image, providerSpec.Disks[0])).To(Equal(in.expectedSizeGB))
+		Expect(providerSpec.Disks[0].SizeGB).To(Equal(in.sizeGB))
+	}
+
+	DescribeTable("Boot disk size",
+		tableFunc,
+		Entry("Defaults an unset size", inspectBootImageInput{
+			image:          "projects/fooproject/global/images/fooimage",
+			expectedSizeGB: util.DefaultBootDiskSizeGB,
+		}),
+		Entry("Defaults an unset size to the image size when larger", inspectBootImageInput{
+			image:          "projects/fooproject/global/images/" + computeservice.LargeImage,
+			expectedSizeGB: 200,
+		}),
+		Entry("Keeps a size larger than the image", inspectBootImageInput{
+			image:          "projects/fooproject/global/images/" + computeservice.LargeImage,
+			sizeGB:         250,
+			expectedSizeGB: 250,
+		}),
+		Entry("Rejects a size smaller than the image", inspectBootImageInput{
+			image:                "projects/fooproject/global/images/" + computeservice.LargeImage,
+			sizeGB:               100,
+			expectedErrSubstring: "is smaller than the 200GB required by image",
+		}),
+	)
+
+	DescribeTable("Image architecture",
+		tableFunc,
+		Entry("Accepts an arm64 image for an arm64 machine type", inspectBootImageInput{
+			image:          "projects/fooproject/global/images/" + computeservice.ARM64Image,
+			machineType:    "t2a-standard-4",
+			expectedSizeGB: util.DefaultBootDiskSizeGB,
+		}),
+		Entry("Rejects an arm64 image for an amd64 machine type", inspectBootImageInput{
+			image:                "projects/fooproject/global/images/" + computeservice.ARM64Image,
+			machineType:          "n2-standard-4",
+			expectedErrSubstring: "does not match machine type \"n2-standard-4\" architecture amd64",
+		}),
+	)
+
+	DescribeTable("Image deprecation",
+		tableFunc,
+		Entry("Warns about a deprecated image", inspectBootImageInput{
+			image:            "projects/fooproject/global/images/family/" + computeservice.DeprecatedImage,
+			expectedSizeGB:   util.DefaultBootDiskSizeGB,
+			expectedWarnings: []string{"image \"deprecated-image\" is deprecated, use \"uefi-image\" instead"},
+		}),
+		Entry("Rejects an obsolete image", inspectBootImageInput{
+			image:                "projects/fooproject/global/images/" + computeservice.ObsoleteImage,
+			expectedErrSubstring: "image \"obsolete-image\" is obsolete",
+		}),
+	)
+})
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"google.golang.org/api/compute/v1"
+)
+
+const (
+	globalInstanceTemplateFmt   = "projects/%s/global/instanceTemplates/%s"
+	regionalInstanceTemplateFmt = "projects/%s/regions/%s/instanceTemplates/%s"
+)
+
+// instanceTemplateReference holds parsed details from a sourceInstanceTemplate string.
+type instanceTemplateReference struct {
+	Project string
+	Region  string // empty for global instance templates.
+	Name    string
+}
+
+// url returns the relative URL of the instance template.
+func (r *instanceTemplateReference) url() string {
+	if r.Region == "" {
+		return fmt.Sprintf(globalInstanceTemplateFmt, r.Project, r.Name)
+	}
+	return fmt.Sprintf(regionalInstanceTemplateFmt, r.Project, r.Region, r.Name)
+}
+
+// parseInstanceTemplateReference parses the instance template reference of a providerSpec.
+// It supports various formats:
+//   - "projects/{project}/global/instanceTemplates/{template}"
+//   - "projects/{project}/regions/{region}/instanceTemplates/{template}"
+//   - "https://www.googleapis.com/compute/v1/projects/{project}/global/instanceTemplates/{template}"
+//   - A simple template name without slashes, in which case providerProject is used.
+func parseInstanceTemplateReference(templateStr, providerProject string) (*instanceTemplateReference, error) {
+	if !strings.Contains(templateStr, "/") {
+		return &instanceTemplateReference{
+			Project: providerProject,
+			Name:    templateStr,
+		}, nil
+	}
+
+	parts := strings.SplitN(templateStr, "projects/", 2)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("instance template %q does not contain expected 'projects/' segment", templateStr)
+	}
+
+	// Expected formats:
+	// For global templates: {project}/global/instanceTemplates/{template} => 4 parts.
+	// For regional templates: {project}/regions/{region}/instanceTemplates/{template} => 5 parts.
+	subParts := strings.Split(parts[1], "/")
+	switch {
+	case len(subParts) == 4 && subParts[1] == "global" && subParts[2] == "instanceTemplates" && subParts[3] != "":
+		return &instanceTemplateReference{
+			Project: subParts[0],
+			Name:    subParts[3],
+		}, nil
+	case len(subParts) == 5 && subParts[1] == "regions" && subParts[3] == "instanceTemplates" && subParts[4] != "":
+		return &instanceTemplateReference{
+			Project: subParts[0],
+			Region:  subParts[2],
+			Name:    subParts[4],
+		}, nil
+	}
+	return nil, fmt.Errorf("unrecognized instance template path format in %q", templateStr)
+}
+
+// GetInstanceTemplate retrieves the source instance template of the machine and returns it together
+// with its relative URL. Regional instance templates must be in the region of the machine.
+func GetInstanceTemplate(gceService computeservice.GCPComputeService, projectID string, providerConfig *machinev1.GCPMachineProviderSpec) (*compute.InstanceTemplate, string, error) {
+	templateRef, err := parseInstanceTemplateReference(providerConfig.SourceInstanceTemplate, projectID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse source instance template reference: %w", err)
+	}
+
+	var template *compute.InstanceTemplate
+	if templateRef.Region == "" {
+		template, err = gceService.InstanceTemplatesGet(templateRef.Project, templateRef.Name)
+	} else {
+		if templateRef.Region != providerConfig.Region {
+			return nil, "", fmt.Errorf("instance template %q is in region %q, not in the region %q of the machine", templateRef.Name, templateRef.Region, providerConfig.Region)
+		}
+		template, err = gceService.RegionInstanceTemplatesGet(templateRef.Project, templateRef.Region, templateRef.Name)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to retrieve instance template %q in project %q: %w", templateRef.Name, templateRef.Project, err)
+	}
+	if template.Properties == nil {
+		return nil, "", fmt.Errorf("instance template %q has no instance properties", templateRef.Name)
+	}
+	return template, templateRef.url(), nil
+}
@@ -0,0 +1,60 @@
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1builder "github.com/openshift/cluster-api-actuator-pkg/testutils/resourcebuilder/machine/v1beta1"
+	computeservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/compute"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+)
+
+var _ = Describe("GetInstanceTemplate", func() {
+
+	type getInstanceTemplateInput struct {
+		sourceInstanceTemplate string
+		expectedURL            string
+		expectedErrSubstring   string
+	}
+
+	var tableFunc func(in getInstanceTemplateInput) = func(in getInstanceTemplateInput) {
+		_, computeService := computeservice.NewComputeServiceMock()
+		providerSpec := machinev1builder.GCPProviderSpec().Build()
+		providerSpec.Region = "us-central1"
+		providerSpec.SourceInstanceTemplate = in.sourceInstanceTemplate
+
+		template, url, err := util.GetInstanceTemplate(computeService, "fooproject", providerSpec)
+		if in.expectedErrSubstring != "" {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(in.expectedErrSubstring))
+			return
+		}
+		Expect(err).ToNot(HaveOccurred())
+		Expect(template.Properties).ToNot(BeNil())
+		Expect(url).To(Equal(in.expectedURL))
+	}
+
+	DescribeTable("Instance template reference",
+		tableFunc,
+		Entry("Resolves a name to a global template of the project", getInstanceTemplateInput{
+			sourceInstanceTemplate: "workers",
+			expectedURL:            "projects/fooproject/global/instanceTemplates/workers",
+		}),
+		Entry("Accepts a global template of another project", getInstanceTemplateInput{
+			sourceInstanceTemplate: "projects/barproject/global/instanceTemplates/workers",
+			expectedURL:            "projects/barproject/global/instanceTemplates/workers",
+		}),
+		Entry("Accepts a regional template in the region of the machine", getInstanceTemplateInput{
+			sourceInstanceTemplate: "https://www.googleapis.com/compute/v1/projects/fooproject/regions/us-central1/instanceTemplates/workers",
+			expectedURL:            "projects/fooproject/regions/us-central1/instanceTemplates/workers",
+		}),
+		Entry("Rejects a regional template in another region", getInstanceTemplateInput{
+			sourceInstanceTemplate: "projects/fooproject/regions/us-east1/instanceTemplates/workers",
+			expectedErrSubstring:   "is in region \"us-east1\", not in the region \"us-central1\" of the machine",
+		}),
+		Entry("Rejects a reference which is not a template", getInstanceTemplateInput{
+			sourceInstanceTemplate: "projects/fooproject/global/images/workers",
+			expectedErrSubstring:   "unrecognized instance template path format",
+		}),
+	)
+})
@@ -0,0 +1,181 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
+	"google.golang.org/api/googleapi"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// KMSKeyAccessibleCondition reports whether the KMS keys used to encrypt the disks can be used.
+	KMSKeyAccessibleCondition = "KMSKeyAccessible"
+
+	// KMSKeyAccessibleReason is the reason of the condition when all KMS keys can be used.
+	KMSKeyAccessibleReason = "KMSKeyAccessible"
+	// KMSKeyNotFoundReason is the reason of the condition when a KMS key does not exist.
+	KMSKeyNotFoundReason = "KMSKeyNotFound"
+	// KMSKeyDisabledReason is the reason of the condition when the primary version of a KMS key is not enabled.
+	KMSKeyDisabledReason = "KMSKeyDisabled"
+	// KMSKeyAccessUnverifiedReason is the reason of the condition when no readable IAM policy grants the
+	// encrypter/decrypter role on a KMS key to the service account encrypting the disks. The role may still
+	// be granted through a group, a custom role or a policy of a folder or organization.
+	KMSKeyAccessUnverifiedReason = "KMSKeyAccessUnverified"
+
+	kmsKeyRingNameFmt          = "projects/%s/locations/%s/keyRings/%s"
+	kmsKeyEncrypterDecrypter   = "roles/cloudkms.cryptoKeyEncrypterDecrypter"
+	kmsKeyVersionEnabled       = "ENABLED"
+	computeServiceAgentFmt     = "service-%s@compute-system.iam.gserviceaccount.com"
+	serviceAccountMemberPrefix = "serviceAccount:"
+)
+
+// KMSKeyError is returned when a KMS key referenced by a disk cannot be used to encrypt it.
+type KMSKeyError struct {
+	// Reason is the reason of the KMSKeyAccessible condition.
+	Reason  string
+	Message string
+	// Warning is set when the key may still be usable, the instances are then created anyway.
+	Warning bool
+}
+
+func (e *KMSKeyError) Error() string {
+	return e.Message
+}
+
+// ValidateKMSKeys makes sure the KMS keys referenced by the disks exist, have an enabled primary
+// version and that the service account encrypting the disks is granted the encrypter/decrypter role.
+// Problems with the keys are returned as a *KMSKeyError. A role binding which cannot be found is only
+// a warning, as the role can be granted in ways the policies do not show. Checks the credentials are
+// not allowed to perform are skipped, as the instance insert will still report them.
+func ValidateKMSKeys(ctx context.Context, kmsService kmsservice.KMSService, projectID string, providerConfig *machinev1.GCPMachineProviderSpec) error {
+	var serviceAgent string
+	var warning *KMSKeyError
+
+	for _, disk := range providerConfig.Disks {
+		if disk.EncryptionKey == nil || disk.EncryptionKey.KMSKey == nil {
+			continue
+		}
+		keyRef := disk.EncryptionKey.KMSKey
+
+		keyProjectID := projectID
+		if keyRef.ProjectID != "" {
+			keyProjectID = keyRef.ProjectID
+		}
+		keyRingName := fmt.Sprintf(kmsKeyRingNameFmt, keyProjectID, keyRef.Location, keyRef.KeyRing)
+		keyName := keyRingName + "/cryptoKeys/" + keyRef.Name
+
+		key, err := kmsService.CryptoKeysGet(ctx, keyName)
+		switch {
+		case isGoogleAPIErrorCode(err, http.StatusNotFound):
+			return &KMSKeyError{Reason: KMSKeyNotFoundReason, Message: fmt.Sprintf("KMS key %s not found", keyName)}
+		case isGoogleAPIErrorCode(err, http.StatusForbidden):
+			klog.Warningf("Not allowed to get KMS key %s, skipping its validation: %v", keyName, err)
+			continue
+		case err != nil:
+			return fmt.Errorf("failed to get KMS key %s: %w", keyName, err)
+		}
+		if key.Primary == nil || key.Primary.State != kmsKeyVersionEnabled {
+			state := "missing"
+			if key.Primary != nil {
+				state = strings.ToLower(key.Primary.State)
+			}
+			return &KMSKeyError{Reason: KMSKeyDisabledReason, Message: fmt.Sprintf("primary version of KMS key %s is %s", keyName, state)}
+		}
+
+		member := disk.EncryptionKey.KMSKeyServiceAccount
+		if member == "" {
+			if serviceAgent == "" {
+				project, err := kmsService.ProjectsGet(ctx, projectID)
+				if isGoogleAPIErrorCode(err, http.StatusForbidden) {
+					klog.Warningf("Not allowed to get project %s, skipping access validation of KMS key %s: %v", projectID, keyName, err)
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get project %s: %w", projectID, err)
+				}
+				serviceAgent = fmt.Sprintf(computeServiceAgentFmt, strings.TrimPrefix(project.Name, "projects/"))
+			}
+			member = serviceAgent
+		}
+
+		granted, err := isEncrypterDecrypterGranted(ctx, kmsService, serviceAccountMemberPrefix+member, keyProjectID, keyRingName, keyName)
+		if err != nil {
+			return err
+		}
+		if !granted && warning == nil {
+			warning = &KMSKeyError{
+				Reason:  KMSKeyAccessUnverifiedReason,
+				Message: fmt.Sprintf("no role binding grants %s on KMS key %s to service account %s", kmsKeyEncrypterDecrypter, keyName, member),
+				Warning: true,
+			}
+		}
+	}
+
+	if warning != nil {
+		return warning
+	}
+	return nil
+}
+
+// isEncrypterDecrypterGranted looks for the encrypter/decrypter role binding of the member on the key,
+// its key ring and its project. When none of the policies can be read the role is assumed to be granted.
+func isEncrypterDecrypterGranted(ctx context.Context, kmsService kmsservice.KMSService, member, projectID, keyRingName, keyName string) (bool, error) {
+	readable := false
+
+	keyPolicy, err := kmsService.CryptoKeysGetIamPolicy(ctx, keyName)
+	if err != nil && !isGoogleAPIErrorCode(err, http.StatusForbidden) {
+		return false, fmt.Errorf("failed to get IAM policy of KMS key %s: %w", keyName, err)
+	}
+	if err == nil {
+		readable = true
+		for _, binding := range keyPolicy.Bindings {
+			if binding.Role == kmsKeyEncrypterDecrypter && slices.Contains(binding.Members, member) {
+				return true, nil
+			}
+		}
+	}
+
+	keyRingPolicy, err := kmsService.KeyRingsGetIamPolicy(ctx, keyRingName)
+	if err != nil && !isGoogleAPIErrorCode(err, http.StatusForbidden) {
+		return false, fmt.Errorf("failed to get IAM policy of KMS key ring %s: %w", keyRingName, err)
+	}
+	if err == nil {
+		readable = true
+		for _, binding := range keyRingPolicy.Bindings {
+			if binding.Role == kmsKeyEncrypterDecrypter && slices.Contains(binding.Members, member) {
+				return true, nil
+			}
+		}
+	}
+
+	projectPolicy, err := kmsService.ProjectsGetIamPolicy(ctx, projectID)
+	if err != nil && !isGoogleAPIErrorCode(err, http.StatusForbidden) {
+		return false, fmt.Errorf("failed to get IAM policy of project %s: %w", projectID, err)
+	}
+	if err == nil {
+		readable = true
+		for _, binding := range projectPolicy.Bindings {
+			if binding.Role == kmsKeyEncrypterDecrypter && slices.Contains(binding.Members, member) {
+				return true, nil
+			}
+		}
+	}
+
+	if !readable {
+		klog.Warningf("Not allowed to get IAM policies of KMS key %s, skipping its access validation", keyName)
+		return true, nil
+	}
+	return false, nil
+}
+
+func isGoogleAPIErrorCode(err error, code int) bool {
+	var googleErr *googleapi.Error
+	return errors.As(err, &googleErr) && googleErr.Code == code
+}
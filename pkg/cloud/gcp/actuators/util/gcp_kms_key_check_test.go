@@ -0,0 +1,149 @@
+package util_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	machinev1 "github.com/openshift/api/machine/v1beta1"
+	machinev1builder "github.com/openshift/cluster-api-actuator-pkg/testutils/resourcebuilder/machine/v1beta1"
+	kmsservice "github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/services/kms"
+	"github.com/openshift/machine-api-provider-gcp/pkg/cloud/gcp/actuators/util"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
+	"google.golang.org/api/googleapi"
+)
+
+var _ = Describe("ValidateKMSKeys", func() {
+
+	type validateKMSKeysInput struct {
+		serviceAccount       string
+		mockKMSService       func(*kmsservice.MockKMSService)
+		expectedReason       string
+		expectWarning        bool
+		expectedErrSubstring string
+	}
+
+	forbidden := &googleapi.Error{Code: http.StatusForbidden}
+	encrypterPolicy := func(member string) *cloudkms.Policy {
+		return &cloudkms.Policy{
+			Bindings: []*cloudkms.Binding{
+				{
+					Role:    "roles/cloudkms.cryptoKeyEncrypterDecrypter",
+					Members: []string{member},
+				},
+			},
+		}
+	}
+
+	var tableFunc func(in validateKMSKeysInput) = func(in validateKMSKeysInput) {
+		kmsService := kmsservice.NewMockKMSService()
+		if in.mockKMSService != nil {
+			in.mockKMSService(kmsService)
+		}
+		providerSpec := machinev1builder.GCPProviderSpec().
+			WithDisks([]*machinev1.GCPDisk{
+				{
+					Boot:  true,
+					Image: "uefi-image",
+					EncryptionKey: &machinev1.GCPEncryptionKeyReference{
+						KMSKey: &machinev1.GCPKMSKeyReference{
+							Name:     "kms-key",
+							KeyRing:  "kms-ring",
+							Location: "global",
+						},
+						KMSKeyServiceAccount: in.serviceAccount,
+					},
+				},
+			}).Build()
+
+		err := util.ValidateKMSKeys(context.Background(), kmsService, "project", providerSpec)
+		if in.expectedReason == "" && in.expectedErrSubstring == "" {
+			Expect(err).ToNot(HaveOccurred())
+			return
+		}
+		Expect(err).To(HaveOccurred())
+
+		var keyErr *util.KMSKeyError
+		Expect(errors.As(err, &keyErr)).To(Equal(in.expectedReason != ""))
+		if in.expectedReason != "" {
+			Expect(keyErr.Reason).To(Equal(in.expectedReason))
+			Expect(keyErr.Warning).To(Equal(in.expectWarning))
+		}
+		if in.expectedErrSubstring != "" {
+			Expect(err.Error()).To(ContainSubstring(in.expectedErrSubstring))
+		}
+	}
+
+	DescribeTable("Encrypter/decrypter role",
+		tableFunc,
+		Entry("Granted to the compute service agent on the project", validateKMSKeysInput{}),
+		Entry("Granted to the service account on the key", validateKMSKeysInput{
+			serviceAccount: "disks@project.iam.gserviceaccount.com",
+			mockKMSService: func(m *kmsservice.MockKMSService) {
+				m.MockCryptoKeysGetIamPolicy = func(_ context.Context, _ string) (*cloudkms.Policy, error) {
+					return encrypterPolicy("serviceAccount:disks@project.iam.gserviceaccount.com"), nil
+				}
+			},
+		}),
+		Entry("Not granted to the service account", validateKMSKeysInput{
+			serviceAccount: "disks@project.iam.gserviceaccount.com",
+			expectedReason: util.KMSKeyAccessUnverifiedReason,
+			expectWarning:  true,
+		}),
+		Entry("Not granted to the compute service agent", validateKMSKeysInput{
+			mockKMSService: func(m *kmsservice.MockKMSService) {
+				m.MockProjectsGetIamPolicy = func(_ context.Context, _ string) (*resourcemanager.Policy, error) {
+					return &resourcemanager.Policy{}, nil
+				}
+			},
+			expectedReason: util.KMSKeyAccessUnverifiedReason,
+			expectWarning:  true,
+		}),
+		Entry("Skipped when no IAM policy can be read", validateKMSKeysInput{
+			serviceAccount: "disks@project.iam.gserviceaccount.com",
+			mockKMSService: func(m *kmsservice.MockKMSService) {
+				m.MockCryptoKeysGetIamPolicy = func(_ context.Context, _ string) (*cloudkms.Policy, error) {
+					return nil, forbidden
+				}
+				m.MockKeyRingsGetIamPolicy = func(_ context.Context, _ string) (*cloudkms.Policy, error) {
+					return nil, forbidden
+				}
+				m.MockProjectsGetIamPolicy = func(_ context.Context, _ string) (*resourcemanager.Policy, error) {
+					return nil, forbidden
+				}
+			},
+		}),
+	)
+
+	DescribeTable("Key state",
+		tableFunc,
+		Entry("Skipped when the key cannot be read", validateKMSKeysInput{
+			mockKMSService: func(m *kmsservice.MockKMSService) {
+				m.MockCryptoKeysGet = func(_ context.Context, _ string) (*cloudkms.CryptoKey, error) {
+					return nil, forbidden
+				}
+			},
+		}),
+		Entry("Key without primary version", validateKMSKeysInput{
+			mockKMSService: func(m *kmsservice.MockKMSService) {
+				m.MockCryptoKeysGet = func(_ context.Context, name string) (*cloudkms.CryptoKey, error) {
+					return &cloudkms.CryptoKey{Name: name}, nil
+				}
+			},
+			expectedReason:       util.KMSKeyDisabledReason,
+			expectedErrSubstring: "is missing",
+		}),
+		Entry("Unexpected error getting the key", validateKMSKeysInput{
+			mockKMSService: func(m *kmsservice.MockKMSService) {
+				m.MockCryptoKeysGet = func(_ context.Context, _ string) (*cloudkms.CryptoKey, error) {
+					return nil, &googleapi.Error{Code: http.StatusInternalServerError}
+				}
+			},
+			expectedErrSubstring: "failed to get KMS key",
+		}),
+	)
+})
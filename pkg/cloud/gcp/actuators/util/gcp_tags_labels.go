@@ -75,6 +75,11 @@ func getOCPLabels(clusterID string) map[string]string {
 	}
 }
 
+// IsOwnedByCluster tells whether the labels of a resource mark it as owned by the cluster.
+func IsOwnedByCluster(labels map[string]string, clusterID string) bool {
+	return labels[fmt.Sprintf(ocpDefaultLabelFmt, clusterID)] == "owned"
+}
+
 // mergeLabels is for merging OCP specific labels, labels defined in Infrastructure.Status and
 // GCPMachineProviderSpec with OCP, GCPMachineProviderSpec, Infrastructure labels precedence order.
 func mergeLabels(ocpLabels, providerSpecLabels, infraLabels map[string]string) map[string]string {